@@ -16,6 +16,10 @@
 
 package stompngo
 
+import (
+	"context"
+)
+
 /*
 	Abort a STOMP transaction.
 
@@ -30,7 +34,15 @@ package stompngo
 		}
 */
 func (c *Connection) Abort(h Headers) error {
-	c.log(ABORT, "start", h)
+	return c.AbortContext(context.Background(), h)
+}
+
+/*
+	AbortContext is Abort, giving up when ctx is done.  See ContextStomper.
+*/
+func (c *Connection) AbortContext(ctx context.Context, h Headers) (e error) {
+	defer func() { e = newOpError("Abort", ABORT, h, e) }()
+	c.debug("ABORT start", "headers", h)
 	if !c.isConnected() {
 		return ECONBAD
 	}
@@ -42,7 +54,7 @@ func (c *Connection) Abort(h Headers) error {
 	if h.Value(HK_TRANSACTION) == "" {
 		return ETIDABTEMT
 	}
-	e := c.transmitCommon(ABORT, h) // transmitCommon Clones() the headers
-	c.log(ABORT, "end", h)
+	e = c.transmitCommonContext(ctx, ABORT, h)
+	c.debug("ABORT end", "headers", h)
 	return e
 }
@@ -16,6 +16,10 @@
 
 package stompngo
 
+import (
+	"context"
+)
+
 /*
 	Ack a STOMP MESSAGE.
 
@@ -40,11 +44,21 @@ package stompngo
 
 */
 func (c *Connection) Ack(h Headers) error {
-	c.log(ACK, "start", h, c.Protocol())
+	return c.AckContext(context.Background(), h)
+}
+
+/*
+	AckContext is Ack, giving up when ctx is done.  See ContextStomper.
+*/
+func (c *Connection) AckContext(ctx context.Context, h Headers) (e error) {
+	defer func() { e = newOpError("Ack", ACK, h, e) }()
+	if c.logs(LogDebug) {
+		c.debug("ACK start", "headers", h)
+	}
 	if !c.isConnected() {
 		return ECONBAD
 	}
-	e := checkHeaders(h, c.Protocol())
+	e = checkHeaders(h, c.Protocol())
 	if e != nil {
 		return e
 	}
@@ -66,7 +80,9 @@ func (c *Connection) Ack(h Headers) error {
 		}
 	}
 
-	e = c.transmitCommon(ACK, h) // transmitCommon Clones() the headers
-	c.log(ACK, "end", h, c.Protocol())
+	e = c.transmitCommonContext(ctx, ACK, h)
+	if c.logs(LogDebug) {
+		c.debug("ACK end", "headers", h)
+	}
 	return e
 }
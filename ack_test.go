@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -40,7 +41,7 @@ func TestAckErrors(t *testing.T) {
 	for _, tv := range terrList {
 		conn.protocol = tv.proto // Fake it
 		e = conn.Ack(tv.headers)
-		if e != tv.errval {
+		if !errors.Is(e, tv.errval) {
 			t.Fatalf("ACK -%s- expected error [%v], got [%v]\n",
 				tv.proto, tv.errval, e)
 		}
@@ -0,0 +1,73 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"math"
+	"time"
+)
+
+/*
+	Backoff describes the delays used between repeated connection attempts.
+
+	The delay before retry number n (starting at 0) is Initial * Multiplier^n,
+	limited to Max.  With no Max it is limited to the largest time.Duration.
+	An Initial of zero or less means no delay.
+*/
+type Backoff struct {
+	Initial     time.Duration // Delay before the first retry
+	Max         time.Duration // Upper limit for any single delay, 0 for no limit
+	Multiplier  float64       // Growth factor, values less than 1 are treated as 1
+	MaxAttempts int           // Number of retries before giving up, 0 to retry forever
+}
+
+/*
+	DefaultBackoff is used when no other Backoff is supplied.
+*/
+var DefaultBackoff = Backoff{Initial: 100 * time.Millisecond,
+	Max:        30 * time.Second,
+	Multiplier: 2.0}
+
+/*
+	Delay returns the delay to use before retry number n, where n starts at 0.
+*/
+func (b Backoff) Delay(n int) time.Duration {
+	if b.Initial <= 0 {
+		return 0
+	}
+	m := b.Multiplier
+	if !(m >= 1) { // NaN too
+		m = 1
+	}
+	lim := b.Max
+	if lim <= 0 {
+		lim = math.MaxInt64
+	}
+	// An overflow is +Inf, which is past any limit
+	d := float64(b.Initial) * math.Pow(m, float64(n))
+	if d >= float64(lim) {
+		return lim
+	}
+	return time.Duration(d)
+}
+
+/*
+	Exhausted reports whether n retries use up the allowed attempts.
+*/
+func (b Backoff) Exhausted(n int) bool {
+	return b.MaxAttempts > 0 && n >= b.MaxAttempts
+}
@@ -16,6 +16,10 @@
 
 package stompngo
 
+import (
+	"context"
+)
+
 /*
 	Begin a STOMP transaction.
 
@@ -30,7 +34,15 @@ package stompngo
 		}
 */
 func (c *Connection) Begin(h Headers) error {
-	c.log(BEGIN, "start", h)
+	return c.BeginContext(context.Background(), h)
+}
+
+/*
+	BeginContext is Begin, giving up when ctx is done.  See ContextStomper.
+*/
+func (c *Connection) BeginContext(ctx context.Context, h Headers) (e error) {
+	defer func() { e = newOpError("Begin", BEGIN, h, e) }()
+	c.debug("BEGIN start", "headers", h)
 	if !c.isConnected() {
 		return ECONBAD
 	}
@@ -42,7 +54,7 @@ func (c *Connection) Begin(h Headers) error {
 	if h.Value(HK_TRANSACTION) == "" {
 		return ETIDBEGEMT
 	}
-	e := c.transmitCommon(BEGIN, h) // transmitCommon Clones() the headers
-	c.log(BEGIN, "end", h)
+	e = c.transmitCommonContext(ctx, BEGIN, h)
+	c.debug("BEGIN end", "headers", h)
 	return e
 }
@@ -0,0 +1,122 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+type codecOrder struct {
+	ID    int
+	Items []string
+}
+
+/*
+	Upper case text, for a registered codec.
+*/
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(string))), nil
+}
+
+func (upperCodec) Unmarshal(b []byte, v interface{}) error {
+	*(v.(*string)) = strings.ToLower(string(b))
+	return nil
+}
+
+/*
+	Test SendValue and Message.Decode with each codec.
+*/
+func TestCodecs(t *testing.T) {
+	if e := RegisterCodec("Application/X-Upper; charset=UTF-8",
+		upperCodec{}); e != nil {
+		t.Fatalf("TestCodecs Expected no register error, got [%v]\n", e)
+	}
+	defer func() { _ = RegisterCodec("application/x-upper", nil) }()
+	b := fakebroker.New(nil)
+	defer b.Close()
+	conn, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestCodecs CONNECT Failed: e:<%q>\n", e)
+	}
+	d := tdest("/queue/codecs")
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "cdid"})
+	if e != nil {
+		t.Fatalf("TestCodecs Expected no subscribe error, got [%v]\n", e)
+	}
+	o := codecOrder{ID: 42, Items: []string{"a", "b"}}
+	for _, ct := range []string{"", CT_GOB, "application/json; charset=UTF-8"} {
+		h := Headers{HK_DESTINATION, d}
+		if ct != "" {
+			h = h.Add(HK_CONTENT_TYPE, ct)
+		}
+		if e = conn.SendValue(h, o); e != nil {
+			t.Fatalf("TestCodecs Expected no send error, got [%v]\n", e)
+		}
+		md := <-sc
+		if ct == "" && md.Message.Headers.Value(HK_CONTENT_TYPE) != CT_JSON {
+			t.Fatalf("TestCodecs Expected [%s], got [%q]\n", CT_JSON,
+				md.Message.Headers)
+		}
+		var got codecOrder
+		if e = md.Message.Decode(&got); e != nil || got.ID != o.ID ||
+			len(got.Items) != 2 {
+			t.Fatalf("TestCodecs Expected [%v], got [%v] [%v]\n", o, got, e)
+		}
+	}
+	// Registered and text codecs
+	for _, ct := range []string{"application/x-upper", ""} {
+		h := Headers{HK_DESTINATION, d, HK_CONTENT_TYPE, ct}
+		if ct == "" {
+			h = Headers{HK_DESTINATION, d} // text/plain by default
+			e = conn.Send(h, "text body")
+		} else {
+			e = conn.SendValue(h, "text body")
+		}
+		if e != nil {
+			t.Fatalf("TestCodecs Expected no send error, got [%v]\n", e)
+		}
+		md := <-sc
+		var s string
+		if e = md.Message.Decode(&s); e != nil || s != "text body" {
+			t.Fatalf("TestCodecs Expected [text body], got [%s] [%v]\n", s, e)
+		}
+		if ct != "" && md.Message.BodyString() != "TEXT BODY" {
+			t.Fatalf("TestCodecs Expected [TEXT BODY], got [%s]\n",
+				md.Message.Body)
+		}
+	}
+	// Errors
+	h := Headers{HK_DESTINATION, d, HK_CONTENT_TYPE, "application/x-none"}
+	if e = conn.SendValue(h, o); !errors.Is(e, ENOCODEC) {
+		t.Fatalf("TestCodecs Expected [%v], got [%v]\n", ENOCODEC, e)
+	}
+	h = Headers{HK_DESTINATION, d, HK_CONTENT_TYPE, CT_TEXT}
+	if e = conn.SendValue(h, o); !errors.Is(e, ECODECVAL) {
+		t.Fatalf("TestCodecs Expected [%v], got [%v]\n", ECODECVAL, e)
+	}
+	m := Message{MESSAGE, Headers{}, []byte("{}")}
+	if e = m.Decode(&o); !errors.Is(e, ENOCODEC) {
+		t.Fatalf("TestCodecs Expected [%v], got [%v]\n", ENOCODEC, e)
+	}
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+}
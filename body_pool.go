@@ -0,0 +1,91 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"sync"
+)
+
+/*
+	Pooled MESSAGE body buffers, see ConnectOptions.PoolBodies.  Buffers come
+	in power of two size classes, from 1 << bodyPoolMin to 1 << bodyPoolMax
+	bytes.  Larger bodies are never pooled.
+*/
+const (
+	bodyPoolMin = 9  // 512 bytes
+	bodyPoolMax = 20 // 1 MiB
+)
+
+var bodyPools [bodyPoolMax - bodyPoolMin + 1]sync.Pool
+
+/*
+	The size class for n bytes, or -1.
+*/
+func bodyClass(n int) int {
+	for i := 0; i <= bodyPoolMax-bodyPoolMin; i++ {
+		if n <= 1<<uint(bodyPoolMin+i) {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+	A body buffer of length n, from the pool when possible.
+*/
+func getBody(n int) []byte {
+	i := bodyClass(n)
+	if i < 0 {
+		return make([]byte, n)
+	}
+	if p, ok := bodyPools[i].Get().(*[]byte); ok {
+		return (*p)[:n]
+	}
+	return make([]byte, n, 1<<uint(bodyPoolMin+i))
+}
+
+/*
+	Return a body buffer to the pool.  Buffers not made by getBody are
+	dropped.
+*/
+func putBody(b []byte) {
+	i := bodyClass(cap(b))
+	if i < 0 || cap(b) != 1<<uint(bodyPoolMin+i) {
+		return
+	}
+	b = b[:0]
+	bodyPools[i].Put(&b)
+}
+
+/*
+	Release gives a MESSAGE body back for reuse, which pays off when the
+	connection uses ConnectOptions.PoolBodies.  Message.Body is set to nil,
+	and no copy of it may be used after Release.  For other frames Release
+	does nothing.
+
+	Example:
+		md := <-sc
+		process(md.Message.Body)
+		md.Release()
+*/
+func (md *MessageData) Release() {
+	if md.Message.Command != MESSAGE {
+		return
+	}
+	putBody(md.Message.Body)
+	md.Message.Body = nil
+}
@@ -0,0 +1,123 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+/*
+	BrokerError is an ERROR frame received from the broker after CONNECT.
+
+	It is the MessageData.Error value when an ERROR frame is delivered to a
+	subscription channel or a Receipt.  Use errors.As to recognize it:
+		var be *stompngo.BrokerError
+		if errors.As(md.Error, &be) {
+			log.Println("broker said:", be.Message, string(be.Body))
+		}
+*/
+type BrokerError struct {
+	Message     string  // The message header
+	ReceiptID   string  // The receipt-id header, if any
+	ContentType string  // The content-type header, if any
+	Body        []byte  // The ERROR frame body
+	Headers     Headers // All ERROR frame headers
+}
+
+/*
+	BrokerErrorNotification is a callback function, provided by the client
+	and called for each ERROR frame received after CONNECT.  It is called on
+	the connection's reader goroutine, and should return promptly.
+*/
+type BrokerErrorNotification func(be *BrokerError)
+
+/*
+	NewBrokerError returns the BrokerError for an ERROR frame, for example one
+	read from Connection.MessageData.
+*/
+func NewBrokerError(m Message) *BrokerError {
+	return &BrokerError{Message: m.Headers.Value(HK_MESSAGE),
+		ReceiptID:   m.Headers.Value(HK_RECEIPT_ID),
+		ContentType: m.Headers.Value(HK_CONTENT_TYPE),
+		Body:        m.Body,
+		Headers:     m.Headers}
+}
+
+/*
+	Error returns the broker message, or the frame body if there is no
+	message header.
+*/
+func (e *BrokerError) Error() string {
+	s := e.Message
+	if s == "" {
+		s = string(e.Body)
+	}
+	if e.ReceiptID != "" {
+		return "broker ERROR: " + s + " (receipt-id " + e.ReceiptID + ")"
+	}
+	return "broker ERROR: " + s
+}
+
+/*
+	OnBrokerError sets the broker ERROR notification callback function.  Set
+	to nil to remove it.
+*/
+func (c *Connection) OnBrokerError(f BrokerErrorNotification) {
+	c.debug("Set OnBrokerError")
+	c.beLock.Lock()
+	c.benotify = f
+	c.beLock.Unlock()
+}
+
+/*
+	Dispatch an ERROR frame.  The callback sees every ERROR.  Every
+	subscription channel is told, without blocking, since the broker closes the
+	connection after an ERROR.  Those channels do not also get the read error
+	that follows.  An ERROR for a pending Receipt goes to that Receipt, and any
+	other ERROR goes to MessageData as before.
+
+	An ERROR vetoed by an interceptor is not a BrokerError: it goes to a
+	pending Receipt or to MessageData, with the veto as the error.
+*/
+func (c *Connection) brokerError(m Message, ie error) {
+	if ie != nil {
+		md := MessageData{Message: m, Error: ie}
+		if !c.completeReceipt(md) {
+			c.input <- md
+		}
+		return
+	}
+	be := NewBrokerError(m)
+	c.beLock.Lock()
+	f := c.benotify
+	c.beLock.Unlock()
+	if f != nil {
+		f(be)
+	}
+	md := MessageData{Message: m, Error: be}
+	c.subsLock.Lock()
+	for _, s := range c.subs {
+		if s.cs || s.bes {
+			continue
+		}
+		select {
+		case s.md <- md:
+			s.bes = true
+		default:
+		}
+	}
+	c.subsLock.Unlock()
+	if !c.completeReceipt(md) {
+		c.input <- MessageData{Message: m}
+	}
+}
@@ -0,0 +1,72 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test that a rejected SUBSCRIBE reaches the subscriber, the callback and
+	MessageData.
+*/
+func TestBrokerErrorSubscribe(t *testing.T) {
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if f.Command == SUBSCRIBE {
+				s.Error("access refused", f)
+				return true
+			}
+			return false
+		}})
+	defer b.Close()
+	n := b.Pipe()
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestBrokerErrorSubscribe CONNECT Failed: e:<%q>\n", e)
+	}
+	cb := make(chan *BrokerError, 1)
+	conn.OnBrokerError(func(be *BrokerError) { cb <- be })
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, "/queue/broker.error",
+		HK_ID, "beid"})
+	if e != nil {
+		t.Fatalf("TestBrokerErrorSubscribe Expected no subscribe error, got [%v]\n", e)
+	}
+	md := <-sc
+	var be *BrokerError
+	if !errors.As(md.Error, &be) || be.Message != "access refused" ||
+		be.ContentType != "text/plain" || string(be.Body) != "access refused" {
+		t.Fatalf("TestBrokerErrorSubscribe Expected BrokerError, got [%v]\n", md.Error)
+	}
+	if cbe := <-cb; cbe.Message != be.Message {
+		t.Fatalf("TestBrokerErrorSubscribe Expected callback [%v], got [%v]\n",
+			be, cbe)
+	}
+	md = <-conn.MessageData
+	if md.Message.Command != ERROR || md.Error != nil {
+		t.Fatalf("TestBrokerErrorSubscribe Expected ERROR, got [%v] [%v]\n",
+			md.Message.Command, md.Error)
+	}
+	if NewBrokerError(md.Message).Error() != "broker ERROR: access refused" {
+		t.Fatalf("TestBrokerErrorSubscribe Unexpected text [%v]\n",
+			NewBrokerError(md.Message))
+	}
+	_ = closeConn(t, n)
+}
@@ -0,0 +1,223 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+)
+
+/*
+	Codec marshals values to message bodies, and unmarshals them back.
+*/
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+/*
+	Content types with built in codecs.
+*/
+const (
+	CT_JSON = "application/json"
+	CT_GOB  = "application/x-gob"
+	CT_TEXT = "text/plain"
+)
+
+/*
+	The content type SendValue uses when the headers have none.
+*/
+const DefaultValueType = CT_JSON
+
+var (
+	codecs = map[string]Codec{CT_JSON: jsonCodec{}, CT_GOB: gobCodec{},
+		CT_TEXT: textCodec{}}
+	codecLock sync.RWMutex
+)
+
+/*
+	RegisterCodec sets the codec for a content type, replacing any codec
+	already set.  Parameters such as charset are ignored, and the type is not
+	case sensitive.  A nil Codec removes the content type.
+*/
+func RegisterCodec(ct string, c Codec) error {
+	mt, e := mediaType(ct)
+	if e != nil {
+		return e
+	}
+	codecLock.Lock()
+	defer codecLock.Unlock()
+	if c == nil {
+		delete(codecs, mt)
+		return nil
+	}
+	codecs[mt] = c
+	return nil
+}
+
+/*
+	CodecFor returns the codec for a content type.  A content type with no
+	codec gives ENOCODEC.
+*/
+func CodecFor(ct string) (Codec, error) {
+	mt, e := mediaType(ct)
+	if e != nil {
+		return nil, e
+	}
+	codecLock.RLock()
+	c, ok := codecs[mt]
+	codecLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ENOCODEC, ct)
+	}
+	return c, nil
+}
+
+/*
+	The media type of a content-type header, without parameters.
+*/
+func mediaType(ct string) (string, error) {
+	if strings.TrimSpace(ct) == "" {
+		return "", fmt.Errorf("%w: no content-type", ENOCODEC)
+	}
+	mt, _, e := mime.ParseMediaType(ct)
+	if e != nil {
+		return "", fmt.Errorf("%w: %q: %v", ENOCODEC, ct, e)
+	}
+	return mt, nil
+}
+
+/*
+	Decode unmarshals the Message body into v, with the codec for the
+	content-type header.  No content-type, or one with no codec, gives
+	ENOCODEC.
+
+	Example:
+		var o Order
+		if e := md.Message.Decode(&o); e != nil {
+			// Do something sane ...
+		}
+*/
+func (m *Message) Decode(v interface{}) error {
+	ct := m.Headers.Value(HK_CONTENT_TYPE)
+	c, e := CodecFor(ct)
+	if e != nil {
+		return e
+	}
+	return c.Unmarshal(m.Body, v)
+}
+
+/*
+	SendValue sends a STOMP MESSAGE with a body marshaled from v.
+
+	The codec is picked by the content-type header.  When there is none,
+	DefaultValueType is used, and a content-type header is added.
+
+	Example:
+		h := stompngo.Headers{stompngo.HK_DESTINATION, "/queue/orders"}
+		e := c.SendValue(h, Order{ID: 42})
+		if e != nil {
+			// Do something sane ...
+		}
+*/
+func (c *Connection) SendValue(h Headers, v interface{}) error {
+	return c.SendValueContext(context.Background(), h, v)
+}
+
+/*
+	SendValueContext is SendValue, giving up when ctx is done.  See
+	ContextStomper.
+*/
+func (c *Connection) SendValueContext(ctx context.Context, h Headers, v interface{}) error {
+	ct, ok := h.Contains(HK_CONTENT_TYPE)
+	if !ok {
+		ct = DefaultValueType
+	}
+	cd, e := CodecFor(ct)
+	if e != nil {
+		return newOpError("SendValue", SEND, h, e)
+	}
+	b, e := cd.Marshal(v)
+	if e != nil {
+		return newOpError("SendValue", SEND, h, e)
+	}
+	if !ok {
+		h = h.Clone().Add(HK_CONTENT_TYPE, ct)
+	}
+	return c.SendBytesContext(ctx, h, b)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if e := gob.NewEncoder(&b).Encode(v); e != nil {
+		return nil, e
+	}
+	return b.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(b []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+/*
+	Text bodies are strings or byte slices as is.
+*/
+type textCodec struct{}
+
+func (textCodec) Marshal(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	case fmt.Stringer:
+		return []byte(t.String()), nil
+	}
+	return nil, fmt.Errorf("%w: %s needs a string or []byte, not %T", ECODECVAL,
+		CT_TEXT, v)
+}
+
+func (textCodec) Unmarshal(b []byte, v interface{}) error {
+	switch t := v.(type) {
+	case *string:
+		*t = string(b)
+	case *[]byte:
+		*t = append((*t)[:0], b...)
+	default:
+		return fmt.Errorf("%w: %s needs a *string or *[]byte, not %T", ECODECVAL,
+			CT_TEXT, v)
+	}
+	return nil
+}
@@ -16,6 +16,10 @@
 
 package stompngo
 
+import (
+	"context"
+)
+
 /*
 	Commit a STOMP transaction.
 
@@ -31,7 +35,15 @@ package stompngo
 
 */
 func (c *Connection) Commit(h Headers) error {
-	c.log(COMMIT, "start", h)
+	return c.CommitContext(context.Background(), h)
+}
+
+/*
+	CommitContext is Commit, giving up when ctx is done.  See ContextStomper.
+*/
+func (c *Connection) CommitContext(ctx context.Context, h Headers) (e error) {
+	defer func() { e = newOpError("Commit", COMMIT, h, e) }()
+	c.debug("COMMIT start", "headers", h)
 	if !c.isConnected() {
 		return ECONBAD
 	}
@@ -43,7 +55,7 @@ func (c *Connection) Commit(h Headers) error {
 	if h.Value(HK_TRANSACTION) == "" {
 		return ETIDCOMEMT
 	}
-	e := c.transmitCommon(COMMIT, h) // transmitCommon Clones() the headers
-	c.log(COMMIT, "end", h)
+	e = c.transmitCommonContext(ctx, COMMIT, h)
+	c.debug("COMMIT end", "headers", h)
 	return e
 }
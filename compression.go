@@ -0,0 +1,166 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+)
+
+/*
+	Body compression.  See ConnectOptions.Compress.
+
+	A compressed SEND body gets a content-encoding header.  Inbound MESSAGE
+	bodies with a known content-encoding are decompressed, and that header is
+	removed.  Compression is skipped for bodies smaller than the minimum size,
+	bodies that do not get smaller, frames that already have a
+	content-encoding header, and frames with suppress-content-length, whose
+	body ends at the first NUL.  Streamed bodies and PreparedFrames are sent
+	as is.
+*/
+
+/*
+	The content-encoding header, and its supported values.
+*/
+const (
+	HK_CONTENT_ENCODING = "content-encoding"
+	CE_GZIP             = "gzip"
+	CE_DEFLATE          = "deflate"
+)
+
+/*
+	Default minimum body size for compression.
+*/
+const DefaultCompressMin = 1024
+
+var (
+	gzipWriters  = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+	flateWriters = sync.Pool{New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	}}
+)
+
+/*
+	Check a content-encoding for ConnectOptions.Compress.
+*/
+func checkCompress(ce string) error {
+	switch ce {
+	case "", CE_GZIP, CE_DEFLATE:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ECOMPRESS, ce)
+}
+
+/*
+	Compress a SEND frame body if the connection asks for it.  The frame
+	given is not changed.
+*/
+func (c *Connection) compressFrame(f Frame) (Frame, error) {
+	if c.cenc == "" || f.Command != SEND || len(f.Body) < c.cmin {
+		return f, nil
+	}
+	if _, ok := f.Headers.Contains(HK_CONTENT_ENCODING); ok {
+		return f, nil
+	}
+	if _, ok := f.Headers.Contains(HK_SUPPRESS_CL); ok {
+		return f, nil
+	}
+	var b bytes.Buffer
+	b.Grow(len(f.Body) / 2)
+	var e error
+	switch c.cenc {
+	case CE_GZIP:
+		w := gzipWriters.Get().(*gzip.Writer)
+		w.Reset(&b)
+		if _, e = w.Write(f.Body); e == nil {
+			e = w.Close()
+		}
+		gzipWriters.Put(w)
+	default:
+		w := flateWriters.Get().(*flate.Writer)
+		w.Reset(&b)
+		if _, e = w.Write(f.Body); e == nil {
+			e = w.Close()
+		}
+		flateWriters.Put(w)
+	}
+	if e != nil {
+		return f, e
+	}
+	if b.Len() >= len(f.Body) {
+		return f, nil // No gain
+	}
+	// The writer adds the new content-length
+	h := f.Headers.DeleteAll(HK_CONTENT_LENGTH).Add(HK_CONTENT_ENCODING, c.cenc)
+	return Frame{f.Command, h, b.Bytes()}, nil
+}
+
+/*
+	Decompress an inbound MESSAGE body.  A failure is set as md.Error, and
+	the message is left as read.
+*/
+func (c *Connection) decompressBody(md *MessageData) {
+	ce, ok := md.Message.Headers.Contains(HK_CONTENT_ENCODING)
+	if !ok || md.BodyReader != nil {
+		return
+	}
+	var r io.Reader
+	var e error
+	switch ce {
+	case CE_GZIP:
+		r, e = gzip.NewReader(bytes.NewReader(md.Message.Body))
+	case CE_DEFLATE:
+		r = flate.NewReader(bytes.NewReader(md.Message.Body))
+	default:
+		return // Not ours to decode
+	}
+	var b []byte
+	if e == nil {
+		// No decompression bombs
+		b, e = ioutil.ReadAll(io.LimitReader(r, int64(c.flim.MaxBody)+1))
+		if e == nil && len(b) > c.flim.MaxBody {
+			e = &FrameLimitError{"MaxBody", c.flim.MaxBody}
+		}
+	}
+	if e != nil {
+		if !isFrameLimit(e) {
+			e = fmt.Errorf("%w: %s: %v", EDECOMP, ce, e)
+		}
+		md.Error = e
+		return
+	}
+	md.Release() // A pooled compressed body
+	md.Message.Body = b
+	h := md.Message.Headers.DeleteAll(HK_CONTENT_ENCODING)
+	if _, ok := h.Contains(HK_CONTENT_LENGTH); ok {
+		h = h.DeleteAll(HK_CONTENT_LENGTH).Add(HK_CONTENT_LENGTH,
+			strconv.Itoa(len(b)))
+	}
+	md.Message.Headers = h
+}
+
+func isFrameLimit(e error) bool {
+	_, ok := e.(*FrameLimitError)
+	return ok
+}
@@ -0,0 +1,122 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Connect to a broker with options, and subscribe.
+*/
+func compressConn(t *testing.T, b *fakebroker.Broker, o *ConnectOptions,
+	d string) (*Connection, <-chan MessageData) {
+	conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+		SPL_12), o)
+	if e != nil {
+		t.Fatalf("TestCompression CONNECT Failed: e:<%q>\n", e)
+	}
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, d})
+	if e != nil {
+		t.Fatalf("TestCompression Expected no subscribe error, got [%v]\n", e)
+	}
+	return conn, sc
+}
+
+/*
+	Test compressed SEND bodies, and decompressed MESSAGE bodies.
+*/
+func TestCompression(t *testing.T) {
+	_, e := ConnectWithOptions(nil, Headers{}, &ConnectOptions{Compress: "br"})
+	if !errors.Is(e, ECOMPRESS) {
+		t.Fatalf("TestCompression Expected [%v], got [%v]\n", ECOMPRESS, e)
+	}
+	big := bytes.Repeat([]byte(`{"id":42,"name":"compressible"},`), 400)
+	for _, ce := range []string{CE_GZIP, CE_DEFLATE} {
+		b := fakebroker.New(nil)
+		d := tdest("/queue/compression." + ce)
+		plain, psc := compressConn(t, b, nil, d)
+		dec, dsc := compressConn(t, b, &ConnectOptions{Decompress: true,
+			FrameLimits: FrameLimits{MaxBody: 64 * 1024}}, d)
+		conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+			SPL_12), &ConnectOptions{Compress: ce})
+		if e != nil {
+			t.Fatalf("TestCompression CONNECT Failed: e:<%q>\n", e)
+		}
+		h := Headers{HK_DESTINATION, d}
+		sends := [][]byte{big, []byte("small"), make([]byte, 128*1024)}
+		for _, body := range sends {
+			// Round robin to both subscribers
+			for i := 0; i < 2; i++ {
+				if e = conn.SendBytes(h, body); e != nil {
+					t.Fatalf("TestCompression Expected no send error, got [%v]\n", e)
+				}
+			}
+		}
+		// Suppressed content-length is never compressed
+		e = conn.SendBytes(h.Add(HK_SUPPRESS_CL, "y"), big)
+		if e != nil {
+			t.Fatalf("TestCompression Expected no send error, got [%v]\n", e)
+		}
+		for i, body := range sends {
+			md := <-psc
+			mce := md.Message.Headers.Value(HK_CONTENT_ENCODING)
+			if (i == 1) != (mce == "") || (i != 1 && len(md.Message.Body) >= len(body)) {
+				t.Fatalf("TestCompression Expected compressed [%d], got [%s] [%d]\n",
+					i, mce, len(md.Message.Body))
+			}
+			md = <-dsc
+			if i == 2 { // Bigger than MaxBody when decompressed
+				var fe *FrameLimitError
+				if !errors.As(md.Error, &fe) || fe.Limit != "MaxBody" {
+					t.Fatalf("TestCompression Expected MaxBody, got [%v]\n", md.Error)
+				}
+				continue
+			}
+			if md.Error != nil || !bytes.Equal(md.Message.Body, body) ||
+				md.Message.Headers.Value(HK_CONTENT_ENCODING) != "" ||
+				md.Message.Headers.Value(HK_CONTENT_LENGTH) !=
+					strconv.Itoa(len(body)) {
+				t.Fatalf("TestCompression Expected [%d] bytes, got [%d] [%q] [%v]\n",
+					len(body), len(md.Message.Body), md.Message.Headers, md.Error)
+			}
+		}
+		md := <-psc
+		if md.Message.Headers.Value(HK_CONTENT_ENCODING) != "" {
+			t.Fatalf("TestCompression Expected no compression, got [%q]\n",
+				md.Message.Headers)
+		}
+		// A body that does not decompress
+		e = conn.SendBytes(h.Add(HK_CONTENT_ENCODING, ce), []byte("not compressed"))
+		if e != nil {
+			t.Fatalf("TestCompression Expected no send error, got [%v]\n", e)
+		}
+		md = <-dsc
+		if !errors.Is(md.Error, EDECOMP) || md.Message.BodyString() != "not compressed" {
+			t.Fatalf("TestCompression Expected [%v], got [%v]\n", EDECOMP, md.Error)
+		}
+		for _, c := range []*Connection{conn, dec, plain} {
+			checkDisconnectError(t, c.Disconnect(empty_headers))
+		}
+		_ = b.Close()
+	}
+}
@@ -16,7 +16,10 @@
 
 package stompngo
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 /*
 	ConnBadValVer Test: Bad Version value.
@@ -30,7 +33,7 @@ func TestConnBadValVer(t *testing.T) {
 		if e == nil {
 			t.Errorf("TestConnBadValVer Expected error, got nil, proto: %s\n", p)
 		}
-		if e != EBADVERCLI {
+		if !errors.Is(e, EBADVERCLI) {
 			t.Errorf("TestConnBadValVer Expected <%v>, got <%v>, proto: %s\n",
 				EBADVERCLI, e, p)
 		}
@@ -53,7 +56,7 @@ func TestConnBadValHost(t *testing.T) {
 		if e == nil {
 			t.Errorf("TestConnBadValHost Expected error, got nil, proto: %s\n", p)
 		}
-		if e != EREQHOST {
+		if !errors.Is(e, EREQHOST) {
 			t.Errorf("TestConnBadValHost Expected <%v>, got <%v>, proto: %s\n",
 				EREQHOST, e, p)
 		}
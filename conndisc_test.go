@@ -17,7 +17,12 @@
 package stompngo
 
 import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
 	"testing"
+	"time"
 )
 
 /*
@@ -227,7 +232,7 @@ func TestConnCDReceipt(t *testing.T) {
 		if e == nil {
 			t.Fatalf("TestConnCDReceipt Expected connect error, got nil\n")
 		}
-		if e != ENORECPT {
+		if !errors.Is(e, ENORECPT) {
 			t.Fatalf("TestConnCDReceipt Expected [%v], got [%v]\n", ENORECPT, e)
 		}
 		// No DISCONNECT checks for this test.
@@ -255,35 +260,35 @@ func TestConnCDEconBad(t *testing.T) {
 		_ = closeConn(t, n)
 		//
 		e = conn.Abort(empty_headers)
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconBad Abort expected [%v] got [%v]\n", ECONBAD, e)
 		}
 		e = conn.Ack(empty_headers)
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconBad Ack expected [%v] got [%v]\n", ECONBAD, e)
 		}
 		e = conn.Begin(empty_headers)
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconBad Begin expected [%v] got [%v]\n", ECONBAD, e)
 		}
 		e = conn.Commit(empty_headers)
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconBad Commit expected [%v] got [%v]\n", ECONBAD, e)
 		}
 		e = conn.Nack(empty_headers)
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconBad Nack expected [%v] got [%v]\n", ECONBAD, e)
 		}
 		e = conn.Send(empty_headers, "")
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconBad Send expected [%v] got [%v]\n", ECONBAD, e)
 		}
 		_, e = conn.Subscribe(empty_headers)
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconBad Subscribe expected [%v] got [%v]\n", ECONBAD, e)
 		}
 		e = conn.Unsubscribe(empty_headers)
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconBad Unsubscribe expected [%v] got [%v]\n", ECONBAD, e)
 		}
 	}
@@ -308,7 +313,7 @@ func TestConnCDEconDiscDone(t *testing.T) {
 		_ = closeConn(t, n)
 		//
 		e = conn.Disconnect(empty_headers)
-		if e != ECONBAD {
+		if !errors.Is(e, ECONBAD) {
 			t.Fatalf("TestConnCDEconDiscDone Previous disconnect expected [%v] got [%v]\n", ECONBAD, e)
 		}
 	}
@@ -365,3 +370,46 @@ func TestConnCDRespData(t *testing.T) {
 		_ = closeConn(t, n)
 	}
 }
+
+/*
+	ConnDisc Test: CONNECTED header decoding.
+*/
+func TestConnCDRespDecode(t *testing.T) {
+	f, e := connectResponse("\r\nCONNECTED\r\nversion:1.2\r\nserver:a\\cb\\nc\r\n\r\n\x00")
+	if e != nil {
+		t.Fatalf("TestConnCDRespDecode Expected no error, got [%v]\n", e)
+	}
+	if f.Command != CONNECTED || f.Headers.Value(HK_VERSION) != SPL_12 ||
+		f.Headers.Value(HK_SERVER) != "a:b\nc" {
+		t.Fatalf("TestConnCDRespDecode Unexpected frame [%q]\n", f)
+	}
+}
+
+/*
+	ConnDisc Test: CONNECT handshake timeout and size limit.
+*/
+func TestConnCDHandshakeLimits(t *testing.T) {
+	for i, resp := range []string{"", "CONNECTED\nserver:" +
+		strings.Repeat("x", 2048)} {
+		cn, sn := net.Pipe()
+		go func() {
+			// Read the CONNECT frame, then stall or send too much
+			_, _ = bufio.NewReader(sn).ReadString(0)
+			_, _ = sn.Write([]byte(resp))
+		}()
+		o := &ConnectOptions{Timeout: 100 * time.Millisecond,
+			MaxConnectedSize: 1024}
+		want := ECONTO
+		if resp != "" {
+			o.Timeout = 10 * time.Second
+			want = ECONSIZE
+		}
+		_, e := ConnectWithOptions(cn, headersProtocol(login_headers, SPL_12), o)
+		if !errors.Is(e, want) {
+			t.Fatalf("TestConnCDHandshakeLimits[%d] Expected [%v], got [%v]\n", i,
+				want, e)
+		}
+		_ = cn.Close()
+		_ = sn.Close()
+	}
+}
@@ -69,6 +69,85 @@ func NewConnector(n net.Conn, h Headers) (STOMPConnector, error) {
 		// Use c
 */
 func Connect(n net.Conn, h Headers) (*Connection, error) {
+	return ConnectWithOptions(n, h, nil)
+}
+
+/*
+	ConnectOptions are optional settings for ConnectWithOptions.  A nil
+	*ConnectOptions gives the defaults.
+*/
+type ConnectOptions struct {
+	// Time allowed from sending CONNECT until CONNECTED is received.  Zero
+	// means no timeout.
+	Timeout time.Duration
+	// Maximum number of bytes read before CONNECTED is complete, including
+	// any leading EOLs.  Zero means DefaultMaxConnectedSize.
+	MaxConnectedSize int
+	// Limits for every frame read from the broker.  Zero values mean the
+	// defaults.
+	FrameLimits FrameLimits
+	// Read MESSAGE bodies into pooled buffers.  Subscribers then give each
+	// body back with MessageData.Release.
+	PoolBodies bool
+	// Write up to WriteBatch frames that are already queued with a single
+	// flush.  Zero means every frame is flushed as it is written.
+	WriteBatch int
+	// How long a batch waits for more frames before the flush.  Zero means
+	// only frames already queued are batched.  Used only with WriteBatch.
+	WriteLinger time.Duration
+	// Size of the buffer for SendAsync frames not yet handed to the writer.
+	// Zero means DefaultSendBuffer.
+	SendBuffer int
+	// What SendAsync does when that buffer is full.
+	SendFullPolicy SendFullPolicy
+	// Compress SEND bodies with CE_GZIP or CE_DEFLATE.  Empty means no
+	// compression.  See CE_GZIP.
+	Compress string
+	// Minimum SEND body size to compress.  Zero means DefaultCompressMin.
+	CompressMin int
+	// Decompress MESSAGE bodies that have a content-encoding header.  Always
+	// on when Compress is set.
+	Decompress bool
+	// Sign and encrypt SEND bodies, and verify and decrypt MESSAGEs, unless
+	// SubscribeCrypto says otherwise for a subscription.  Nil means none.
+	Crypto *Crypto
+	// Interceptors added with Use before the CONNECT frame is written.
+	Interceptors []interface{}
+	// Logger from connection start, in place of any STOMP_LOGGER logger.
+	// See SetLeveledLogger.
+	Logger Logger
+	// Close the network connection at shutdown.  Set by DialURL, before
+	// the reader can see it.
+	ownsConn bool
+}
+
+/*
+	Default cap on the size of the broker CONNECT response.
+*/
+const DefaultMaxConnectedSize = 64 * 1024
+
+/*
+	ConnectWithOptions is Connect with a handshake timeout and a cap on the
+	size of the broker response.  A silent or misbehaving peer fails the
+	connect with ECONTO or ECONSIZE.
+
+	Example:
+		o := &stompngo.ConnectOptions{Timeout: 10 * time.Second}
+		c, e := stompngo.ConnectWithOptions(n, h, o)
+		if e != nil {
+			// Do something sane ...
+		}
+		// Use c
+*/
+func ConnectWithOptions(n net.Conn, h Headers, o *ConnectOptions) (*Connection, error) {
+	c, e := connectWithOptions(n, h, o)
+	return c, newOpError("Connect", CONNECT, h, e)
+}
+
+func connectWithOptions(n net.Conn, h Headers, o *ConnectOptions) (*Connection, error) {
+	if o == nil {
+		o = &ConnectOptions{}
+	}
 	if h == nil {
 		return nil, EHDRNIL
 	}
@@ -78,24 +157,68 @@ func Connect(n net.Conn, h Headers) (*Connection, error) {
 	if _, ok := h.Contains(HK_RECEIPT); ok {
 		return nil, ENORECPT
 	}
+	if e := checkCompress(o.Compress); e != nil {
+		return nil, e
+	}
+	if o.Crypto != nil && o.Crypto.Keys == nil {
+		return nil, ENOKEY
+	}
+	cm := o.CompressMin
+	if cm <= 0 {
+		cm = DefaultCompressMin
+	}
 	ch := h.Clone()
+	wb := o.WriteBatch
+	if wb < 0 {
+		wb = 0
+	}
 	//fmt.Printf("CONDB01\n")
 	c := &Connection{netconn: n,
 		input:             make(chan MessageData, 1),
-		output:            make(chan wiredata),
+		output:            make(chan wiredata, wb),
 		connected:         false,
 		session:           "",
 		protocol:          SPL_10,
 		subs:              make(map[string]*subscription),
+		rcpts:             make(map[string]*Receipt),
 		DisconnectReceipt: MessageData{},
 		ssdc:              make(chan struct{}),
 		wtrsdc:            make(chan struct{}),
+		rdrdc:             make(chan struct{}),
+		wtrdc:             make(chan struct{}),
 		scc:               1,
-		dld:               &deadlineData{}}
+		dld:               &deadlineData{},
+		flim:              o.FrameLimits.resolve(),
+		pbodies:           o.PoolBodies,
+		wbatch:            wb,
+		wlinger:           o.WriteLinger,
+		asize:             o.SendBuffer,
+		apol:              o.SendFullPolicy,
+		cenc:              o.Compress,
+		cmin:              cm,
+		cdec:              o.Decompress || o.Compress != "",
+		cry:               o.Crypto,
+		ownsConn:          o.ownsConn}
+
+	if e := c.Use(o.Interceptors...); e != nil {
+		return nil, e
+	}
+
+	// A WebSocket message holds one frame
+	if w, ok := n.(*WebSocketConn); ok {
+		w.limitFrom(c.flim)
+	}
 
 	// Basic metric data
 	c.mets = &metrics{st: time.Now()}
 
+	// Note the broker endpoint
+	if ep, ok := n.(Endpointer); ok {
+		c.endpoint = ep.Endpoint()
+	} else if ra := n.RemoteAddr(); ra != nil {
+		c.endpoint = ra.String()
+	}
+
 	// Assumed for now
 	c.MessageData = c.input
 
@@ -109,6 +232,9 @@ func Connect(n net.Conn, h Headers) (*Connection, error) {
 		c.SetLogger(log.New(os.Stdout, ln+" ",
 			log.Ldate|log.Lmicroseconds|log.Lshortfile))
 	}
+	if o.Logger != nil {
+		c.SetLeveledLogger(o.Logger)
+	}
 
 	// Initialize elapsed time tracking data if needed
 	c.eltd = nil
@@ -116,6 +242,10 @@ func Connect(n net.Conn, h Headers) (*Connection, error) {
 		c.eltd = &eltmets{}
 	}
 
+	// Bound the whole handshake
+	if o.Timeout > 0 {
+		_ = n.SetDeadline(time.Now().Add(o.Timeout))
+	}
 	// OK, put a CONNECT on the wire
 	c.wtr = bufio.NewWriterSize(n, senv.WriteBufsz()) // Create the writer
 	// fmt.Println("TCDBG", c.wtr.Size())
@@ -133,21 +263,28 @@ func Connect(n net.Conn, h Headers) (*Connection, error) {
 		// fmt.Printf("Frame: %q\n", f)
 	}
 	r := make(chan error)                               // Make the error channel for a write
-	if e := c.writeWireData(wiredata{f, r}); e != nil { // Send the CONNECT frame
+	if e := c.writeWireData(wiredata{frame: f, errchan: r}); e != nil { // Send the CONNECT frame
 		return c, e
 	}
 	e := <-r // Retrieve any error
 	//
 	if e != nil {
 		c.sysAbort() // Shutdown,  we are done with errors
-		return c, e
+		return c, connectTimeout(e)
 	}
 	//fmt.Printf("CONDB03\n")
 	//
-	e = c.connectHandler(ch)
+	ms := o.MaxConnectedSize
+	if ms <= 0 {
+		ms = DefaultMaxConnectedSize
+	}
+	e = c.connectHandler(ch, ms)
 	if e != nil {
 		c.sysAbort() // Shutdown ,  we are done with errors
-		return c, e
+		return c, connectTimeout(e)
+	}
+	if o.Timeout > 0 {
+		_ = n.SetDeadline(time.Time{})
 	}
 	//fmt.Printf("CONDB04\n")
 	// We are connected
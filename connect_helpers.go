@@ -19,6 +19,8 @@ package stompngo
 import (
 	"bufio"
 	"bytes"
+	"io"
+	"net"
 
 	// "fmt"
 	"github.com/gmallard/stompngo/senv"
@@ -34,21 +36,25 @@ func (e *CONNERROR) Error() string {
 	return e.err.Error() + ":" + e.desc
 }
 
+/*
+	Unwrap returns ECONERR.
+*/
+func (e *CONNERROR) Unwrap() error {
+	return e.err
+}
+
 /*
 	Connection handler, one time use during initial connect.
 
 	Handle broker response, react to version incompatabilities, set up session,
 	and if necessary initialize heart beats.
 */
-func (c *Connection) connectHandler(h Headers) (e error) {
+func (c *Connection) connectHandler(h Headers, ms int) (e error) {
 	//fmt.Printf("CHDB01\n")
-	c.rdr = bufio.NewReaderSize(c.netconn, senv.ReadBufsz())
-	b, e := c.rdr.ReadBytes(0)
-	if e != nil {
-		return e
-	}
-	//fmt.Printf("CHDB02\n")
-	f, e := connectResponse(string(b))
+	lr := &limitReader{r: c.netconn, n: int64(ms)}
+	c.rdr = bufio.NewReaderSize(lr, senv.ReadBufsz())
+	f, e := c.readConnectResponse()
+	lr.n = -1 // No limit from now on
 	if e != nil {
 		return e
 	}
@@ -87,73 +93,81 @@ func (c *Connection) connectHandler(h Headers) (e error) {
 }
 
 /*
-	Handle data from the wire after CONNECT is sent. Attempt to create a Frame
-	from the wire data.
+	Read the broker response after CONNECT is sent, using the regular frame
+	reader.  Leading EOLs are skipped.
 
 	Called one time per connection at connection start.
 */
-func connectResponse(s string) (*Frame, error) {
-	//
-	f := new(Frame)
-	f.Headers = Headers{}
-	f.Body = make([]uint8, 0)
-
-	// Get f.Command
-	c := strings.SplitN(s, "\n", 2)
-	if len(c) < 2 {
-		if len(c) == 1 {
-			// fmt.Printf("lenc is: %d, data:%#v\n", len(c), c[0])
-			if bytes.Compare(HandShake, []byte(c[0])) == 0 {
-				return nil, EBADSSLP
-			}
+func (c *Connection) readConnectResponse() (*Frame, error) {
+	b, e := c.rdr.Peek(1)
+	if e != nil {
+		return nil, e
+	}
+	if b[0] == HandShake[0] { // Never the start of a STOMP frame
+		if b, _ = c.rdr.Peek(len(HandShake)); bytes.Equal(b, HandShake) {
+			return nil, EBADSSLP
 		}
-		return nil, EBADFRM
 	}
-	f.Command = c[0]
-	if f.Command != CONNECTED && f.Command != ERROR {
-		return f, EUNKFRM
+	var f Frame
+	for f.Command == "" {
+		f, e = c.readFrameFor(connectCmds)
+		switch e {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF: // Partial frame
+			return &f, EBADFRM
+		case EINVBCMD:
+			return &f, EUNKFRM
+		default:
+			return &f, e
+		}
 	}
-
-	switch c[1] {
-	case "\x00", "\n": // No headers, malformed bodies
-		f.Body = []uint8(c[1])
-		return f, EBADFRM
-	case "\n\x00": // No headers, no body is OK
-		return f, nil
-	default: // Otherwise continue
+	if f.Command == CONNECTED && len(f.Body) > 0 {
+		return &f, EBDYDATA
 	}
+	return &f, nil
+}
 
-	b := strings.SplitN(c[1], "\n\n", 2)
-	if len(b) == 1 { // No Headers, b[0] == body
-		w := []uint8(b[0])
-		f.Body = w[0 : len(w)-1]
-		if f.Command == CONNECTED && len(f.Body) > 0 {
-			return f, EBDYDATA
-		}
-		return f, nil
-	}
+/*
+	Parse broker CONNECT response data.
+*/
+func connectResponse(s string) (*Frame, error) {
+	c := &Connection{rdr: bufio.NewReader(strings.NewReader(s)),
+		dld: &deadlineData{}, flim: FrameLimits{}.resolve()}
+	return c.readConnectResponse()
+}
 
-	// Here:
-	// b[0] - the headers
-	// b[1] - the body
+/*
+	Reader that fails once a byte limit is used up.  A negative limit means no
+	limit.
+*/
+type limitReader struct {
+	r io.Reader
+	n int64
+}
 
-	// Get f.Headers
-	for _, l := range strings.Split(b[0], "\n") {
-		p := strings.SplitN(l, ":", 2)
-		if len(p) < 2 {
-			f.Body = []uint8(p[0]) // Bad feedback
-			return f, EUNKHDR
-		}
-		f.Headers = append(f.Headers, p[0], p[1])
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return l.r.Read(p)
 	}
-	// get f.Body
-	w := []uint8(b[1])
-	f.Body = w[0 : len(w)-1]
-	if f.Command == CONNECTED && len(f.Body) > 0 {
-		return f, EBDYDATA
+	if l.n == 0 {
+		return 0, ECONSIZE
 	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, e := l.r.Read(p)
+	l.n -= int64(n)
+	return n, e
+}
 
-	return f, nil
+/*
+	Report a network timeout during the handshake as ECONTO.
+*/
+func connectTimeout(e error) error {
+	if ne, ok := e.(net.Error); ok && ne.Timeout() {
+		return ECONTO
+	}
+	return e
 }
 
 /*
@@ -188,7 +202,7 @@ func (c *Connection) setProtocolLevel(ch, sh Headers) (e error) {
 	shr := sh.Value(HK_VERSION)
 
 	if chw == shr && Supported(shr) {
-		c.protocol = shr
+		c.setProtocol(shr)
 		return nil
 	}
 	if chw == "" && shr == "" { // Straight up 1.0
@@ -201,7 +215,7 @@ func (c *Connection) setProtocolLevel(ch, sh Headers) (e error) {
 			if !Supported(shr) {
 				return EBADVERSVR // Client and server agree, but we do not support it
 			}
-			c.protocol = shr
+			c.setProtocol(shr)
 			return nil
 		} else {
 			return EBADVERCLI
@@ -213,10 +227,19 @@ func (c *Connection) setProtocolLevel(ch, sh Headers) (e error) {
 		}
 	}
 
-	c.protocol = shr // Could be anything we support
+	c.setProtocol(shr) // Could be anything we support
 	return nil
 }
 
+/*
+	Set the protocol level.  The writer may already be logging it.
+*/
+func (c *Connection) setProtocol(p string) {
+	c.protoLock.Lock()
+	c.protocol = p
+	c.protoLock.Unlock()
+}
+
 /*
 	Internal function, used only during CONNECT processing.
 */
@@ -18,7 +18,6 @@ package stompngo
 
 import (
 	"log"
-	"runtime"
 	"time"
 )
 
@@ -38,6 +37,14 @@ func (c *Connection) Session() string {
 	return c.session
 }
 
+/*
+	Endpoint returns the broker address this connection uses.  For connections
+	obtained from Failover.Dial this is the selected failover endpoint.
+*/
+func (c *Connection) Endpoint() string {
+	return c.endpoint
+}
+
 /*
 	Protocol returns the current connection protocol level.
 */
@@ -48,7 +55,8 @@ func (c *Connection) Protocol() string {
 }
 
 /*
-	SetLogger enables a client defined logger for this connection.
+	SetLogger enables a client defined logger for this connection.  Every
+	level is logged.  See SetLeveledLogger for more control.
 
 	Set to "nil" to disable logging.
 
@@ -58,16 +66,22 @@ func (c *Connection) Protocol() string {
 		c.SetLogger(l)
 */
 func (c *Connection) SetLogger(l *log.Logger) {
-	logLock.Lock()
-	c.logger = l
-	logLock.Unlock()
+	var ll Logger
+	if l != nil {
+		ll = NewStdLogger(l, LogDebug)
+	}
+	c.logLock.Lock()
+	c.logger, c.stdlog = ll, l
+	c.logLock.Unlock()
 }
 
 /*
-	GetLogger - returns the current connection logger.
+	GetLogger - returns the current connection logger, if set by SetLogger.
 */
 func (c *Connection) GetLogger() *log.Logger {
-	return c.logger
+	c.logLock.RLock()
+	defer c.logLock.RUnlock()
+	return c.stdlog
 }
 
 /*
@@ -167,41 +181,6 @@ func (c *Connection) SetSubChanCap(nc int) {
 
 // Unexported Connection methods
 
-/*
-	Log data if possible.
-*/
-func (c *Connection) log(v ...interface{}) {
-	logLock.Lock()
-	defer logLock.Unlock()
-	if c.logger == nil {
-		return
-	}
-	_, fn, ld, ok := runtime.Caller(1)
-
-	if ok {
-		c.logger.Printf("%s %s %d %v\n", c.session, fn, ld, v)
-	} else {
-		c.logger.Printf("%s %v\n", c.session, v)
-	}
-	return
-}
-
-/*
-	Log data if possible (extended / abbreviated) logic).
-*/
-func (c *Connection) logx(v ...interface{}) {
-	_, fn, ld, ok := runtime.Caller(1)
-
-	c.sessLock.Lock()
-	if ok {
-		c.logger.Printf("%s %s %d %v\n", c.session, fn, ld, v)
-	} else {
-		c.logger.Printf("%s %v\n", c.session, v)
-	}
-	c.sessLock.Unlock()
-	return
-}
-
 /*
 	Shutdown heartbeats
 */
@@ -226,7 +205,7 @@ func (c *Connection) shutdownHeartBeats() {
 	Shutdown logic.
 */
 func (c *Connection) shutdown() {
-	c.log("SHUTDOWN", "starts")
+	c.debug("SHUTDOWN starts")
 	c.shutdownHeartBeats()
 	// Close all individual subscribe channels
 	// This is a write lock
@@ -237,7 +216,8 @@ func (c *Connection) shutdown() {
 	}
 	c.setConnected(false)
 	c.subsLock.Unlock()
-	c.log("SHUTDOWN", "ends")
+	c.failReceipts(ECONBAD)
+	c.debug("SHUTDOWN ends")
 	return
 }
 
@@ -253,7 +233,9 @@ func (c *Connection) sysAbort() {
 	Read error handler.
 */
 func (c *Connection) handleReadError(md MessageData) {
-	c.log("HDRERR", "starts", md)
+	c.debug("HDRERR starts", "error", md.Error)
+	md.Error = newOpError("read", md.Message.Command, md.Message.Headers,
+		md.Error)
 	c.shutdownHeartBeats() // We are done here
 	// Notify any general subscriber of error
 	select {
@@ -265,13 +247,16 @@ func (c *Connection) handleReadError(md MessageData) {
 	c.subsLock.RLock()
 	if c.isConnected() {
 		for key := range c.subs {
+			if c.subs[key].bes {
+				continue // Already told, by a BrokerError
+			}
 			c.subs[key].md <- md
 		}
 	}
 	c.subsLock.RUnlock()
 	// Try to catch the writer
 	close(c.wtrsdc)
-	c.log("HDRERR", "ends")
+	c.debug("HDRERR ends")
 	// Let further shutdown logic proceed normally.
 	return
 }
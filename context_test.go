@@ -0,0 +1,127 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test helper.  A fake broker that stops reading when it sees a SEND with a
+	"block" body, until release is closed.  The Pipe connection has no
+	buffering, so the client writer stalls behind it.
+*/
+func blockingBroker(release chan struct{}, swallow string) *fakebroker.Broker {
+	return fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if f.Command == SEND && string(f.Body) == "block" {
+				<-release
+			}
+			return f.Command == swallow
+		}})
+}
+
+/*
+	Test that context variants give up while the writer is stalled, and leave
+	the connection usable.
+*/
+func TestContextStalledWriter(t *testing.T) {
+	release := make(chan struct{})
+	b := blockingBroker(release, "")
+	defer b.Close()
+	n := b.Pipe()
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestContextStalledWriter CONNECT Failed: e:<%q>\n", e)
+	}
+	var cs ContextStomper = conn
+	d := "/queue/context.stalled"
+	sh := Headers{HK_DESTINATION, d}
+	if e = cs.SendContext(context.Background(), sh, "block"); e != nil {
+		t.Fatalf("TestContextStalledWriter Expected no send error, got [%v]\n", e)
+	}
+	// The writer is now stuck behind the broker.
+	for i := 0; i < 2; i++ {
+		ctx, cf := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		e = cs.SendContext(ctx, sh, "timed")
+		cf()
+		if !errors.Is(e, context.DeadlineExceeded) {
+			t.Fatalf("TestContextStalledWriter[%d] Expected [%v], got [%v]\n", i,
+				context.DeadlineExceeded, e)
+		}
+	}
+	ctx, cf := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	sc, e := cs.SubscribeContext(ctx, Headers{HK_DESTINATION, d, HK_ID, "ctxsub"})
+	cf()
+	if !errors.Is(e, context.DeadlineExceeded) || sc != nil {
+		t.Fatalf("TestContextStalledWriter Expected [%v], got [%v]\n",
+			context.DeadlineExceeded, e)
+	}
+	if conn.hasSubscription("ctxsub") {
+		t.Fatalf("TestContextStalledWriter Expected abandoned subscription removed\n")
+	}
+	//
+	close(release)
+	// Everything queued is still written.  The SEND with a receipt is last.
+	if e = conn.Send(Headers{HK_DESTINATION, d, HK_RECEIPT, "ctx1"}, "last"); e != nil {
+		t.Fatalf("TestContextStalledWriter Expected no send error, got [%v]\n", e)
+	}
+	md := <-conn.MessageData
+	if md.Message.Command != RECEIPT {
+		t.Fatalf("TestContextStalledWriter Expected RECEIPT, got [%v]\n",
+			md.Message.Command)
+	}
+	// No subscription survived at the broker, so the messages are retained:
+	// "block", the first "timed" which reached the writer, and "last".  The
+	// second "timed" never reached the writer, and was not sent.
+	if q := b.QueueDepth(d); q != 3 {
+		t.Fatalf("TestContextStalledWriter Expected queue depth 3, got [%v]\n", q)
+	}
+	e = cs.DisconnectContext(context.Background(), empty_headers)
+	checkDisconnectError(t, e)
+	_ = closeConn(t, n)
+}
+
+/*
+	Test that DisconnectContext gives up waiting for a receipt, and still shuts
+	the connection down.
+*/
+func TestContextDisconnectReceipt(t *testing.T) {
+	b := blockingBroker(nil, DISCONNECT)
+	defer b.Close()
+	n := b.Pipe()
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_11))
+	if e != nil {
+		t.Fatalf("TestContextDisconnectReceipt CONNECT Failed: e:<%q>\n", e)
+	}
+	ctx, cf := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cf()
+	e = conn.DisconnectContext(ctx, empty_headers)
+	if !errors.Is(e, context.DeadlineExceeded) {
+		t.Fatalf("TestContextDisconnectReceipt Expected [%v], got [%v]\n",
+			context.DeadlineExceeded, e)
+	}
+	if conn.Connected() {
+		t.Fatalf("TestContextDisconnectReceipt Expected connection shut down\n")
+	}
+	_ = closeConn(t, n)
+}
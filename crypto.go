@@ -0,0 +1,328 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strconv"
+)
+
+/*
+	KeyProvider supplies keys for message signing and encryption.  Keys are
+	named by a key id, which is sent with each message in the StompPlusKeyID
+	header, so that keys can be rotated.  Keys must be at least MinKeySize
+	bytes.
+*/
+type KeyProvider interface {
+	// CurrentKey returns the key id and key for outbound messages.
+	CurrentKey() (string, []byte, error)
+	// Key returns the key for a key id from an inbound message.
+	Key(id string) ([]byte, error)
+}
+
+/*
+	StaticKeys is a KeyProvider with a fixed set of keys.  ID names the key
+	for outbound messages.
+*/
+type StaticKeys struct {
+	ID   string
+	Keys map[string][]byte
+}
+
+/*
+	CurrentKey returns the key named by ID.
+*/
+func (s *StaticKeys) CurrentKey() (string, []byte, error) {
+	k, e := s.Key(s.ID)
+	return s.ID, k, e
+}
+
+/*
+	Key returns the key for a key id, or ENOKEY.
+*/
+func (s *StaticKeys) Key(id string) ([]byte, error) {
+	k, ok := s.Keys[id]
+	if !ok {
+		return nil, ENOKEY
+	}
+	return k, nil
+}
+
+/*
+	Crypto is message level signing and encryption, independent of any TLS to
+	the broker.  See ConnectOptions.Crypto and SubscribeCrypto.
+
+	Outbound SEND bodies are encrypted with AES-256-GCM when Encrypt is set,
+	and then signed with HMAC-SHA256 when Sign is set.  Inbound MESSAGEs must
+	be protected the same way: each is verified and decrypted before it is
+	delivered.  A MESSAGE that fails is still delivered, as read, with a
+	*CryptoError.
+
+	The signature covers the key id, the cipher, the signedHeaders and the
+	body.  Other headers, e.g. user headers, are not protected, and a broker
+	that changes a signed header, such as one that rewrites destinations,
+	fails verification.
+
+	The signing and encryption keys are derived from the provider key.
+	Compression, when used, happens before encryption.  Crypto needs the
+	whole body: SendReader and SendPrepared give ECRYPTBODY, as does a
+	subscription with StompPlusStreamBody.
+*/
+type Crypto struct {
+	Keys    KeyProvider
+	Sign    bool
+	Encrypt bool
+}
+
+/*
+	Message crypto headers.  STOMP Protocol Enhancements.
+*/
+const (
+	StompPlusKeyID     = "sng_kid" // Key id
+	StompPlusSignature = "sng_sig" // Base64 HMAC-SHA256 signature
+	StompPlusCipher    = "sng_enc" // Encryption, CipherAESGCM
+	CipherAESGCM       = "aes-256-gcm"
+)
+
+/*
+	Minimum provider key size, in bytes.
+*/
+const MinKeySize = 16
+
+/*
+	CryptoError is a message that could not be signed, verified, encrypted or
+	decrypted.  Err is ESIGNATURE, EDECRYPT, ENOKEY, EKEYSIZE, ECRYPTBODY,
+	or an error from the KeyProvider.
+*/
+type CryptoError struct {
+	Op    string // "sign", "verify", "encrypt" or "decrypt"
+	KeyID string
+	Err   error
+}
+
+func (e *CryptoError) Error() string {
+	return "stompngo " + e.Op + " key " + strconv.Quote(e.KeyID) + ": " +
+		e.Err.Error()
+}
+
+/*
+	Unwrap returns Err.
+*/
+func (e *CryptoError) Unwrap() error {
+	return e.Err
+}
+
+/*
+	True if cr signs or encrypts.
+*/
+func (cr *Crypto) active() bool {
+	return cr != nil && (cr.Sign || cr.Encrypt)
+}
+
+/*
+	Headers covered by the signature, in signing order.  The body length is
+	covered by the body itself.
+*/
+var signedHeaders = []string{HK_DESTINATION, HK_CONTENT_TYPE,
+	HK_CONTENT_ENCODING}
+
+/*
+	Derive a key for one use from a provider key.
+*/
+func deriveKey(k []byte, use string) []byte {
+	m := hmac.New(sha256.New, k)
+	_, _ = m.Write([]byte("stompngo " + use))
+	return m.Sum(nil)
+}
+
+/*
+	The signature of a body, covering the key id, the cipher, and the
+	signedHeaders of h.  An absent header is signed differently from an
+	empty one.
+*/
+func signature(k []byte, kid, enc string, h Headers, b []byte) []byte {
+	m := hmac.New(sha256.New, deriveKey(k, "sign"))
+	_, _ = m.Write([]byte(kid + "\n" + enc + "\n"))
+	for _, n := range signedHeaders {
+		l := n + "\n"
+		if v, ok := h.Contains(n); ok {
+			l = n + ":" + v + "\n"
+		}
+		_, _ = m.Write([]byte(l))
+	}
+	_, _ = m.Write(b)
+	return m.Sum(nil)
+}
+
+/*
+	The AEAD for a provider key.
+*/
+func gcmFor(k []byte) (cipher.AEAD, error) {
+	b, e := aes.NewCipher(deriveKey(k, "encrypt"))
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(b)
+}
+
+/*
+	Prepare a SEND frame body for the wire: compress, then sign and encrypt.
+	The frame given is not changed.
+*/
+func (c *Connection) sealFrame(f Frame) (Frame, error) {
+	f, e := c.compressFrame(f)
+	if e != nil || c.cry == nil || f.Command != SEND {
+		return f, e
+	}
+	return c.cry.seal(f)
+}
+
+/*
+	Open an inbound MESSAGE for a subscription: verify and decrypt, then
+	decompress.  A body still encrypted is not decompressed.  A vetoed
+	message is left as read.
+*/
+func (c *Connection) openMessage(md *MessageData, ps *subscription) {
+	if md.Error != nil {
+		return
+	}
+	if ps.cry != nil {
+		ps.cry.open(md)
+	}
+	if md.Error != nil || !c.cdec {
+		return
+	}
+	if _, ok := md.Message.Headers.Contains(StompPlusCipher); !ok {
+		c.decompressBody(md)
+	}
+}
+
+/*
+	Sign and encrypt a SEND frame.  The frame given is not changed.
+*/
+func (cr *Crypto) seal(f Frame) (Frame, error) {
+	if !cr.Sign && !cr.Encrypt {
+		return f, nil
+	}
+	kid, k, e := cr.Keys.CurrentKey()
+	if e == nil && len(k) < MinKeySize {
+		e = EKEYSIZE
+	}
+	if e != nil {
+		return f, &CryptoError{Op: "sign", KeyID: kid, Err: e}
+	}
+	h := f.Headers.DeleteAll(StompPlusKeyID).DeleteAll(StompPlusSignature).
+		DeleteAll(StompPlusCipher).DeleteAll(HK_CONTENT_LENGTH)
+	h = h.Add(StompPlusKeyID, kid)
+	// The default content type is signed as it will be sent
+	if _, ok := h.Contains(HK_CONTENT_TYPE); !ok {
+		if _, ok = h.Contains(HK_SUPPRESS_CT); !ok {
+			h = h.Add(HK_CONTENT_TYPE, DFLT_CONTENT_TYPE)
+		}
+	}
+	b, enc := f.Body, ""
+	if cr.Encrypt {
+		a, e := gcmFor(k)
+		if e != nil {
+			return f, &CryptoError{Op: "encrypt", KeyID: kid, Err: e}
+		}
+		n := make([]byte, a.NonceSize(), a.NonceSize()+len(b)+a.Overhead())
+		if _, e = io.ReadFull(rand.Reader, n); e != nil {
+			return f, &CryptoError{Op: "encrypt", KeyID: kid, Err: e}
+		}
+		b, enc = a.Seal(n, n, b, []byte(kid)), CipherAESGCM
+		h = h.Add(StompPlusCipher, enc)
+	}
+	if cr.Sign {
+		s := signature(k, kid, enc, h, b)
+		h = h.Add(StompPlusSignature, base64.StdEncoding.EncodeToString(s))
+	}
+	return Frame{f.Command, h, b}, nil
+}
+
+/*
+	Verify and decrypt an inbound MESSAGE.  A failure is set as md.Error, and
+	the message is left as read.
+*/
+func (cr *Crypto) open(md *MessageData) {
+	if !cr.active() {
+		return
+	}
+	h := md.Message.Headers
+	kid := h.Value(StompPlusKeyID)
+	fail := func(op string, e error) {
+		md.Error = &CryptoError{Op: op, KeyID: kid, Err: e}
+	}
+	if md.BodyReader != nil {
+		fail("verify", ECRYPTBODY) // Never read
+		return
+	}
+	k, e := cr.Keys.Key(kid)
+	if e == nil && len(k) < MinKeySize {
+		e = EKEYSIZE
+	}
+	if e != nil {
+		fail("verify", e)
+		return
+	}
+	b := md.Message.Body
+	enc, encok := h.Contains(StompPlusCipher)
+	if cr.Sign {
+		s, e := base64.StdEncoding.DecodeString(h.Value(StompPlusSignature))
+		if e != nil || !hmac.Equal(s, signature(k, kid, enc, h, b)) {
+			fail("verify", ESIGNATURE)
+			return
+		}
+	}
+	if cr.Encrypt {
+		if enc != CipherAESGCM {
+			fail("decrypt", EDECRYPT)
+			return
+		}
+		a, e := gcmFor(k)
+		if e != nil {
+			fail("decrypt", e)
+			return
+		}
+		if len(b) < a.NonceSize() {
+			fail("decrypt", EDECRYPT)
+			return
+		}
+		p, e := a.Open(nil, b[:a.NonceSize()], b[a.NonceSize():], []byte(kid))
+		if e != nil {
+			fail("decrypt", EDECRYPT)
+			return
+		}
+		md.Release() // A pooled cipher text
+		md.Message.Body = p
+	} else if encok {
+		fail("decrypt", EDECRYPT) // Encrypted, but not expected
+		return
+	}
+	h = h.DeleteAll(StompPlusSignature).DeleteAll(StompPlusCipher)
+	if _, ok := h.Contains(HK_CONTENT_LENGTH); ok {
+		h = h.DeleteAll(HK_CONTENT_LENGTH).Add(HK_CONTENT_LENGTH,
+			strconv.Itoa(len(md.Message.Body)))
+	}
+	md.Message.Headers = h
+}
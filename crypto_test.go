@@ -0,0 +1,222 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test signed and encrypted messages, and tampered messages.
+*/
+func TestCrypto(t *testing.T) {
+	keys := &StaticKeys{ID: "k1", Keys: map[string][]byte{
+		"k1":    bytes.Repeat([]byte("k"), 32),
+		"short": []byte("k")}}
+	cr := &Crypto{Keys: keys, Sign: true, Encrypt: true}
+	b := fakebroker.New(nil)
+	defer b.Close()
+	if _, e := ConnectWithOptions(b.Pipe(), Headers{},
+		&ConnectOptions{Crypto: &Crypto{}}); !errors.Is(e, ENOKEY) {
+		t.Fatalf("TestCrypto Expected [%v], got [%v]\n", ENOKEY, e)
+	}
+	conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+		SPL_12), &ConnectOptions{Crypto: cr, Compress: CE_GZIP})
+	if e != nil {
+		t.Fatalf("TestCrypto CONNECT Failed: e:<%q>\n", e)
+	}
+	plain, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestCrypto CONNECT Failed: e:<%q>\n", e)
+	}
+	ds, dp := tdest("/queue/crypto.secure"), tdest("/queue/crypto.plain")
+	ssc, e := conn.Subscribe(Headers{HK_DESTINATION, ds, HK_ID, "secure"})
+	if e != nil {
+		t.Fatalf("TestCrypto Expected no subscribe error, got [%v]\n", e)
+	}
+	// The same connection, but no crypto for this subscription
+	psc, e := conn.SubscribeCrypto(Headers{HK_DESTINATION, dp, HK_ID, "plain"},
+		nil)
+	if e != nil {
+		t.Fatalf("TestCrypto Expected no subscribe error, got [%v]\n", e)
+	}
+	body := bytes.Repeat([]byte("secret message "), 100)
+	for _, d := range []string{ds, dp} {
+		if e = conn.SendBytes(Headers{HK_DESTINATION, d}, body); e != nil {
+			t.Fatalf("TestCrypto Expected no send error, got [%v]\n", e)
+		}
+	}
+	md := <-ssc
+	if md.Error != nil || !bytes.Equal(md.Message.Body, body) ||
+		md.Message.Headers.Value(StompPlusKeyID) != "k1" ||
+		md.Message.Headers.Value(StompPlusSignature) != "" {
+		t.Fatalf("TestCrypto Expected [%d] bytes, got [%d] [%q] [%v]\n",
+			len(body), len(md.Message.Body), md.Message.Headers, md.Error)
+	}
+	md = <-psc
+	if md.Error != nil || bytes.Contains(md.Message.Body, []byte("secret")) ||
+		md.Message.Headers.Value(StompPlusCipher) != CipherAESGCM {
+		t.Fatalf("TestCrypto Expected cipher text, got [%q] [%v]\n",
+			md.Message.Headers, md.Error)
+	}
+	// Tampered, unsigned, and unknown or bad keys, all delivered with errors
+	sig := md.Message.Headers.Value(StompPlusSignature)
+	ct := md.Message.Body
+	// Signed for another destination
+	h := md.Message.Headers.Delete(HK_DESTINATION).Add(HK_DESTINATION, ds)
+	if e = plain.SendBytes(h, ct); e != nil {
+		t.Fatalf("TestCrypto Expected no send error, got [%v]\n", e)
+	}
+	if md = <-ssc; !errors.Is(md.Error, ESIGNATURE) {
+		t.Fatalf("TestCrypto Expected [%v], got [%v]\n", ESIGNATURE, md.Error)
+	}
+	ct[len(ct)-1] ^= 1
+	for _, tv := range []struct {
+		h    Headers
+		want error
+	}{
+		{Headers{StompPlusKeyID, "k1", StompPlusCipher, CipherAESGCM,
+			StompPlusSignature, sig}, ESIGNATURE},
+		{Headers{StompPlusKeyID, "k1", StompPlusCipher, CipherAESGCM}, ESIGNATURE},
+		{Headers{StompPlusKeyID, "k2"}, ENOKEY},
+		{Headers{StompPlusKeyID, "short"}, EKEYSIZE},
+	} {
+		h := Headers{HK_DESTINATION, ds}.AddHeaders(tv.h)
+		if e = plain.SendBytes(h, ct); e != nil {
+			t.Fatalf("TestCrypto Expected no send error, got [%v]\n", e)
+		}
+		md = <-ssc
+		var ce *CryptoError
+		if !errors.Is(md.Error, tv.want) || !errors.As(md.Error, &ce) ||
+			!bytes.Equal(md.Message.Body, ct) {
+			t.Fatalf("TestCrypto Expected [%v], got [%v]\n", tv.want, md.Error)
+		}
+	}
+	// Signed headers, as sent and changed
+	f, e := cr.seal(Frame{SEND, Headers{HK_DESTINATION, ds,
+		HK_CONTENT_TYPE, "text/plain"}, body})
+	if e != nil {
+		t.Fatalf("TestCrypto Expected no seal error, got [%v]\n", e)
+	}
+	for _, tv := range []struct {
+		ct   string
+		want error
+	}{
+		{"text/plain", nil},
+		{"text/html", ESIGNATURE},
+	} {
+		h := f.Headers.Delete(HK_CONTENT_TYPE).Add(HK_CONTENT_TYPE, tv.ct)
+		if e = plain.SendBytes(h, f.Body); e != nil {
+			t.Fatalf("TestCrypto Expected no send error, got [%v]\n", e)
+		}
+		if md = <-ssc; !errors.Is(md.Error, tv.want) {
+			t.Fatalf("TestCrypto Expected [%v], got [%v]\n", tv.want, md.Error)
+		}
+	}
+	// Crypto needs the whole body
+	if e = conn.SendReader(Headers{HK_DESTINATION, ds}, strings.NewReader("x"),
+		1); !errors.Is(e, ECRYPTBODY) {
+		t.Fatalf("TestCrypto Expected [%v], got [%v]\n", ECRYPTBODY, e)
+	}
+	p, e := conn.PrepareSend(Headers{HK_DESTINATION, ds})
+	if e != nil {
+		t.Fatalf("TestCrypto Expected no prepare error, got [%v]\n", e)
+	}
+	if e = conn.SendPrepared(p, []byte("x")); !errors.Is(e, ECRYPTBODY) {
+		t.Fatalf("TestCrypto Expected [%v], got [%v]\n", ECRYPTBODY, e)
+	}
+	sh := Headers{HK_DESTINATION, dp, HK_ID, "stream", StompPlusStreamBody, "0"}
+	if _, e = conn.Subscribe(sh); !errors.Is(e, ECRYPTBODY) {
+		t.Fatalf("TestCrypto Expected [%v], got [%v]\n", ECRYPTBODY, e)
+	}
+	if _, e = conn.SubscribeCrypto(sh, nil); e != nil {
+		t.Fatalf("TestCrypto Expected no subscribe error, got [%v]\n", e)
+	}
+	// Encryption with a bad key fails the send
+	keys.ID = "short"
+	if e = conn.Send(Headers{HK_DESTINATION, ds}, "x"); !errors.Is(e, EKEYSIZE) {
+		t.Fatalf("TestCrypto Expected [%v], got [%v]\n", EKEYSIZE, e)
+	}
+	checkDisconnectError(t, plain.Disconnect(empty_headers))
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+}
+
+/*
+	Test helper.  Keys that wait to be released for inbound messages.
+*/
+type gatedKeys struct {
+	StaticKeys
+	in   chan struct{}
+	gate chan struct{}
+}
+
+func (g *gatedKeys) Key(id string) ([]byte, error) {
+	g.in <- struct{}{}
+	<-g.gate
+	return g.StaticKeys.Key(id)
+}
+
+/*
+	Test that subscriptions can change while a message is being verified.
+*/
+func TestCryptoSlowKey(t *testing.T) {
+	keys := &gatedKeys{StaticKeys: StaticKeys{ID: "k1", Keys: map[string][]byte{
+		"k1": bytes.Repeat([]byte("k"), 32)}},
+		in: make(chan struct{}, 1), gate: make(chan struct{})}
+	b := fakebroker.New(nil)
+	defer b.Close()
+	conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+		SPL_12), &ConnectOptions{Crypto: &Crypto{Keys: keys, Sign: true}})
+	if e != nil {
+		t.Fatalf("TestCryptoSlowKey CONNECT Failed: e:<%q>\n", e)
+	}
+	d := tdest("/queue/crypto.slow")
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "slow"})
+	if e != nil {
+		t.Fatalf("TestCryptoSlowKey Expected no subscribe error, got [%v]\n", e)
+	}
+	if e = conn.Send(Headers{HK_DESTINATION, d}, "slow"); e != nil {
+		t.Fatalf("TestCryptoSlowKey Expected no send error, got [%v]\n", e)
+	}
+	<-keys.in // The reader is verifying
+	done := make(chan error, 1)
+	go func() {
+		_, e := conn.Subscribe(Headers{HK_DESTINATION, d + ".other",
+			HK_ID, "other"})
+		done <- e
+	}()
+	select {
+	case e = <-done:
+		if e != nil {
+			t.Fatalf("TestCryptoSlowKey Expected no subscribe error, got [%v]\n", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TestCryptoSlowKey Expected subscribe, got timeout\n")
+	}
+	close(keys.gate)
+	if md := <-sc; md.Error != nil || md.Message.BodyString() != "slow" {
+		t.Fatalf("TestCryptoSlowKey Expected [slow], got [%q] [%v]\n",
+			md.Message.Body, md.Error)
+	}
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+}
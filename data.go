@@ -18,6 +18,8 @@ package stompngo
 
 import (
 	"bufio"
+	"context"
+	"io"
 	"log"
 	"net"
 	"sync"
@@ -93,6 +95,9 @@ type Frame Message
 type MessageData struct {
 	Message Message
 	Error   error
+	// The MESSAGE body, when the subscription streams bodies.  See
+	// StompPlusStreamBody.  Message.Body is empty in that case.
+	BodyReader io.ReadCloser
 }
 
 /*
@@ -101,6 +106,10 @@ type MessageData struct {
 type wiredata struct {
 	frame   Frame
 	errchan chan error
+	body    io.Reader      // Streamed body, or nil
+	bodylen int64          // Streamed body length
+	pf      *PreparedFrame // Prepared headers for frame, or nil
+	wlen    int64          // Bytes written, set by the writer
 }
 
 /*
@@ -120,6 +129,49 @@ type Stomper interface {
 	SendBytes(h Headers, b []byte) error
 }
 
+/*
+	ContextStomper is an interface that models STOMP specification commands
+	which stop waiting when a context is done.
+
+	A context that is done before the frame is handed to the connection's
+	writer means the frame is not sent, and the connection is unchanged.  A
+	context that is done later means the frame is still written in full, but
+	the caller does not wait for that.  In both cases the context error is
+	returned.  See SubscribeContext, UnsubscribeContext and DisconnectContext
+	for their additional cleanup rules.
+*/
+type ContextStomper interface {
+	AbortContext(ctx context.Context, h Headers) error
+	AckContext(ctx context.Context, h Headers) error
+	BeginContext(ctx context.Context, h Headers) error
+	CommitContext(ctx context.Context, h Headers) error
+	DisconnectContext(ctx context.Context, h Headers) error
+	NackContext(ctx context.Context, h Headers) error
+	SendContext(ctx context.Context, h Headers, b string) error
+	SubscribeContext(ctx context.Context, h Headers) (<-chan MessageData, error)
+	UnsubscribeContext(ctx context.Context, h Headers) error
+	//
+	SendBytesContext(ctx context.Context, h Headers, b []byte) error
+}
+
+/*
+	ReceiptStomper is an interface that models STOMP specification commands
+	sent with a receipt request.  Each returns a Receipt future.
+*/
+type ReceiptStomper interface {
+	AbortWithReceipt(h Headers) (*Receipt, error)
+	AckWithReceipt(h Headers) (*Receipt, error)
+	BeginWithReceipt(h Headers) (*Receipt, error)
+	CommitWithReceipt(h Headers) (*Receipt, error)
+	NackWithReceipt(h Headers) (*Receipt, error)
+	SendWithReceipt(h Headers, b string) (*Receipt, error)
+	SubscribeWithReceipt(h Headers) (<-chan MessageData, *Receipt, error)
+	UnsubscribeWithReceipt(h Headers) (*Receipt, error)
+	//
+	SendBytesWithReceipt(h Headers, b []byte) (*Receipt, error)
+	NewReceipt() (*Receipt, error)
+}
+
 /*
 	StatsReader is an interface that modela a reader for the statistics
 	maintained by the stompngo package.
@@ -168,6 +220,15 @@ type Monitor interface {
 	SubChanCap() int
 }
 
+/*
+	Endpointer is implemented by values that know the broker endpoint they
+	use: Connection, Reconnector, and network connections from
+	Failover.Dial.
+*/
+type Endpointer interface {
+	Endpoint() string
+}
+
 /*
 	ParmHandler is an interface that models stompngo client parameter
 	specification.
@@ -178,8 +239,28 @@ type ParmHandler interface {
 	SetSubChanCap(nc int)
 }
 
+/*
+	LeveledLogHandler is an interface that models setting the leveled Logger
+	of a stompngo connection.
+*/
+type LeveledLogHandler interface {
+	SetLeveledLogger(l Logger)
+	GetLeveledLogger() Logger
+}
+
+/*
+	BrokerErrorHandler is an interface that models the broker ERROR callback
+	of a stompngo connection.
+*/
+type BrokerErrorHandler interface {
+	OnBrokerError(f BrokerErrorNotification)
+}
+
 /*
 	STOMPConnector is an interface that encapsulates the Connection struct.
+	Connection also implements ContextStomper, ReceiptStomper,
+	LeveledLogHandler, BrokerErrorHandler and Endpointer, which are not part
+	of STOMPConnector so that existing implementations still satisfy it.
 */
 type STOMPConnector interface {
 	Stomper
@@ -212,17 +293,47 @@ type Connection struct {
 	ssdc              chan struct{} // System shutdown channel
 	abortOnce         sync.Once     // Ensure close ssdc once
 	wtrsdc            chan struct{} // Special writer shutdown channel
+	rdrdc             chan struct{} // Closed when the reader ends
+	wtrdc             chan struct{} // Closed when the writer ends
 	hbd               *heartBeatData
 	wtr               *bufio.Writer
 	rdr               *bufio.Reader
 	Hbrf              bool // Indicates a heart beat read/receive failure, which is possibly transient.  Valid for 1.1+ only.
 	Hbsf              bool // Indicates a heart beat send failure, which is possibly transient.  Valid for 1.1+ only.
-	logger            *log.Logger
+	logger            Logger
+	stdlog            *log.Logger   // Set by SetLogger
+	logLock           sync.RWMutex  // logger lock
 	mets              *metrics      // Client metrics
 	scc               int           // Subscribe channel capacity
 	discLock          sync.Mutex    // DISCONNECT lock
 	dld               *deadlineData // Deadline data
 	eltd              *eltmets      // Elapsed time data
+	endpoint          string        // Broker address actually used
+	ownsConn          bool          // Close netconn at shutdown
+	rcpts             map[string]*Receipt
+	rcptLock          sync.Mutex // Receipt map lock
+	rcptErr           error      // Set when pending receipts were failed
+	benotify          BrokerErrorNotification
+	beLock            sync.Mutex     // benotify lock
+	flim              FrameLimits    // Resolved inbound frame limits
+	rbs               *bodyStream    // Body stream for the frame just read
+	pbodies           bool           // Pool MESSAGE bodies
+	wbatch            int            // Maximum frames per flush, 0 is no batching
+	wlinger           time.Duration  // Wait for more frames in a batch
+	wbuf              []wiredata     // Current write batch
+	wbe               []error        // Current write batch results
+	asize             int            // Asynchronous send buffer size
+	apol              SendFullPolicy // Asynchronous send full buffer policy
+	abuf              chan wiredata  // Asynchronous send buffer
+	aonce             sync.Once      // Start the asynchronous send pump once
+	alock             sync.RWMutex   // aclosed lock, held for reads by senders
+	aclosed           bool           // Asynchronous sends fail at once
+	cenc              string         // SEND body content-encoding, or none
+	cmin              int            // Minimum SEND body size to compress
+	cdec              bool           // Decompress MESSAGE bodies
+	cry               *Crypto        // Message signing and encryption
+	icpts             icptChains     // Interceptor chains, see Use
+	icptLock          sync.RWMutex   // icpts lock
 }
 
 type subscription struct {
@@ -233,6 +344,9 @@ type subscription struct {
 	drav bool             // Drain After value validity
 	dra  uint             // Start draining after # messages (MESSAGE frames)
 	drmc uint             // Current drain count if draining
+	bes  bool             // BrokerError sent, the last MessageData
+	sbt  int64            // Stream bodies at least this large, if > 0
+	cry  *Crypto          // Verify and decrypt MESSAGEs, if not nil
 }
 
 /*
@@ -330,6 +444,76 @@ const (
 
 	// DISCONNECT timeout
 	EDISCTO = Error("DISCONNECT timeout")
+
+	// CONNECT handshake limits
+	ECONTO   = Error("CONNECT timeout")
+	ECONSIZE = Error("CONNECT response too large")
+
+	// Receipt futures
+	EDUPRID = Error("duplicate receipt id")
+
+	// Inbound frame limits, see FrameLimitError
+	EFRAMELIMIT = Error("inbound frame limit exceeded")
+
+	// Protocol violations, see ProtocolError
+	EPROTOVIOL = Error("STOMP protocol violation")
+
+	// Undefined escape sequence in a STOMP 1.2 header
+	EHDRESC = Error("invalid header escape sequence")
+
+	// Streamed bodies
+	ESTRMLEN  = Error("stream body needs a reader and a length of 0 or more")
+	ESTRMSIZE = Error("invalid sng_stream value")
+
+	// Asynchronous sends, see SendFullPolicy
+	ESENDFULL = Error("asynchronous send buffer full")
+	ESENDDROP = Error("asynchronous send dropped from a full buffer")
+
+	// A PreparedFrame used with a different protocol level
+	EPREPPROTO = Error("prepared frame protocol level mismatch")
+
+	// Body codecs
+	ENOCODEC  = Error("no codec for content-type")
+	ECODECVAL = Error("value not supported by codec")
+
+	// Body compression
+	ECOMPRESS = Error("unsupported content-encoding")
+	EDECOMP   = Error("body decompression failed")
+
+	// Message crypto, see CryptoError
+	ESIGNATURE = Error("message signature missing or invalid")
+	EDECRYPT   = Error("message decryption failed")
+	ENOKEY     = Error("no key for key id")
+	EKEYSIZE   = Error("key too short")
+	ECRYPTBODY = Error("message crypto needs a complete body")
+
+	// Interceptors, see Use
+	EBADICPT = Error("not an inbound or outbound interceptor")
+	ESWALLOW = Error("frame swallowed by an interceptor")
+
+	// Request and reply
+	EREQCLOSED = Error("requester closed")
+	EDUPCID    = Error("duplicate correlation-id")
+	ENOREPLYTO = Error("no reply-to header")
+
+	// Reconnect attempts exhausted
+	ERECONN = Error("reconnect attempts exhausted")
+
+	// Failover errors
+	EFOBADURL = Error("invalid failover URL")
+	EFOSCHEME = Error("unsupported scheme, failover URL")
+	EFOBADOPT = Error("invalid option, failover URL")
+	EFONOEP   = Error("no endpoints, failover")
+
+	// Broker URL errors
+	EBADURL    = Error("invalid broker URL")
+	EURLSCHEME = Error("unsupported scheme, broker URL")
+	ETLSCA     = Error("no certificates found, CA file")
+
+	// WebSocket errors
+	EWSHANDSHAKE = Error("websocket handshake failed")
+	EWSPROTO     = Error("websocket protocol error")
+	EWSMSGSIZE   = Error("websocket message too large")
 )
 
 /*
@@ -408,7 +592,7 @@ type metrics struct {
 */
 var validCmds = map[string]bool{MESSAGE: true, ERROR: true, RECEIPT: true}
 
-var logLock sync.Mutex
+var connectCmds = map[string]bool{CONNECTED: true, ERROR: true}
 
 const (
 	NetProtoTCP = "tcp" // Protocol Name
@@ -424,6 +608,7 @@ const (
 	HK_ACK            = "ack"
 	HK_CONTENT_TYPE   = "content-type"
 	HK_CONTENT_LENGTH = "content-length"
+	HK_CORRELATION_ID = "correlation-id"
 	HK_DESTINATION    = "destination"
 	HK_HEART_BEAT     = "heart-beat"
 	HK_HOST           = "host" // HK_VHOST aloas
@@ -436,6 +621,7 @@ const (
 	HK_PASSCODE       = "passcode"
 	HK_RECEIPT        = "receipt"
 	HK_RECEIPT_ID     = "receipt-id"
+	HK_REPLY_TO       = "reply-to"
 	HK_SESSION        = "session"
 	HK_SERVER         = "server"
 	HK_SUBSCRIPTION   = "subscription"
@@ -472,6 +658,7 @@ const (
 const (
 	StompPlusDrainAfter = "sng_drafter" // SUBSCRIBE Header
 	StompPlusDrainNow   = "sng_drnow"   // UNSUBSCRIBE Header
+	StompPlusStreamBody = "sng_stream"  // SUBSCRIBE Header, minimum bytes
 )
 
 var (
@@ -168,3 +168,17 @@ func BenchmarkHeaderAppend(b *testing.B) {
 		_ = append(h, "akey", "avalue")
 	}
 }
+
+/*
+	Data Test: Connection implements the connection interfaces.  A failure
+	is a compile error.
+*/
+func TestDataInterfaces(t *testing.T) {
+	c := &Connection{}
+	var _ STOMPConnector = c
+	var _ ContextStomper = c
+	var _ ReceiptStomper = c
+	var _ LeveledLogHandler = c
+	var _ BrokerErrorHandler = c
+	var _ Endpointer = c
+}
@@ -49,7 +49,7 @@ type deadlineData struct {
 	WriteDeadline sets the write deadline duration.
 */
 func (c *Connection) WriteDeadline(d time.Duration) {
-	c.log("Write Deadline", d)
+	c.debug("Write Deadline", "duration", d)
 	c.dld.wdld = d
 	c.dld.wds = true
 }
@@ -58,7 +58,7 @@ func (c *Connection) WriteDeadline(d time.Duration) {
 	EnableWriteDeadline enables/disables the use of write deadlines.
 */
 func (c *Connection) EnableWriteDeadline(e bool) {
-	c.log("Enable Write Deadline", e)
+	c.debug("Enable Write Deadline", "enabled", e)
 	c.dld.wde = e
 }
 
@@ -66,7 +66,7 @@ func (c *Connection) EnableWriteDeadline(e bool) {
 	ExpiredNotification sets the expired notification callback function.
 */
 func (c *Connection) ExpiredNotification(enf ExpiredNotification) {
-	c.log("Set ExpiredNotification")
+	c.debug("Set ExpiredNotification")
 	c.dld.dlnotify = enf
 	c.dld.dns = true
 }
@@ -83,7 +83,7 @@ func (c *Connection) IsWriteDeadlineEnabled() bool {
 	ReadDeadline sets the write deadline duration.
 */
 func (c *Connection) ReadDeadline(d time.Duration) {
-	c.log("Read Deadline", d)
+	c.debug("Read Deadline", "duration", d)
 	c.dld.rdld = d
 	c.dld.rds = true
 }
@@ -92,7 +92,7 @@ func (c *Connection) ReadDeadline(d time.Duration) {
 	EnableReadDeadline enables/disables the use of read deadlines.
 */
 func (c *Connection) EnableReadDeadline(e bool) {
-	c.log("Enable Read Deadline", e)
+	c.debug("Enable Read Deadline", "enabled", e)
 	c.dld.rde = e
 }
 
@@ -0,0 +1,231 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+	"github.com/gmallard/stompngo/senv"
+)
+
+/*
+	Test CONNECT headers built from broker URLs.
+*/
+func TestDialURLHeaders(t *testing.T) {
+	u, _ := url.Parse("stomp+ssl://usr:pw@h1:61614/vh?heart-beat=100,200&accept-version=1.2")
+	h, e := urlHeaders(u, Headers{HK_HOST, "ignored", "client-id", "cid"})
+	if e != nil {
+		t.Fatalf("TestDialURLHeaders Expected no error, got [%v]\n", e)
+	}
+	want := Headers{HK_LOGIN, "usr", HK_PASSCODE, "pw", HK_HOST, "vh",
+		HK_ACCEPT_VERSION, "1.2", HK_HEART_BEAT, "100,200", "client-id", "cid"}
+	if !h.Compare(want) {
+		t.Fatalf("TestDialURLHeaders Expected [%v], got [%v]\n", want, h)
+	}
+	//
+	u, _ = url.Parse("tcp://h2")
+	h, e = urlHeaders(u, nil)
+	if e != nil {
+		t.Fatalf("TestDialURLHeaders Expected no error, got [%v]\n", e)
+	}
+	if h.Value(HK_HOST) != "h2" || h.Value(HK_ACCEPT_VERSION) != "1.0,1.1,1.2" {
+		t.Fatalf("TestDialURLHeaders Unexpected defaults [%v]\n", h)
+	}
+	//
+	for _, bad := range []string{"tcp:///vh", "tcp://h?login=x", "http://h:1"} {
+		if _, e = DialURL(bad, nil); e == nil {
+			t.Fatalf("TestDialURLHeaders Expected error for [%v], got nil\n", bad)
+		}
+	}
+	// Passcodes never appear in errors
+	for _, bad := range []string{"stomp://usr:s3cret@/vh", "stomp://usr:s3cret@h:x/vh",
+		"stomp://usr:s3cret@h/vh?passcode=x"} {
+		if _, e = DialURL(bad, nil); e == nil || strings.Contains(e.Error(), "s3cret") {
+			t.Fatalf("TestDialURLHeaders Expected redacted error for [%v], got [%v]\n",
+				bad, e)
+		}
+	}
+}
+
+/*
+	Test that a failed CONNECT gives no Connection.
+*/
+func TestDialURLRefused(t *testing.T) {
+	b, e := fakebroker.Start(&fakebroker.Config{Login: "usr", Passcode: "pw"})
+	if e != nil {
+		t.Fatalf("TestDialURLRefused Expected no listen error, got [%v]\n", e)
+	}
+	defer b.Close()
+	conn, e := DialURL("stomp://usr:s3cret@"+b.Addr()+"?accept-version=1.2", nil)
+	if e == nil || conn != nil || strings.Contains(e.Error(), "s3cret") {
+		t.Fatalf("TestDialURLRefused Expected error and no connection, got [%v] [%v]\n",
+			conn, e)
+	}
+}
+
+/*
+	Test DialURL over plain TCP.  The connection owns, and closes, its socket.
+*/
+func TestDialURLTCP(t *testing.T) {
+	h, p := senv.HostAndPort()
+	u := "stomp://" + testuser + ":" + testpw + "@" + net.JoinHostPort(h, p) +
+		"/" + senv.Vhost() + "?accept-version=1.1"
+	conn, e := DialURL(u, &DialOptions{Timeout: 5 * time.Second})
+	if e != nil {
+		t.Fatalf("TestDialURLTCP Expected no error, got [%v]\n", e)
+	}
+	if conn.Protocol() != SPL_11 {
+		t.Fatalf("TestDialURLTCP Expected protocol [%v], got [%v]\n", SPL_11,
+			conn.Protocol())
+	}
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	checkClosed(t, conn.netconn)
+}
+
+/*
+	Test that DialURL closes its socket when the broker drops the connection.
+*/
+func TestDialURLDrop(t *testing.T) {
+	b, e := fakebroker.Start(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if f.Command == CONNECT {
+				s.Send((&fakebroker.Frame{Command: CONNECTED}).Add(HK_VERSION,
+					SPL_12))
+				s.Close() // Before DialURL returns
+				return true
+			}
+			return false
+		}})
+	if e != nil {
+		t.Fatalf("TestDialURLDrop Expected no listen error, got [%v]\n", e)
+	}
+	defer b.Close()
+	conn, e := DialURL("stomp://"+b.Addr()+"?accept-version=1.2", nil)
+	if e != nil {
+		t.Fatalf("TestDialURLDrop Expected no error, got [%v]\n", e)
+	}
+	<-conn.rdrdc
+	for i := 0; conn.netconn.SetDeadline(time.Time{}) == nil; i++ {
+		if i == 500 {
+			t.Fatalf("Expected a closed network connection\n")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+/*
+	Test DialURL over TLS, verifying the broker with a CA file.
+*/
+func TestDialURLTLS(t *testing.T) {
+	dir, e := ioutil.TempDir("", "stompngo")
+	if e != nil {
+		t.Fatalf("TestDialURLTLS Expected no error, got [%v]\n", e)
+	}
+	defer os.RemoveAll(dir)
+	cert, ca := selfSigned(t, "localhost")
+	caf := filepath.Join(dir, "ca.pem")
+	if e = ioutil.WriteFile(caf, ca, 0600); e != nil {
+		t.Fatalf("TestDialURLTLS Expected no error, got [%v]\n", e)
+	}
+	//
+	b := fakebroker.New(nil)
+	defer b.Close()
+	l, e := tls.Listen(NetProtoTCP, "127.0.0.1:0",
+		&tls.Config{Certificates: []tls.Certificate{cert}})
+	if e != nil {
+		t.Fatalf("TestDialURLTLS Expected no listen error, got [%v]\n", e)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			n, e := l.Accept()
+			if e != nil {
+				return
+			}
+			b.ServeConn(n)
+		}
+	}()
+	u := "stomp+ssl://" + l.Addr().String() + "/"
+	// The certificate is not valid for the IP address
+	if _, e = DialURL(u, &DialOptions{CAFile: caf}); e == nil {
+		t.Fatalf("TestDialURLTLS Expected verification error, got nil\n")
+	}
+	conn, e := DialURL(u, &DialOptions{CAFile: caf, ServerName: "localhost"})
+	if e != nil {
+		t.Fatalf("TestDialURLTLS Expected no error, got [%v]\n", e)
+	}
+	if _, ok := conn.netconn.(*tls.Conn); !ok {
+		t.Fatalf("TestDialURLTLS Expected a TLS connection, got [%T]\n",
+			conn.netconn)
+	}
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	checkClosed(t, conn.netconn)
+}
+
+/*
+	Test helper.  Check that a network connection was closed locally.
+*/
+func checkClosed(t *testing.T, n net.Conn) {
+	if _, e := n.Write([]byte{0}); e == nil {
+		t.Fatalf("Expected a closed network connection\n")
+	}
+}
+
+/*
+	Test helper.  A self-signed certificate for host, and its PEM encoding.
+*/
+func selfSigned(t *testing.T, host string) (tls.Certificate, []byte) {
+	k, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		t.Fatalf("selfSigned Expected no error, got [%v]\n", e)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, e := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &k.PublicKey, k)
+	if e != nil {
+		t.Fatalf("selfSigned Expected no error, got [%v]\n", e)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: k},
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -52,14 +53,27 @@ import (
 
 */
 func (c *Connection) Disconnect(h Headers) error {
+	return c.DisconnectContext(context.Background(), h)
+}
+
+/*
+	DisconnectContext is Disconnect, giving up when ctx is done.  See
+	ContextStomper.
+
+	If ctx is done before the DISCONNECT frame is handed to the writer, the
+	connection is left as is.  Otherwise the connection is shut down, whether
+	or not the write and any receipt wait complete.
+*/
+func (c *Connection) DisconnectContext(ctx context.Context, h Headers) (e error) {
+	defer func() { e = newOpError("Disconnect", DISCONNECT, h, e) }()
 	c.discLock.Lock()
 	defer c.discLock.Unlock()
 	//
 	if !c.isConnected() {
 		return ECONBAD
 	}
-	c.log(DISCONNECT, "start", h)
-	e := checkHeaders(h, c.Protocol())
+	c.debug("DISCONNECT start", "headers", h)
+	e = checkHeaders(h, c.Protocol())
 	if e != nil {
 		return e
 	}
@@ -80,65 +94,86 @@ func (c *Connection) Disconnect(h Headers) error {
 	//
 	f := Frame{DISCONNECT, ch, NULLBUFF}
 	//
-	r := make(chan error)
-	if e = c.writeWireData(wiredata{f, r}); e != nil {
+	r, e := c.writeWireDataContext(ctx, f)
+	if e != nil {
 		return e
 	}
-	e = <-r
+	e = c.awaitWrite(ctx, r)
+	var ce error // Context done while waiting for a receipt
 	// Drive shutdown logic
 	// Only set DisconnectReceipt if we sucessfully received one, and it is
 	// the one we were expecting.
 	if !cwr && e == nil {
 		// Can be RECEIPT or ERROR frame
-		mds, e := c.getMessageData()
+		mds, me := c.getMessageData(ctx)
+		if me != nil && me == ctx.Err() {
+			ce = me
+		}
 		//
 		// fmt.Println(DISCONNECT, "sanchek", mds)
 		//
 		switch mds.Message.Command {
 		case ERROR:
-			e = fmt.Errorf("DISBRKERR -> %q", mds.Message)
-			c.log(DISCONNECT, "errf", e)
+			e = NewBrokerError(mds.Message)
+			c.warn("DISCONNECT errf", "error", e)
+		case "":
+			// Timeout, context done, or the connection read failed
+			if e = me; e == nil {
+				if e = mds.Error; e == nil {
+					e = ECONBAD
+				}
+			}
+			c.warn("DISCONNECT nomd", "error", e)
 		case RECEIPT:
 			gr := mds.Message.Headers.Value(HK_RECEIPT_ID)
 			if wrid != gr {
-				e = fmt.Errorf("%s wanted:%s got:%s", EBADRID, wrid, gr)
-				c.log(DISCONNECT, "nadrid", e)
+				e = fmt.Errorf("%w wanted:%s got:%s", EBADRID, wrid, gr)
+				c.warn("DISCONNECT nadrid", "error", e)
 			} else {
 				c.DisconnectReceipt = mds
-				c.log(DISCONNECT, "OK")
+				c.debug("DISCONNECT OK")
 			}
 		default:
-			e = fmt.Errorf("DISBADFRM -> %q", mds.Message)
-			c.log(DISCONNECT, "badf", e)
+			e = &ProtocolError{Reason: "unexpected DISCONNECT response",
+				Command: mds.Message.Command, Headers: mds.Message.Headers}
+			c.warn("DISCONNECT badf", "error", e)
 		}
 	}
-	c.log(DISCONNECT, "ends", ch)
+	c.debug("DISCONNECT ends", "headers", ch)
 	c.shutdown()
 	c.sysAbort()
-	c.log(DISCONNECT, "system shutdown cannel closed")
+	if c.ownsConn {
+		_ = c.netconn.Close()
+	}
+	c.debug("DISCONNECT system shutdown cannel closed")
+	if ce != nil {
+		return ce
+	}
 	return e
 }
 
-func (c *Connection) getMessageData() (MessageData, error) {
+func (c *Connection) getMessageData(ctx context.Context) (MessageData, error) {
 	var md MessageData
 	var me error
 	me = nil
+	var to <-chan time.Time // nil, never fires
 	if os.Getenv("STOMP_MAXDISCTO") != "" {
 		d, e := time.ParseDuration(os.Getenv("STOMP_MAXDISCTO"))
 		if e != nil {
-			c.log("DISCGETMD PDERROR -> ", e)
-			md = <-c.input
+			c.warn("DISCGETMD PDERROR", "error", e)
 		} else {
-			c.log("DISCGETMD DUR -> ", d)
-			select {
-			case <-time.After(d):
-				me = EDISCTO
-			case md = <-c.input:
-			}
+			c.debug("DISCGETMD DUR", "duration", d)
+			to = time.After(d)
 		}
 	} else {
-		c.log("DISNOMAX", me)
-		md = <-c.input
+		c.debug("DISNOMAX")
+	}
+	select {
+	case <-to:
+		me = EDISCTO
+	case <-ctx.Done():
+		me = ctx.Err()
+	case md = <-c.input:
 	}
 	//
 	return md, me
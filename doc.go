@@ -72,6 +72,18 @@
 		RECEIPT		A receipt from the broker for a previous frame sent by the client.
 
 
+	Headers and Bodies
+
+	No method changes the Headers or body you pass to it.  Escaping, and the
+	content-type and content-length headers added by default, happen as the
+	frame is written.  Headers are not copied, so do not change them while a
+	call that uses them is in progress.  A call that returns early because its
+	context is done, and SendAsync, may still be using them.
+
+	For many sends with the same headers, see PrepareSend.  The headers are
+	checked, escaped and serialized once.
+
+
 	Subscribe and MessageData Channels
 
 	The Subscribe method returns a channel from which you receive MessageData values.
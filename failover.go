@@ -0,0 +1,276 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Failover dials one of several brokers.
+
+	Each call to Dial makes one pass over the endpoint list, in order or in
+	random order.  If no endpoint accepts the connection, Dial waits according
+	to Backoff and makes another pass, until MaxReconnectAttempts further
+	passes have failed.
+
+	The endpoint that accepted the connection is reported by
+	Connection.Endpoint (see Endpointer).  Dial can be used as the Dialer of
+	a Reconnector, and DialContext as the ContextDialer of one, so that
+	Disconnect stops a Dial in progress.
+*/
+type Failover struct {
+	Endpoints            []string      // Broker addresses, host:port
+	Randomize            bool          // Try endpoints in random order
+	Backoff              Backoff       // Delays between passes.  MaxAttempts is not used.
+	MaxReconnectAttempts int           // Passes after the first one, -1 for no limit
+	Timeout              time.Duration // Timeout for each endpoint, zero for none
+	lock                 sync.Mutex
+	last                 string
+}
+
+/*
+	Failover URL defaults, as used by ActiveMQ.
+*/
+const (
+	failoverInitialDelay = 10 * time.Millisecond
+	failoverMaxDelay     = 30 * time.Second
+	failoverMultiplier   = 2.0
+	failoverTimeout      = 30 * time.Second
+)
+
+/*
+	ParseFailover parses an ActiveMQ style failover URL.
+
+	Both the parenthesized and the plain form are accepted:
+		failover:(tcp://a:61613,tcp://b:61613)?randomize=false
+		failover:tcp://a:61613,tcp://b:61613
+
+	The "tcp" and "stomp" schemes are supported.  A missing port defaults to
+	61613.  The endpoint Timeout is 30 seconds.  Supported options, with
+	defaults:
+		randomize=true
+		initialReconnectDelay=10 (ms)
+		maxReconnectDelay=30000 (ms)
+		useExponentialBackOff=true
+		backOffMultiplier=2.0
+		maxReconnectAttempts=-1 (no limit, 0 means a single pass)
+	Other options are ignored.
+
+	Example:
+		f, e := stompngo.ParseFailover(
+			"failover:(tcp://a:61613,tcp://b:61613)?randomize=false&maxReconnectAttempts=10")
+		if e != nil {
+			// Do something sane ...
+		}
+		n, e := f.Dial()
+		if e != nil {
+			// Do something sane ...
+		}
+		c, e := stompngo.Connect(n, h)
+		// c.Endpoint() is the broker actually used
+*/
+func ParseFailover(s string) (*Failover, error) {
+	if !strings.HasPrefix(s, "failover:") {
+		return nil, fmt.Errorf("%w: %s", EFOBADURL, s)
+	}
+	r := strings.TrimPrefix(s, "failover:")
+	var list, query string
+	if strings.HasPrefix(r, "(") {
+		i := strings.Index(r, ")")
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s", EFOBADURL, s)
+		}
+		list = r[1:i]
+		query = strings.TrimPrefix(r[i+1:], "?")
+	} else {
+		p := strings.SplitN(r, "?", 2)
+		list = p[0]
+		if len(p) == 2 {
+			query = p[1]
+		}
+	}
+	f := &Failover{Randomize: true,
+		Backoff: Backoff{Initial: failoverInitialDelay,
+			Max:        failoverMaxDelay,
+			Multiplier: failoverMultiplier},
+		MaxReconnectAttempts: -1,
+		Timeout:              failoverTimeout}
+	for _, u := range strings.Split(list, ",") {
+		ep, e := failoverEndpoint(strings.TrimSpace(u))
+		if e != nil {
+			return nil, e
+		}
+		f.Endpoints = append(f.Endpoints, ep)
+	}
+	q, e := url.ParseQuery(query)
+	if e != nil {
+		return nil, fmt.Errorf("%w: %v", EFOBADURL, e)
+	}
+	return f, f.options(q)
+}
+
+/*
+	Convert one broker URI to host:port.
+*/
+func failoverEndpoint(u string) (string, error) {
+	pu, e := url.Parse(u)
+	if e != nil {
+		return "", fmt.Errorf("%w: %v", EFOBADURL, e)
+	}
+	switch pu.Scheme {
+	case "tcp", "stomp":
+	default:
+		return "", fmt.Errorf("%w: %s", EFOSCHEME, u)
+	}
+	if pu.Hostname() == "" {
+		return "", fmt.Errorf("%w: %s", EFOBADURL, u)
+	}
+	p := pu.Port()
+	if p == "" {
+		p = "61613"
+	}
+	return net.JoinHostPort(pu.Hostname(), p), nil
+}
+
+/*
+	Apply failover URL options.
+*/
+func (f *Failover) options(q url.Values) error {
+	for _, k := range []string{"randomize", "initialReconnectDelay",
+		"maxReconnectDelay", "backOffMultiplier", "useExponentialBackOff",
+		"maxReconnectAttempts"} {
+		if _, ok := q[k]; !ok {
+			continue
+		}
+		v := q.Get(k)
+		var e error
+		switch k {
+		case "randomize":
+			f.Randomize, e = strconv.ParseBool(v)
+		case "initialReconnectDelay":
+			f.Backoff.Initial, e = failoverMillis(v)
+		case "maxReconnectDelay":
+			f.Backoff.Max, e = failoverMillis(v)
+		case "backOffMultiplier":
+			f.Backoff.Multiplier, e = strconv.ParseFloat(v, 64)
+		case "useExponentialBackOff":
+			var b bool
+			if b, e = strconv.ParseBool(v); e == nil && !b {
+				f.Backoff.Multiplier = 1
+			}
+		case "maxReconnectAttempts":
+			f.MaxReconnectAttempts, e = strconv.Atoi(v)
+		}
+		if e != nil {
+			return fmt.Errorf("%w: %s=%s", EFOBADOPT, k, v)
+		}
+	}
+	return nil
+}
+
+func failoverMillis(v string) (time.Duration, error) {
+	i, e := strconv.ParseInt(v, 10, 64)
+	return time.Duration(i) * time.Millisecond, e
+}
+
+/*
+	Dial connects to the first endpoint that accepts a connection.  The error
+	from the last failed attempt is returned when all passes fail.
+*/
+func (f *Failover) Dial() (net.Conn, error) {
+	return f.DialContext(context.Background())
+}
+
+/*
+	DialContext is Dial, giving up when ctx is done.
+*/
+func (f *Failover) DialContext(ctx context.Context) (net.Conn, error) {
+	if len(f.Endpoints) == 0 {
+		return nil, EFONOEP
+	}
+	d := &net.Dialer{Timeout: f.Timeout}
+	var le error
+	for n := 0; ; n++ {
+		for _, ep := range f.order() {
+			c, e := d.DialContext(ctx, NetProtoTCP, ep)
+			if ctx.Err() != nil {
+				if c != nil {
+					_ = c.Close()
+				}
+				return nil, ctx.Err()
+			}
+			if e == nil {
+				f.lock.Lock()
+				f.last = ep
+				f.lock.Unlock()
+				return &endpointConn{c, ep}, nil
+			}
+			le = e
+		}
+		if f.MaxReconnectAttempts >= 0 && n >= f.MaxReconnectAttempts {
+			return nil, le
+		}
+		t := time.NewTimer(f.Backoff.Delay(n))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+/*
+	Endpoint returns the endpoint used by the latest successful Dial.
+*/
+func (f *Failover) Endpoint() string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.last
+}
+
+/*
+	Endpoints in the order to try for one pass.
+*/
+func (f *Failover) order() []string {
+	eps := append([]string(nil), f.Endpoints...)
+	if f.Randomize {
+		rand.Shuffle(len(eps), func(i, j int) { eps[i], eps[j] = eps[j], eps[i] })
+	}
+	return eps
+}
+
+/*
+	A network connection that knows the endpoint it was dialed with.
+*/
+type endpointConn struct {
+	net.Conn
+	ep string
+}
+
+func (c *endpointConn) Endpoint() string {
+	return c.ep
+}
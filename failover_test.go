@@ -0,0 +1,157 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+	"github.com/gmallard/stompngo/senv"
+)
+
+/*
+	Test failover URL parsing.
+*/
+func TestFailoverParse(t *testing.T) {
+	f, e := ParseFailover("failover:(tcp://a:61613,stomp://b)?randomize=false&maxReconnectAttempts=10&initialReconnectDelay=5&useExponentialBackOff=false&backOffMultiplier=3")
+	if e != nil {
+		t.Fatalf("TestFailoverParse Expected no error, got [%v]\n", e)
+	}
+	if strings.Join(f.Endpoints, ",") != "a:61613,b:61613" {
+		t.Fatalf("TestFailoverParse Expected [a:61613,b:61613], got [%v]\n",
+			f.Endpoints)
+	}
+	if f.Randomize || f.MaxReconnectAttempts != 10 ||
+		f.Backoff.Initial != 5*time.Millisecond || f.Backoff.Multiplier != 1 ||
+		f.Backoff.Max != 30*time.Second {
+		t.Fatalf("TestFailoverParse Unexpected options [%+v]\n", f)
+	}
+	//
+	f, e = ParseFailover("failover:tcp://a:1,tcp://b:2")
+	if e != nil {
+		t.Fatalf("TestFailoverParse Expected no error, got [%v]\n", e)
+	}
+	if !f.Randomize || f.MaxReconnectAttempts != -1 || len(f.Endpoints) != 2 {
+		t.Fatalf("TestFailoverParse Unexpected defaults [%+v]\n", f)
+	}
+	//
+	for _, bad := range []string{"tcp://a:1", "failover:(tcp://a:1",
+		"failover:(http://a:1)", "failover:(tcp://a:1)?randomize=maybe"} {
+		if _, e = ParseFailover(bad); e == nil {
+			t.Fatalf("TestFailoverParse Expected error for [%v], got nil\n", bad)
+		}
+	}
+}
+
+/*
+	Test that failover skips a dead endpoint, and reports the one chosen.
+*/
+func TestFailoverDial(t *testing.T) {
+	// An address nobody listens on
+	l, e := net.Listen(NetProtoTCP, "127.0.0.1:0")
+	if e != nil {
+		t.Fatalf("TestFailoverDial Expected no listen error, got [%v]\n", e)
+	}
+	dead := l.Addr().String()
+	_ = l.Close()
+	//
+	h, p := senv.HostAndPort()
+	live := net.JoinHostPort(h, p)
+	f, e := ParseFailover("failover:(tcp://" + dead + ",tcp://" + live +
+		")?randomize=false&maxReconnectAttempts=0")
+	if e != nil {
+		t.Fatalf("TestFailoverDial Expected no error, got [%v]\n", e)
+	}
+	n, e := f.Dial()
+	if e != nil {
+		t.Fatalf("TestFailoverDial Expected no dial error, got [%v]\n", e)
+	}
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestFailoverDial CONNECT Failed: e:<%q>\n", e)
+	}
+	m, ok := interface{}(conn).(Endpointer)
+	if !ok || m.Endpoint() != live || f.Endpoint() != live {
+		t.Fatalf("TestFailoverDial Expected endpoint [%v], got [%v] [%v]\n", live,
+			m.Endpoint(), f.Endpoint())
+	}
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	_ = closeConn(t, n)
+	//
+	f.Endpoints = []string{dead}
+	if _, e = f.Dial(); e == nil {
+		t.Fatalf("TestFailoverDial Expected dial error, got nil\n")
+	}
+}
+
+/*
+	Test that a failover dial with no attempt limit can be cancelled, also by
+	Reconnector Disconnect.
+*/
+func TestFailoverCancel(t *testing.T) {
+	b, e := fakebroker.Start(nil)
+	if e != nil {
+		t.Fatalf("TestFailoverCancel Expected no listen error, got [%v]\n", e)
+	}
+	f, e := ParseFailover("failover:(tcp://" + b.Addr() +
+		")?initialReconnectDelay=5&maxReconnectDelay=20")
+	if e != nil {
+		t.Fatalf("TestFailoverCancel Expected no error, got [%v]\n", e)
+	}
+	calls, ends := make(chan struct{}, 100), make(chan error, 100)
+	d := func(ctx context.Context) (net.Conn, error) {
+		calls <- struct{}{}
+		n, e := f.DialContext(ctx)
+		ends <- e
+		return n, e
+	}
+	r, e := NewReconnectorContext(d, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestFailoverCancel CONNECT Failed: e:<%q>\n", e)
+	}
+	<-calls
+	<-ends
+	r.SetBackoff(Backoff{Initial: time.Millisecond, MaxAttempts: 10})
+	_ = b.Close() // Every dial fails from now on
+	select {
+	case <-calls:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TestFailoverCancel Expected a reconnect dial, got timeout\n")
+	}
+	_ = r.Disconnect(empty_headers)
+	select {
+	case e = <-ends:
+		if e != context.Canceled {
+			t.Fatalf("TestFailoverCancel Expected [%v], got [%v]\n",
+				context.Canceled, e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TestFailoverCancel Expected a cancelled dial, got timeout\n")
+	}
+	//
+	ctx, cf := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cf()
+	if _, e = f.DialContext(ctx); e != context.DeadlineExceeded {
+		t.Fatalf("TestFailoverCancel Expected [%v], got [%v]\n",
+			context.DeadlineExceeded, e)
+	}
+}
@@ -0,0 +1,355 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+/*
+	Package fakebroker is a small, in-process STOMP broker for tests.
+
+	It speaks STOMP 1.0, 1.1 and 1.2, negotiates heart beats, and supports
+	SEND, SUBSCRIBE, UNSUBSCRIBE, ACK, NACK, BEGIN, COMMIT, ABORT, DISCONNECT
+	and receipts.  Destinations starting with "/topic/" are broadcast to the
+	current subscribers, all other destinations are treated as queues which
+	retain messages until they are consumed.
+
+	The broker is reachable either through a loopback listener:
+
+		b, e := fakebroker.Start(nil)
+		if e != nil {
+			// Do something sane ...
+		}
+		defer b.Close()
+		n, e := net.Dial("tcp", b.Addr())
+
+	or through an in memory net.Pipe:
+
+		n := b.Pipe()
+
+	Tests that need unusual broker behavior can script it with
+	Config.Script, which sees every inbound frame before the default handling.
+
+	This package is intended for tests only.  It makes no attempt at
+	performance or persistence.
+*/
+package fakebroker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+)
+
+/*
+	Config controls fake broker behavior.  A nil *Config gives the defaults.
+*/
+type Config struct {
+	// Protocol levels the broker accepts.  Default: 1.0, 1.1, 1.2.
+	Versions []string
+	// The broker heart-beat header value sent in CONNECTED, "sx,sy".  When
+	// empty the broker mirrors the client request, i.e. it sends at the rate
+	// the client wants to receive, and wants to receive at the rate the
+	// client sends.
+	HeartBeat string
+	// Value of the CONNECTED server header.  Default: "fakebroker/1.0".
+	Server string
+	// If Login is not empty, CONNECT must supply this login and Passcode.
+	Login    string
+	Passcode string
+	// Script, if not nil, is called for every inbound frame, including
+	// CONNECT, before default processing.  Return true to indicate the frame
+	// has been fully handled and default processing should be skipped.
+	Script func(s *Session, f *Frame) bool
+	// Optional logger for wire level tracing.
+	Logger *log.Logger
+}
+
+/*
+	Broker is a running fake broker.
+*/
+type Broker struct {
+	cfg      Config
+	lock     sync.Mutex
+	queues   map[string][]*message      // Retained messages by destination
+	subs     map[string][]*subscription // Subscriptions by destination
+	rr       map[string]int             // Round robin index by destination
+	sessions map[*Session]bool          // Live sessions
+	nsess    int64                      // Session id counter
+	nmsg     int64                      // Message id counter
+	nack     int64                      // 1.2 ack id counter
+	ln       net.Listener               // Loopback listener, possibly nil
+	wg       sync.WaitGroup             // Running goroutines
+	closed   bool                       // Close called
+}
+
+type message struct {
+	id      string   // message-id
+	dest    string   // destination
+	headers []string // client supplied headers, filtered
+	body    []byte   // payload
+}
+
+type subscription struct {
+	s    *Session
+	id   string
+	dest string
+	ack  string
+}
+
+type pending struct {
+	ackid string // 1.2 ack header value
+	m     *message
+	sub   *subscription
+}
+
+var defaultVersions = []string{"1.0", "1.1", "1.2"}
+
+/*
+	ErrClosed is returned by Listen after the broker has been closed.
+*/
+var ErrClosed = errors.New("fakebroker: broker closed")
+
+/*
+	New returns a broker that is not yet listening.  Use Listen or Pipe to
+	reach it.
+*/
+func New(cfg *Config) *Broker {
+	b := &Broker{queues: make(map[string][]*message),
+		subs:     make(map[string][]*subscription),
+		rr:       make(map[string]int),
+		sessions: make(map[*Session]bool)}
+	if cfg != nil {
+		b.cfg = *cfg
+	}
+	if len(b.cfg.Versions) == 0 {
+		b.cfg.Versions = defaultVersions
+	}
+	if b.cfg.Server == "" {
+		b.cfg.Server = "fakebroker/1.0"
+	}
+	return b
+}
+
+/*
+	Start returns a broker listening on an ephemeral loopback port.
+*/
+func Start(cfg *Config) (*Broker, error) {
+	b := New(cfg)
+	if e := b.Listen("127.0.0.1:0"); e != nil {
+		return nil, e
+	}
+	return b, nil
+}
+
+/*
+	Listen starts accepting connections on a TCP address.
+*/
+func (b *Broker) Listen(addr string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	ln, e := net.Listen("tcp", addr)
+	if e != nil {
+		return e
+	}
+	b.ln = ln
+	b.wg.Add(1)
+	go b.accept(ln)
+	return nil
+}
+
+/*
+	Addr returns the listener address as host:port, or "" when not listening.
+*/
+func (b *Broker) Addr() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.ln == nil {
+		return ""
+	}
+	return b.ln.Addr().String()
+}
+
+/*
+	Pipe returns the client end of an in memory connection to the broker.
+*/
+func (b *Broker) Pipe() net.Conn {
+	c, s := net.Pipe()
+	b.ServeConn(s)
+	return c
+}
+
+/*
+	ServeConn serves STOMP on an already established connection.  It returns
+	immediately.
+*/
+func (b *Broker) ServeConn(n net.Conn) {
+	s := newSession(b, n)
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		_ = n.Close()
+		return
+	}
+	b.sessions[s] = true
+	b.wg.Add(2)
+	b.lock.Unlock()
+	go s.writer()
+	go s.reader()
+}
+
+/*
+	Close stops the listener and drops every session.
+*/
+func (b *Broker) Close() error {
+	b.lock.Lock()
+	b.closed = true
+	var e error
+	if b.ln != nil {
+		e = b.ln.Close()
+	}
+	for s := range b.sessions {
+		_ = s.conn.Close()
+	}
+	b.lock.Unlock()
+	b.wg.Wait()
+	return e
+}
+
+/*
+	QueueDepth returns the number of retained messages for a destination.
+*/
+func (b *Broker) QueueDepth(dest string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.queues[dest])
+}
+
+/*
+	Sessions returns the number of live sessions.
+*/
+func (b *Broker) Sessions() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.sessions)
+}
+
+func (b *Broker) accept(ln net.Listener) {
+	defer b.wg.Done()
+	for {
+		n, e := ln.Accept()
+		if e != nil {
+			return
+		}
+		b.ServeConn(n)
+	}
+}
+
+func (b *Broker) logf(format string, v ...interface{}) {
+	if b.cfg.Logger != nil {
+		b.cfg.Logger.Output(2, fmt.Sprintf(format, v...))
+	}
+}
+
+/*
+	Publish a message.  Lock must be held.
+*/
+func (b *Broker) publish(m *message) {
+	if isTopic(m.dest) {
+		for _, sub := range b.subs[m.dest] {
+			b.deliver(sub, m)
+		}
+		return
+	}
+	b.queues[m.dest] = append(b.queues[m.dest], m)
+	b.dispatch(m.dest)
+}
+
+/*
+	Hand retained queue messages to subscribers, round robin.  Lock must be
+	held.
+*/
+func (b *Broker) dispatch(dest string) {
+	for len(b.queues[dest]) > 0 {
+		subs := b.subs[dest]
+		if len(subs) == 0 {
+			return
+		}
+		i := b.rr[dest] % len(subs)
+		b.rr[dest] = i + 1
+		m := b.queues[dest][0]
+		b.queues[dest] = b.queues[dest][1:]
+		b.deliver(subs[i], m)
+	}
+}
+
+/*
+	Send a MESSAGE to one subscription.  Lock must be held.
+*/
+func (b *Broker) deliver(sub *subscription, m *message) {
+	f := &Frame{Command: "MESSAGE", Body: m.body}
+	f.Add("destination", m.dest).Add("message-id", m.id).Add("subscription", sub.id)
+	if sub.ack != "auto" {
+		p := &pending{m: m, sub: sub}
+		if sub.s.proto == "1.2" {
+			b.nack++
+			p.ackid = fmt.Sprintf("ack-%d", b.nack)
+			f.Add("ack", p.ackid)
+		}
+		sub.s.unacked = append(sub.s.unacked, p)
+	}
+	f.Headers = append(f.Headers, m.headers...)
+	sub.s.Send(f)
+}
+
+/*
+	Remove a subscription, returning unacknowledged messages to their queue.
+	Lock must be held.
+*/
+func (b *Broker) unsubscribe(sub *subscription) {
+	subs := b.subs[sub.dest]
+	for i, v := range subs {
+		if v == sub {
+			b.subs[sub.dest] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	delete(sub.s.subs, sub.id)
+	var keep, back []*pending
+	for _, p := range sub.s.unacked {
+		if p.sub == sub {
+			back = append(back, p)
+		} else {
+			keep = append(keep, p)
+		}
+	}
+	sub.s.unacked = keep
+	if len(back) == 0 || isTopic(sub.dest) {
+		return
+	}
+	rq := make([]*message, 0, len(back)+len(b.queues[sub.dest]))
+	for _, p := range back {
+		rq = append(rq, p.m)
+	}
+	b.queues[sub.dest] = append(rq, b.queues[sub.dest]...)
+	b.dispatch(sub.dest)
+}
+
+func isTopic(d string) bool {
+	return strings.HasPrefix(d, "/topic/")
+}
@@ -0,0 +1,196 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package fakebroker
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+/*
+	Test client helper: a raw connection with a frame reader.
+*/
+type tclient struct {
+	t     *testing.T
+	n     net.Conn
+	r     *bufio.Reader
+	proto string
+}
+
+func newClient(t *testing.T, b *Broker, proto string) *tclient {
+	c := &tclient{t: t, n: b.Pipe(), proto: "1.0"}
+	c.r = bufio.NewReader(c.n)
+	f := &Frame{Command: "CONNECT"}
+	f.Add("accept-version", proto).Add("host", "localhost")
+	c.send(f)
+	r := c.recv()
+	if r.Command != "CONNECTED" {
+		t.Fatalf("Expected CONNECTED, got [%v]\n", r.Command)
+	}
+	v, ok := r.Value("version")
+	if !ok {
+		v = "1.0" // 1.0 brokers need not send a version
+	}
+	if v != proto {
+		t.Fatalf("Expected version [%v], got [%v]\n", proto, v)
+	}
+	c.proto = proto
+	return c
+}
+
+func (c *tclient) send(f *Frame) {
+	_ = c.n.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, e := c.n.Write(f.bytes(c.proto)); e != nil {
+		c.t.Fatalf("Expected no write error, got [%v]\n", e)
+	}
+}
+
+func (c *tclient) recv() *Frame {
+	_ = c.n.SetReadDeadline(time.Now().Add(5 * time.Second))
+	f, e := readFrame(c.r, c.proto, nil)
+	if e != nil {
+		c.t.Fatalf("Expected no read error, got [%v]\n", e)
+	}
+	return f
+}
+
+/*
+	Test CONNECT protocol negotiation and login checks.
+*/
+func TestFakeConnect(t *testing.T) {
+	b := New(nil)
+	defer b.Close()
+	for _, p := range []string{"1.0", "1.1", "1.2"} {
+		c := newClient(t, b, p)
+		_ = c.n.Close()
+	}
+	//
+	lb := New(&Config{Login: "guest", Passcode: "guest"})
+	defer lb.Close()
+	n := lb.Pipe()
+	r := bufio.NewReader(n)
+	f := &Frame{Command: "CONNECT"}
+	f.Add("accept-version", "1.2").Add("login", "bad").Add("passcode", "bad")
+	if _, e := n.Write(f.bytes("1.0")); e != nil {
+		t.Fatalf("Expected no write error, got [%v]\n", e)
+	}
+	f, e := readFrame(r, "1.0", nil)
+	if e != nil {
+		t.Fatalf("Expected no read error, got [%v]\n", e)
+	}
+	if f.Command != "ERROR" {
+		t.Fatalf("Expected ERROR, got [%v]\n", f.Command)
+	}
+}
+
+/*
+	Test queue retention, delivery and receipts.
+*/
+func TestFakeQueue(t *testing.T) {
+	b := New(nil)
+	defer b.Close()
+	c := newClient(t, b, "1.2")
+	d := "/queue/fake.queue"
+	body := "a:b\nc"
+	c.send((&Frame{Command: "SEND", Body: []byte(body)}).Add("destination", d).Add("receipt", "r1"))
+	if r := c.recv(); r.Command != "RECEIPT" {
+		t.Fatalf("Expected RECEIPT, got [%v]\n", r.Command)
+	}
+	if q := b.QueueDepth(d); q != 1 {
+		t.Fatalf("Expected queue depth 1, got [%v]\n", q)
+	}
+	c.send((&Frame{Command: "SUBSCRIBE"}).Add("destination", d).Add("id", "s1").Add("ack", "client-individual"))
+	m := c.recv()
+	if m.Command != "MESSAGE" || string(m.Body) != body {
+		t.Fatalf("Expected MESSAGE [%v], got [%v] [%v]\n", body, m.Command, string(m.Body))
+	}
+	ack, ok := m.Value("ack")
+	if !ok {
+		t.Fatalf("Expected 1.2 ack header, got [%v]\n", m.Headers)
+	}
+	// Unacknowledged messages go back to the queue on UNSUBSCRIBE
+	c.send((&Frame{Command: "UNSUBSCRIBE"}).Add("id", "s1").Add("receipt", "r2"))
+	c.recv()
+	if q := b.QueueDepth(d); q != 1 {
+		t.Fatalf("Expected queue depth 1 after UNSUBSCRIBE, got [%v]\n", q)
+	}
+	c.send((&Frame{Command: "SUBSCRIBE"}).Add("destination", d).Add("id", "s2").Add("ack", "client-individual"))
+	m = c.recv()
+	ack, _ = m.Value("ack")
+	c.send((&Frame{Command: "ACK"}).Add("id", ack).Add("receipt", "r3"))
+	c.recv()
+	c.send((&Frame{Command: "UNSUBSCRIBE"}).Add("id", "s2").Add("receipt", "r4"))
+	c.recv()
+	if q := b.QueueDepth(d); q != 0 {
+		t.Fatalf("Expected queue depth 0 after ACK, got [%v]\n", q)
+	}
+}
+
+/*
+	Test transactions: aborted SENDs are dropped, committed SENDs delivered.
+*/
+func TestFakeTransaction(t *testing.T) {
+	b := New(nil)
+	defer b.Close()
+	c := newClient(t, b, "1.1")
+	d := "/queue/fake.trans"
+	for _, end := range []string{"ABORT", "COMMIT"} {
+		c.send((&Frame{Command: "BEGIN"}).Add("transaction", "t1"))
+		c.send((&Frame{Command: "SEND", Body: []byte(end)}).Add("destination", d).Add("transaction", "t1"))
+		if q := b.QueueDepth(d); q != 0 {
+			t.Fatalf("Expected queue depth 0 before %v, got [%v]\n", end, q)
+		}
+		c.send((&Frame{Command: end}).Add("transaction", "t1").Add("receipt", end))
+		c.recv()
+	}
+	if q := b.QueueDepth(d); q != 1 {
+		t.Fatalf("Expected queue depth 1, got [%v]\n", q)
+	}
+	c.send((&Frame{Command: "SUBSCRIBE"}).Add("destination", d).Add("id", "s1"))
+	if m := c.recv(); string(m.Body) != "COMMIT" {
+		t.Fatalf("Expected [COMMIT], got [%v]\n", string(m.Body))
+	}
+}
+
+/*
+	Test heart-beat negotiation and broker heart beats.
+*/
+func TestFakeHeartBeat(t *testing.T) {
+	b := New(nil)
+	defer b.Close()
+	n := b.Pipe()
+	r := bufio.NewReader(n)
+	f := &Frame{Command: "CONNECT"}
+	f.Add("accept-version", "1.1").Add("host", "localhost").Add("heart-beat", "0,50")
+	if _, e := n.Write(f.bytes("1.0")); e != nil {
+		t.Fatalf("Expected no write error, got [%v]\n", e)
+	}
+	_ = n.SetReadDeadline(time.Now().Add(5 * time.Second))
+	f, e := readFrame(r, "1.0", nil)
+	if e != nil {
+		t.Fatalf("Expected no read error, got [%v]\n", e)
+	}
+	if v, _ := f.Value("heart-beat"); v != "50,0" {
+		t.Fatalf("Expected heart-beat [50,0], got [%v]\n", v)
+	}
+	hb, e := r.ReadByte()
+	if e != nil || hb != '\n' {
+		t.Fatalf("Expected heart beat, got [%q] [%v]\n", hb, e)
+	}
+}
@@ -0,0 +1,204 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package fakebroker
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+	Frame is a STOMP frame as seen by the fake broker.
+
+	Headers are key and value pairs, keys at even indices and values at odd
+	indices, exactly like stompngo.Headers.  This package can not import
+	stompngo (the stompngo tests import this package), hence the separate
+	type.
+*/
+type Frame struct {
+	Command string
+	Headers []string
+	Body    []byte
+}
+
+/*
+	Value returns the first value for a header key, and whether the key is
+	present at all.
+*/
+func (f *Frame) Value(k string) (string, bool) {
+	for i := 0; i+1 < len(f.Headers); i += 2 {
+		if f.Headers[i] == k {
+			return f.Headers[i+1], true
+		}
+	}
+	return "", false
+}
+
+/*
+	Add appends a header key and value pair to the frame.
+*/
+func (f *Frame) Add(k, v string) *Frame {
+	f.Headers = append(f.Headers, k, v)
+	return f
+}
+
+var errBadHeader = errors.New("fakebroker: malformed header line")
+
+/*
+	Read one frame from the wire.  Leading EOLs (heart beats) are skipped, and
+	reported through the hb callback.  Header values are decoded only when
+	decode is true, i.e. for STOMP 1.1+ frames other than CONNECT.
+*/
+func readFrame(r *bufio.Reader, proto string, hb func()) (*Frame, error) {
+	var line string
+	var e error
+	for {
+		line, e = readLine(r)
+		if e != nil {
+			return nil, e
+		}
+		if line != "" {
+			break
+		}
+		if hb != nil {
+			hb()
+		}
+	}
+	f := &Frame{Command: line}
+	decode := proto != "1.0" && f.Command != "CONNECT" && f.Command != "STOMP"
+	for {
+		line, e = readLine(r)
+		if e != nil {
+			return nil, e
+		}
+		if line == "" {
+			break
+		}
+		p := strings.SplitN(line, ":", 2)
+		if len(p) != 2 {
+			return nil, errBadHeader
+		}
+		if decode {
+			p[0], p[1] = unescape(p[0]), unescape(p[1])
+		}
+		f.Headers = append(f.Headers, p[0], p[1])
+	}
+	if v, ok := f.Value("content-length"); ok {
+		l, e := strconv.Atoi(strings.TrimSpace(v))
+		if e != nil {
+			return nil, e
+		}
+		f.Body = make([]byte, l)
+		if _, e = io.ReadFull(r, f.Body); e != nil {
+			return nil, e
+		}
+		if _, e = r.ReadByte(); e != nil { // trailing NUL
+			return nil, e
+		}
+		return f, nil
+	}
+	b, e := r.ReadBytes(0)
+	if e != nil {
+		return nil, e
+	}
+	f.Body = b[:len(b)-1]
+	return f, nil
+}
+
+/*
+	Read a single line, accepting both LF and CRLF line endings.
+*/
+func readLine(r *bufio.Reader) (string, error) {
+	s, e := r.ReadString('\n')
+	if e != nil {
+		return "", e
+	}
+	s = s[:len(s)-1]
+	if strings.HasSuffix(s, "\r") {
+		s = s[:len(s)-1]
+	}
+	return s, nil
+}
+
+/*
+	Serialize a frame for the wire.  Header values are escaped when escape is
+	true.  A content-length header is always supplied.
+*/
+func (f *Frame) bytes(proto string) []byte {
+	escape := proto != "1.0" && f.Command != "CONNECTED"
+	b := make([]byte, 0, 256+len(f.Body))
+	b = append(b, f.Command...)
+	b = append(b, '\n')
+	for i := 0; i+1 < len(f.Headers); i += 2 {
+		k, v := f.Headers[i], f.Headers[i+1]
+		if k == "content-length" {
+			continue
+		}
+		if escape {
+			k, v = escapeValue(k, proto), escapeValue(v, proto)
+		}
+		b = append(b, k...)
+		b = append(b, ':')
+		b = append(b, v...)
+		b = append(b, '\n')
+	}
+	b = append(b, "content-length:"...)
+	b = append(b, strconv.Itoa(len(f.Body))...)
+	b = append(b, "\n\n"...)
+	b = append(b, f.Body...)
+	b = append(b, 0)
+	return b
+}
+
+func escapeValue(s, proto string) string {
+	r := strings.NewReplacer("\\", "\\\\", "\n", "\\n", ":", "\\c")
+	if proto == "1.2" {
+		r = strings.NewReplacer("\\", "\\\\", "\n", "\\n", ":", "\\c",
+			"\r", "\\r")
+	}
+	return r.Replace(s)
+}
+
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b = append(b, s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 'c':
+			b = append(b, ':')
+		case '\\':
+			b = append(b, '\\')
+		default: // Be lenient, keep what the client sent
+			b = append(b, '\\', s[i])
+		}
+	}
+	return string(b)
+}
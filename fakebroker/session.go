@@ -0,0 +1,496 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package fakebroker
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Session is one client connection to the fake broker.
+*/
+type Session struct {
+	b     *Broker
+	conn  net.Conn
+	rdr   *bufio.Reader
+	id    string
+	proto string
+	//
+	connected    bool                     // CONNECTED sent
+	disconnected bool                     // DISCONNECT received
+	subs         map[string]*subscription // Guarded by broker lock
+	unacked      []*pending               // Guarded by broker lock
+	tx           map[string][]*Frame      // Reader goroutine only
+	//
+	olock  sync.Mutex
+	outq   [][]byte      // Pending wire data
+	eclose bool          // Close after outq drains
+	osig   chan struct{} // Wake the writer
+	done   chan struct{} // Session ended
+}
+
+func newSession(b *Broker, n net.Conn) *Session {
+	return &Session{b: b, conn: n, rdr: bufio.NewReader(n), proto: "1.0",
+		subs: make(map[string]*subscription),
+		tx:   make(map[string][]*Frame),
+		osig: make(chan struct{}, 1),
+		done: make(chan struct{})}
+}
+
+/*
+	ID returns the session id sent in CONNECTED.
+*/
+func (s *Session) ID() string {
+	return s.id
+}
+
+/*
+	Protocol returns the negotiated protocol level.
+*/
+func (s *Session) Protocol() string {
+	return s.proto
+}
+
+/*
+	Send queues a frame for the client.  Headers are escaped as required by
+	the session protocol level and content-length is always supplied.
+*/
+func (s *Session) Send(f *Frame) {
+	s.b.logf("%s SEND %s %q", s.id, f.Command, f.Headers)
+	s.WriteRaw(f.bytes(s.proto))
+}
+
+/*
+	WriteRaw queues arbitrary bytes for the client, e.g. heart beats or
+	deliberately malformed frames.
+*/
+func (s *Session) WriteRaw(b []byte) {
+	s.olock.Lock()
+	s.outq = append(s.outq, b)
+	s.olock.Unlock()
+	s.wake()
+}
+
+/*
+	Close closes the session once all queued data is written.
+*/
+func (s *Session) Close() {
+	s.olock.Lock()
+	s.eclose = true
+	s.olock.Unlock()
+	s.wake()
+}
+
+/*
+	Error sends an ERROR frame and then closes the session, as brokers do.
+*/
+func (s *Session) Error(msg string, f *Frame) {
+	ef := &Frame{Command: "ERROR", Body: []byte(msg)}
+	ef.Add("message", msg).Add("content-type", "text/plain")
+	if f != nil {
+		if r, ok := f.Value("receipt"); ok {
+			ef.Add("receipt-id", r)
+		}
+	}
+	s.Send(ef)
+	s.Close()
+}
+
+func (s *Session) wake() {
+	select {
+	case s.osig <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Session) writer() {
+	defer s.b.wg.Done()
+	for {
+		select {
+		case <-s.osig:
+		case <-s.done:
+			return
+		}
+		s.olock.Lock()
+		q, ec := s.outq, s.eclose
+		s.outq = nil
+		s.olock.Unlock()
+		for _, d := range q {
+			if _, e := s.conn.Write(d); e != nil {
+				_ = s.conn.Close()
+				return
+			}
+		}
+		if ec {
+			_ = s.conn.Close()
+			return
+		}
+	}
+}
+
+func (s *Session) reader() {
+	defer s.b.wg.Done()
+	defer s.end()
+	for {
+		f, e := readFrame(s.rdr, s.proto, nil)
+		if e != nil {
+			s.b.logf("%s READ ERROR %v", s.id, e)
+			return
+		}
+		s.b.logf("%s RECV %s %q", s.id, f.Command, f.Headers)
+		if s.disconnected {
+			continue // Ignore everything after DISCONNECT
+		}
+		if s.b.cfg.Script != nil && s.b.cfg.Script(s, f) {
+			continue
+		}
+		s.handle(f)
+	}
+}
+
+func (s *Session) end() {
+	b := s.b
+	b.lock.Lock()
+	s.dropSubs()
+	delete(b.sessions, s)
+	b.lock.Unlock()
+	close(s.done)
+	_ = s.conn.Close()
+}
+
+/*
+	Remove all session subscriptions.  Lock must be held.
+*/
+func (s *Session) dropSubs() {
+	for _, sub := range s.subs {
+		s.b.unsubscribe(sub)
+	}
+}
+
+func (s *Session) handle(f *Frame) {
+	if !s.connected {
+		if f.Command != "CONNECT" && f.Command != "STOMP" {
+			s.Error("not connected", f)
+			return
+		}
+		s.connect(f)
+		return
+	}
+	switch f.Command {
+	case "SEND", "ACK", "NACK":
+		if tid, ok := f.Value("transaction"); ok {
+			if _, ok := s.tx[tid]; !ok {
+				s.Error("unknown transaction: "+tid, f)
+				return
+			}
+			s.tx[tid] = append(s.tx[tid], f)
+			break
+		}
+		if !s.apply(f) {
+			return
+		}
+	case "SUBSCRIBE":
+		if !s.subscribe(f) {
+			return
+		}
+	case "UNSUBSCRIBE":
+		s.unsubscribe(f)
+	case "BEGIN", "COMMIT", "ABORT":
+		if !s.transaction(f) {
+			return
+		}
+	case "DISCONNECT":
+		// Stop deliveries now, the client may be slow to close the socket.
+		s.disconnected = true
+		s.b.lock.Lock()
+		s.dropSubs()
+		s.b.lock.Unlock()
+	default:
+		s.Error("unknown command: "+f.Command, f)
+		return
+	}
+	if r, ok := f.Value("receipt"); ok {
+		s.Send((&Frame{Command: "RECEIPT"}).Add("receipt-id", r))
+	}
+}
+
+func (s *Session) connect(f *Frame) {
+	proto := "1.0"
+	if av, ok := f.Value("accept-version"); ok {
+		proto = ""
+		for _, v := range strings.Split(av, ",") {
+			v = strings.TrimSpace(v)
+			if hasValue(s.b.cfg.Versions, v) && v > proto {
+				proto = v
+			}
+		}
+		if proto == "" {
+			s.Error("supported protocol versions are "+
+				strings.Join(s.b.cfg.Versions, ","), f)
+			return
+		}
+	} else if !hasValue(s.b.cfg.Versions, proto) {
+		s.Error("protocol 1.0 not supported", f)
+		return
+	}
+	if s.b.cfg.Login != "" {
+		l, _ := f.Value("login")
+		p, _ := f.Value("passcode")
+		if l != s.b.cfg.Login || p != s.b.cfg.Passcode {
+			s.Error("authentication failed", f)
+			return
+		}
+	}
+	s.b.lock.Lock()
+	s.b.nsess++
+	s.id = fmt.Sprintf("fakebroker-session-%d", s.b.nsess)
+	s.b.lock.Unlock()
+	//
+	cf := &Frame{Command: "CONNECTED"}
+	if proto != "1.0" {
+		cf.Add("version", proto)
+	}
+	cf.Add("session", s.id).Add("server", s.b.cfg.Server)
+	var send time.Duration
+	if proto != "1.0" {
+		var shb string
+		send, shb = s.heartBeats(f)
+		cf.Add("heart-beat", shb)
+	}
+	s.Send(cf)
+	s.proto = proto
+	s.connected = true
+	if send > 0 {
+		s.b.wg.Add(1)
+		go s.heartBeater(send)
+	}
+}
+
+/*
+	Negotiate heart beats, returning the broker send interval and the
+	heart-beat header value for CONNECTED.
+*/
+func (s *Session) heartBeats(f *Frame) (time.Duration, string) {
+	chb, _ := f.Value("heart-beat")
+	cx, cy := parseHB(chb)
+	shb := s.b.cfg.HeartBeat
+	if shb == "" {
+		shb = strconv.FormatInt(cy, 10) + "," + strconv.FormatInt(cx, 10)
+	}
+	sx, _ := parseHB(shb)
+	if sx == 0 || cy == 0 {
+		return 0, shb
+	}
+	if cy > sx {
+		sx = cy
+	}
+	return time.Duration(sx) * time.Millisecond, shb
+}
+
+func (s *Session) heartBeater(d time.Duration) {
+	defer s.b.wg.Done()
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.WriteRaw([]byte{'\n'})
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func parseHB(v string) (int64, int64) {
+	p := strings.Split(v, ",")
+	if len(p) != 2 {
+		return 0, 0
+	}
+	x, e1 := strconv.ParseInt(strings.TrimSpace(p[0]), 10, 64)
+	y, e2 := strconv.ParseInt(strings.TrimSpace(p[1]), 10, 64)
+	if e1 != nil || e2 != nil {
+		return 0, 0
+	}
+	return x, y
+}
+
+/*
+	Apply a SEND, ACK or NACK frame, either directly or at COMMIT time.
+	Returns false if the session is being closed.
+*/
+func (s *Session) apply(f *Frame) bool {
+	switch f.Command {
+	case "SEND":
+		d, ok := f.Value("destination")
+		if !ok {
+			s.Error("destination required", f)
+			return false
+		}
+		m := &message{dest: d, body: f.Body}
+		for i := 0; i+1 < len(f.Headers); i += 2 {
+			switch f.Headers[i] {
+			case "destination", "content-length", "receipt", "transaction":
+			default:
+				m.headers = append(m.headers, f.Headers[i], f.Headers[i+1])
+			}
+		}
+		s.b.lock.Lock()
+		s.b.nmsg++
+		m.id = fmt.Sprintf("fakebroker-msg-%d", s.b.nmsg)
+		s.b.publish(m)
+		s.b.lock.Unlock()
+	default: // ACK, NACK
+		s.b.lock.Lock()
+		s.ack(f)
+		s.b.lock.Unlock()
+	}
+	return true
+}
+
+/*
+	Acknowledge (or reject) pending messages.  Unknown ids are ignored.  Lock
+	must be held.
+*/
+func (s *Session) ack(f *Frame) {
+	idx := -1
+	for i, p := range s.unacked {
+		if s.proto == "1.2" {
+			if v, _ := f.Value("id"); v == p.ackid {
+				idx = i
+				break
+			}
+			continue
+		}
+		if v, _ := f.Value("message-id"); v == p.m.id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return
+	}
+	p := s.unacked[idx]
+	var keep []*pending
+	for i, q := range s.unacked {
+		switch {
+		case i == idx:
+		case p.sub.ack == "client" && i < idx && q.sub == p.sub:
+			// Cumulative ack
+		default:
+			keep = append(keep, q)
+		}
+	}
+	s.unacked = keep
+}
+
+func (s *Session) subscribe(f *Frame) bool {
+	d, ok := f.Value("destination")
+	if !ok {
+		s.Error("destination required", f)
+		return false
+	}
+	id, ok := f.Value("id")
+	if !ok {
+		if s.proto != "1.0" {
+			s.Error("id required", f)
+			return false
+		}
+		id = d
+	}
+	am, ok := f.Value("ack")
+	if !ok {
+		am = "auto"
+	}
+	b := s.b
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := s.subs[id]; ok {
+		s.Error("duplicate subscription id: "+id, f)
+		return false
+	}
+	sub := &subscription{s: s, id: id, dest: d, ack: am}
+	s.subs[id] = sub
+	b.subs[d] = append(b.subs[d], sub)
+	b.dispatch(d)
+	return true
+}
+
+func (s *Session) unsubscribe(f *Frame) {
+	b := s.b
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if id, ok := f.Value("id"); ok {
+		if sub, ok := s.subs[id]; ok {
+			b.unsubscribe(sub)
+			return
+		}
+	}
+	if d, ok := f.Value("destination"); ok {
+		for _, sub := range s.subs {
+			if sub.dest == d {
+				b.unsubscribe(sub)
+				return
+			}
+		}
+	}
+}
+
+func (s *Session) transaction(f *Frame) bool {
+	tid, ok := f.Value("transaction")
+	if !ok || tid == "" {
+		s.Error("transaction required", f)
+		return false
+	}
+	fs, known := s.tx[tid]
+	switch f.Command {
+	case "BEGIN":
+		if known {
+			s.Error("transaction already started: "+tid, f)
+			return false
+		}
+		s.tx[tid] = nil
+		return true
+	}
+	if !known {
+		s.Error("unknown transaction: "+tid, f)
+		return false
+	}
+	delete(s.tx, tid)
+	if f.Command == "COMMIT" {
+		for _, tf := range fs {
+			if !s.apply(tf) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasValue(a []string, w string) bool {
+	for _, v := range a {
+		if v == w {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+/*
+	FrameReader reads STOMP frames from any io.Reader, with the same rules a
+	Connection uses: header escapes for the protocol level, content-length
+	or NUL terminated bodies, and FrameLimits.  Frames from clients and from
+	brokers are both read.  It is meant for proxies and capture tools.
+
+	Example:
+		fr, e := stompngo.NewFrameReader(r, stompngo.SPL_12)
+		if e != nil {
+			// Do something sane ...
+		}
+		for {
+			f, e := fr.ReadFrame()
+			if e != nil {
+				break // io.EOF at the end of r
+			}
+			if f.Command == "" {
+				continue // A heart beat
+			}
+			// Use f
+		}
+*/
+type FrameReader struct {
+	c *Connection
+}
+
+/*
+	FrameWriter writes STOMP frames to any io.Writer, as a Connection does.
+	Headers are escaped for the protocol level.  Unless suppressed with
+	HK_SUPPRESS_CT and HK_SUPPRESS_CL, content-type and content-length
+	headers are added when they are missing.  Every frame is flushed.
+*/
+type FrameWriter struct {
+	c *Connection
+}
+
+/*
+	Every command, from clients and from brokers.
+*/
+var codecCmds = map[string]bool{CONNECT: true, STOMP: true, DISCONNECT: true,
+	SEND: true, SUBSCRIBE: true, UNSUBSCRIBE: true, ACK: true, NACK: true,
+	BEGIN: true, COMMIT: true, ABORT: true, CONNECTED: true, MESSAGE: true,
+	RECEIPT: true, ERROR: true}
+
+/*
+	A connection with only what frame reads and writes need.
+*/
+func codecConnection(p string) (*Connection, error) {
+	for _, v := range supported {
+		if p == v {
+			return &Connection{protocol: p, dld: &deadlineData{},
+				flim: FrameLimits{}.resolve()}, nil
+		}
+	}
+	return nil, EBADVERCLI
+}
+
+/*
+	NewFrameReader returns a FrameReader for a protocol level, one of SPL_10,
+	SPL_11 or SPL_12.  The default FrameLimits apply.
+*/
+func NewFrameReader(r io.Reader, p string) (*FrameReader, error) {
+	c, e := codecConnection(p)
+	if e != nil {
+		return nil, e
+	}
+	c.rdr = bufio.NewReader(r)
+	return &FrameReader{c: c}, nil
+}
+
+/*
+	SetFrameLimits sets the limits for frames read.  Zero values mean the
+	defaults.
+*/
+func (fr *FrameReader) SetFrameLimits(l FrameLimits) {
+	fr.c.flim = l.resolve()
+}
+
+/*
+	ReadFrame reads the next frame.  A heart beat gives a frame with an empty
+	Command.  Frame limits give a FrameLimitError, and an unknown command
+	gives EINVBCMD.
+*/
+func (fr *FrameReader) ReadFrame() (Frame, error) {
+	f, e := fr.c.readFrameFor(codecCmds)
+	if e == EINVBCMD {
+		return f, fmt.Errorf("%w\n%s", EINVBCMD, HexData([]byte(f.Command)))
+	}
+	if e != nil || f.Command == "" {
+		return f, e
+	}
+	return f, checkHeaders(f.Headers, fr.c.Protocol())
+}
+
+/*
+	NewFrameWriter returns a FrameWriter for a protocol level, one of SPL_10,
+	SPL_11 or SPL_12.
+*/
+func NewFrameWriter(w io.Writer, p string) (*FrameWriter, error) {
+	c, e := codecConnection(p)
+	if e != nil {
+		return nil, e
+	}
+	c.wtr = bufio.NewWriter(w)
+	return &FrameWriter{c: c}, nil
+}
+
+/*
+	WriteFrame writes a frame.  A frame with an empty Command is written as a
+	heart beat.
+*/
+func (fw *FrameWriter) WriteFrame(f Frame) error {
+	if f.Command == "" {
+		if e := fw.c.wtr.WriteByte('\n'); e != nil {
+			return e
+		}
+		return fw.c.wtr.Flush()
+	}
+	if e := checkHeaders(f.Headers, fw.c.Protocol()); e != nil {
+		return e
+	}
+	if _, e := f.writeFrame(fw.c.wtr, fw.c, nil, 0); e != nil {
+		return e
+	}
+	return fw.c.wtr.Flush()
+}
+
+/*
+	WriteProtocol writes the frame to w for a protocol level, as a
+	FrameWriter does.  For many frames to the same writer, use a FrameWriter.
+
+	It is not named WriteTo: go vet expects a WriteTo method to be an
+	io.WriterTo, which has no protocol level.
+
+	Example:
+		f := stompngo.Frame{stompngo.SEND, stompngo.Headers{
+			stompngo.HK_DESTINATION, "/queue/a"}, []byte("body")}
+		e := f.WriteProtocol(os.Stdout, stompngo.SPL_12)
+		if e != nil {
+			// Do something sane ...
+		}
+*/
+func (f *Frame) WriteProtocol(w io.Writer, p string) error {
+	fw, e := NewFrameWriter(w, p)
+	if e != nil {
+		return e
+	}
+	return fw.WriteFrame(*f)
+}
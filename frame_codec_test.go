@@ -0,0 +1,120 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+/*
+	Test FrameWriter and FrameReader round trips.
+*/
+func TestFrameCodec(t *testing.T) {
+	if _, e := NewFrameReader(nil, "1.3"); !errors.Is(e, EBADVERCLI) {
+		t.Fatalf("TestFrameCodec Expected [%v], got [%v]\n", EBADVERCLI, e)
+	}
+	for _, p := range supported {
+		var buf bytes.Buffer
+		fw, e := NewFrameWriter(&buf, p)
+		if e != nil {
+			t.Fatalf("TestFrameCodec Expected no error, got [%v]\n", e)
+		}
+		h := Headers{HK_DESTINATION, "/queue/frame.codec", "k", "v:x"}
+		in := []Frame{
+			{SEND, h, []byte("body\x00with NUL")},
+			{},
+			{SUBSCRIBE, Headers{HK_DESTINATION, "/queue/a", HK_ID, "1"}, NULLBUFF},
+			{MESSAGE, Headers{HK_SUPPRESS_CL, "y", HK_SUPPRESS_CT, "y"},
+				[]byte("cut\x00here")},
+			{CONNECT, Headers{HK_LOGIN, "a:b", HK_PASSCODE, "c\\d"}, NULLBUFF},
+		}
+		for _, f := range in {
+			if e = fw.WriteFrame(f); e != nil {
+				t.Fatalf("TestFrameCodec Expected no write error, got [%v]\n", e)
+			}
+		}
+		if len(h) != 4 || h[3] != "v:x" {
+			t.Fatalf("TestFrameCodec Expected unchanged headers, got [%q]\n", h)
+		}
+		fr, _ := NewFrameReader(&buf, p)
+		for i, want := range in {
+			f, e := fr.ReadFrame()
+			if e != nil {
+				t.Fatalf("TestFrameCodec Expected no read error, got [%v] [%s]\n",
+					e, p)
+			}
+			wb := want.Body
+			if i == 3 {
+				wb = []byte("cut") // Body ends at the NUL
+			}
+			if f.Command != want.Command || !bytes.Equal(f.Body, wb) {
+				t.Fatalf("TestFrameCodec Expected [%s] [%q], got [%s] [%q]\n",
+					want.Command, wb, f.Command, f.Body)
+			}
+			for j := 0; j < len(want.Headers); j += 2 {
+				k := want.Headers[j]
+				if k == HK_SUPPRESS_CL || k == HK_SUPPRESS_CT {
+					continue
+				}
+				if v := f.Headers.Value(k); v != want.Headers[j+1] {
+					t.Fatalf("TestFrameCodec Expected [%s:%s], got [%s] [%s]\n", k,
+						want.Headers[j+1], v, p)
+				}
+			}
+		}
+		if _, e = fr.ReadFrame(); e != io.EOF {
+			t.Fatalf("TestFrameCodec Expected [%v], got [%v]\n", io.EOF, e)
+		}
+	}
+}
+
+/*
+	Test that CONNECT headers are written as is, and unknown commands fail.
+*/
+func TestFrameCodecRaw(t *testing.T) {
+	var buf bytes.Buffer
+	fw, _ := NewFrameWriter(&buf, SPL_12)
+	_ = fw.WriteFrame(Frame{STOMP, Headers{HK_PASSCODE, "a:b", HK_SUPPRESS_CT,
+		"y", HK_SUPPRESS_CL, "y"}, NULLBUFF})
+	want := "STOMP\npasscode:a:b\nsuppress-content-type:y\n" +
+		"suppress-content-length:y\n\n\x00"
+	if buf.String() != want {
+		t.Fatalf("TestFrameCodecRaw Expected [%q], got [%q]\n", want, buf.String())
+	}
+	// The same from a frame
+	buf.Reset()
+	f := Frame{STOMP, Headers{HK_PASSCODE, "a:b", HK_SUPPRESS_CT, "y",
+		HK_SUPPRESS_CL, "y"}, NULLBUFF}
+	if e := f.WriteProtocol(&buf, SPL_12); e != nil || buf.String() != want {
+		t.Fatalf("TestFrameCodecRaw Expected [%q], got [%q] [%v]\n", want,
+			buf.String(), e)
+	}
+	if e := f.WriteProtocol(&buf, "1.3"); !errors.Is(e, EBADVERCLI) {
+		t.Fatalf("TestFrameCodecRaw Expected [%v], got [%v]\n", EBADVERCLI, e)
+	}
+	fr, _ := NewFrameReader(bytes.NewBufferString("BOGUS\n\n\x00"), SPL_12)
+	if _, e := fr.ReadFrame(); !errors.Is(e, EINVBCMD) {
+		t.Fatalf("TestFrameCodecRaw Expected [%v], got [%v]\n", EINVBCMD, e)
+	}
+	fr, _ = NewFrameReader(bytes.NewBufferString("SEND\nk:v\\t\n\n\x00"), SPL_12)
+	if _, e := fr.ReadFrame(); !errors.Is(e, EHDRESC) {
+		t.Fatalf("TestFrameCodecRaw Expected [%v], got [%v]\n", EHDRESC, e)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build go1.18
+// +build go1.18
+
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"testing"
+)
+
+/*
+	Fuzz FrameReader.  Any frame read at STOMP 1.1 or 1.2 must write and read
+	back the same.  STOMP 1.0 headers are decoded but never encoded, so they
+	only must not panic.
+*/
+func FuzzFrameReader(f *testing.F) {
+	f.Add([]byte("MESSAGE\ndestination:/queue/a\nmessage-id:1\n\nbody\x00"), byte(2))
+	f.Add([]byte("SEND\nk:a\\cb\\\\c\\nd\\re\ncontent-length:3\n\na\x00b\x00"), byte(2))
+	f.Add([]byte("\r\n\nCONNECTED\r\nversion:1.1\r\n\r\n\x00"), byte(1))
+	f.Add([]byte("ERROR\nmessage:x\nmessage:y\n\n\x00"), byte(0))
+	f.Fuzz(func(t *testing.T, b []byte, pb byte) {
+		p := supported[int(pb)%len(supported)]
+		fr, _ := NewFrameReader(bytes.NewReader(b), p)
+		fr.SetFrameLimits(FrameLimits{MaxBody: 4096})
+		in, e := fr.ReadFrame()
+		if e != nil || p == SPL_10 {
+			return
+		}
+		var buf bytes.Buffer
+		fw, _ := NewFrameWriter(&buf, p)
+		if e = fw.WriteFrame(in); e != nil {
+			t.Fatalf("write of %q failed: %v", in, e)
+		}
+		fr, _ = NewFrameReader(&buf, p)
+		out, e := fr.ReadFrame()
+		if e != nil {
+			t.Fatalf("read of %q failed: %v", buf.Bytes(), e)
+		}
+		if in.Command != out.Command || !bytes.Equal(in.Body, out.Body) {
+			t.Fatalf("frame %q read back as %q", in, out)
+		}
+		for i := 0; i < len(in.Headers); i += 2 {
+			if v := out.Headers.Value(in.Headers[i]); v != in.Headers.Value(in.Headers[i]) {
+				t.Fatalf("header %q read back as %q", in.Headers[i], v)
+			}
+		}
+	})
+}
+
+/*
+	Fuzz FrameWriter.  A SEND frame with any valid header and body must read
+	back the same.
+*/
+func FuzzFrameWriter(f *testing.F) {
+	f.Add("k", "v", []byte("body"), byte(2))
+	f.Add("a:b", "c\\d\ne\rf", []byte("x\x00y"), byte(1))
+	f.Fuzz(func(t *testing.T, k, v string, b []byte, pb byte) {
+		p := supported[1+int(pb)%2] // Headers are encoded from 1.1
+		in := Frame{SEND, Headers{k, v, HK_DESTINATION, "/queue/fuzz"}, b}
+		var buf bytes.Buffer
+		fw, _ := NewFrameWriter(&buf, p)
+		if fw.WriteFrame(in) != nil {
+			return // Not valid headers
+		}
+		fr, _ := NewFrameReader(&buf, p)
+		fr.SetFrameLimits(FrameLimits{MaxHeaderLine: 1 << 20,
+			MaxHeaderBytes: 1 << 20, MaxBody: 1 << 20})
+		out, e := fr.ReadFrame()
+		if e != nil {
+			t.Fatalf("read of %q failed: %v", buf.Bytes(), e)
+		}
+		if out.Command != SEND || !bytes.Equal(out.Body, b) ||
+			out.Headers.Value(k) != v {
+			t.Fatalf("frame %q read back as %q", in, out)
+		}
+	})
+}
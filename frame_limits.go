@@ -0,0 +1,144 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bufio"
+	"fmt"
+)
+
+/*
+	FrameLimits bound the frames read from the broker.  A zero field means the
+	default, and a negative field means no limit.
+
+	A frame that breaks a limit ends the connection with a *FrameLimitError.
+	The reader never allocates more than the limit allows.
+*/
+type FrameLimits struct {
+	MaxHeaders     int // Number of header lines
+	MaxHeaderLine  int // Bytes in one header line, or in the command line
+	MaxHeaderBytes int // Bytes in all header lines
+	MaxBody        int // Bytes in the body
+}
+
+/*
+	Default frame limits.
+*/
+const (
+	DefaultMaxHeaders     = 1000
+	DefaultMaxHeaderLine  = 64 * 1024
+	DefaultMaxHeaderBytes = 1024 * 1024
+	DefaultMaxBody        = 64 * 1024 * 1024
+)
+
+// Large enough to never be reached, small enough to never overflow
+const noFrameLimit = int(^uint(0) >> 2)
+
+/*
+	FrameLimitError reports a broken frame limit.  It matches EFRAMELIMIT with
+	errors.Is.
+*/
+type FrameLimitError struct {
+	Limit string // The FrameLimits field name
+	Max   int    // The limit
+}
+
+func (e *FrameLimitError) Error() string {
+	return fmt.Sprintf("%s: %s %d", EFRAMELIMIT, e.Limit, e.Max)
+}
+
+/*
+	Is reports whether target is EFRAMELIMIT.
+*/
+func (e *FrameLimitError) Is(target error) bool {
+	return target == EFRAMELIMIT
+}
+
+/*
+	Limits with defaults filled in, and no limit made explicit.
+*/
+func (l FrameLimits) resolve() FrameLimits {
+	f := func(v, d int) int {
+		switch {
+		case v == 0:
+			return d
+		case v < 0:
+			return noFrameLimit
+		}
+		return v
+	}
+	return FrameLimits{MaxHeaders: f(l.MaxHeaders, DefaultMaxHeaders),
+		MaxHeaderLine:  f(l.MaxHeaderLine, DefaultMaxHeaderLine),
+		MaxHeaderBytes: f(l.MaxHeaderBytes, DefaultMaxHeaderBytes),
+		MaxBody:        f(l.MaxBody, DefaultMaxBody)}
+}
+
+/*
+	Read one line, ending with LF.  At most lim bytes before the line end are
+	accepted.  A line that fits the read buffer is returned in place, and is
+	only valid until the next read.
+*/
+func readLimitedLine(r *bufio.Reader, lim int) ([]byte, error) {
+	b, e := r.ReadSlice('\n')
+	if e != bufio.ErrBufferFull {
+		if len(b) > lim+2 || (e == nil && len(trimEOL(b)) > lim) {
+			return nil, &FrameLimitError{"MaxHeaderLine", lim}
+		}
+		return b, e
+	}
+	var l []byte
+	for {
+		if len(l)+len(b) > lim+2 { // Room for CRLF
+			return nil, &FrameLimitError{"MaxHeaderLine", lim}
+		}
+		l = append(l, b...)
+		if e != bufio.ErrBufferFull {
+			break
+		}
+		b, e = r.ReadSlice('\n')
+	}
+	if e == nil && len(trimEOL(l)) > lim {
+		return nil, &FrameLimitError{"MaxHeaderLine", lim}
+	}
+	return l, e
+}
+
+/*
+	Read through the next NUL.  At most lim bytes before the NUL are accepted.
+	Data that fits the read buffer is returned in place, and is only valid
+	until the next read.
+*/
+func readLimitedNul(r *bufio.Reader, lim int) ([]byte, error) {
+	b, e := r.ReadSlice(0)
+	if len(b) > lim+1 {
+		return nil, &FrameLimitError{"MaxBody", lim}
+	}
+	if e != bufio.ErrBufferFull {
+		return b, e
+	}
+	l := append([]byte(nil), b...)
+	for {
+		b, e = r.ReadSlice(0)
+		if len(l)+len(b) > lim+1 {
+			return nil, &FrameLimitError{"MaxBody", lim}
+		}
+		l = append(l, b...)
+		if e != bufio.ErrBufferFull {
+			return l, e
+		}
+	}
+}
@@ -0,0 +1,80 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test that each inbound frame limit ends the connection with a
+	FrameLimitError.
+*/
+func TestFrameLimits(t *testing.T) {
+	lims := FrameLimits{MaxHeaders: 8, MaxHeaderLine: 64, MaxHeaderBytes: 128,
+		MaxBody: 256}
+	for _, tv := range []struct {
+		limit string
+		frame string
+	}{
+		{"MaxHeaderLine", "MESSAGE\nh:" + strings.Repeat("x", 64) + "\n\n\x00"},
+		{"MaxHeaderLine", strings.Repeat("M", 100) + "\n\n\x00"},
+		{"MaxHeaders", "MESSAGE\na:1\nb:2\nc:3\nd:4\ne:5\nf:6\ng:7\nh:8\ni:9\n\n\x00"},
+		{"MaxHeaderBytes", "MESSAGE\na:" + strings.Repeat("a", 60) +
+			"\nb:" + strings.Repeat("b", 60) + "\nc:" + strings.Repeat("c", 60) +
+			"\n\n\x00"},
+		{"MaxBody", "MESSAGE\ncontent-length:2147483647\n\nxyz"},
+		{"MaxBody", "MESSAGE\n\n" + strings.Repeat("z", 300) + "\x00"},
+	} {
+		raw := tv.frame
+		b := fakebroker.New(&fakebroker.Config{
+			Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+				if f.Command == SUBSCRIBE {
+					s.WriteRaw([]byte(raw))
+					return true
+				}
+				return false
+			}})
+		n := b.Pipe()
+		conn, e := ConnectWithOptions(n, headersProtocol(login_headers, SPL_12),
+			&ConnectOptions{FrameLimits: lims})
+		if e != nil {
+			t.Fatalf("TestFrameLimits CONNECT Failed: e:<%q>\n", e)
+		}
+		sc, e := conn.Subscribe(Headers{HK_DESTINATION, "/queue/frame.limits",
+			HK_ID, "flid"})
+		if e != nil {
+			t.Fatalf("TestFrameLimits Expected no subscribe error, got [%v]\n", e)
+		}
+		md := <-sc
+		var fe *FrameLimitError
+		if !errors.Is(md.Error, EFRAMELIMIT) || !errors.As(md.Error, &fe) ||
+			fe.Limit != tv.limit {
+			t.Fatalf("TestFrameLimits Expected [%v], got [%v]\n", tv.limit, md.Error)
+		}
+		<-conn.rdrdc
+		if conn.Connected() {
+			t.Fatalf("TestFrameLimits Expected connection end for [%v]\n", tv.limit)
+		}
+		_ = closeConn(t, n)
+		b.Close()
+	}
+}
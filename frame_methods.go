@@ -40,20 +40,17 @@ func (f *Frame) Bytes(sclok bool) []byte {
 		b = append(b, hb...)
 	}
 	b = append(b, "\n"...)
-	if len(f.Body) > 0 {
+	body := f.Body // The frame is not changed
+	if len(body) > 0 {
 		if sclok {
-			nz := bytes.IndexByte(f.Body, 0)
+			nz := bytes.IndexByte(body, 0)
 			// fmt.Printf("WDBG41 ok:%v\n", nz)
-			if nz == 0 {
-				f.Body = []byte{}
-				// fmt.Printf("WDBG42 body:%v bodystring: %v\n", f.Body, string(f.Body))
-			} else if nz > 0 {
-				f.Body = f.Body[0:nz]
-				// fmt.Printf("WDBG43 body:%v bodystring: %v\n", f.Body, string(f.Body))
+			if nz >= 0 {
+				body = body[0:nz]
 			}
 		}
-		if len(f.Body) > 0 {
-			b = append(b, f.Body...)
+		if len(body) > 0 {
+			b = append(b, body...)
 		}
 	}
 	b = append(b, ZRB...)
@@ -0,0 +1,139 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"fmt"
+)
+
+/*
+	Byte level helpers for the frame parser.  Lines are parsed in place, in
+	the bufio.Reader buffer, and only the decoded results become strings.
+*/
+
+/*
+	A line without its LF or CRLF.
+*/
+func trimEOL(b []byte) []byte {
+	if n := len(b); n > 0 && b[n-1] == '\n' {
+		b = b[:n-1]
+	}
+	if n := len(b); n > 0 && b[n-1] == '\r' {
+		b = b[:n-1]
+	}
+	return b
+}
+
+/*
+	Whether headers are escaped for a command.  CONNECT, STOMP and CONNECTED
+	headers never are.
+*/
+func escapedCommand(cmd string) bool {
+	return cmd != CONNECT && cmd != STOMP && cmd != CONNECTED
+}
+
+/*
+	The command for a command line.  Known commands are never allocated.
+*/
+func commandString(b []byte) string {
+	switch string(b) { // No allocation here
+	case MESSAGE:
+		return MESSAGE
+	case RECEIPT:
+		return RECEIPT
+	case ERROR:
+		return ERROR
+	case CONNECTED:
+		return CONNECTED
+	}
+	return string(b)
+}
+
+/*
+	A header key.  Keys the broker sends on every frame are never allocated.
+*/
+func headerKey(b []byte) string {
+	switch string(b) { // No allocation here
+	case HK_DESTINATION:
+		return HK_DESTINATION
+	case HK_MESSAGE_ID:
+		return HK_MESSAGE_ID
+	case HK_SUBSCRIPTION:
+		return HK_SUBSCRIPTION
+	case HK_CONTENT_LENGTH:
+		return HK_CONTENT_LENGTH
+	case HK_CONTENT_TYPE:
+		return HK_CONTENT_TYPE
+	case HK_ACK:
+		return HK_ACK
+	case HK_RECEIPT_ID:
+		return HK_RECEIPT_ID
+	case HK_MESSAGE:
+		return HK_MESSAGE
+	}
+	return string(b)
+}
+
+/*
+	Decode header bytes in one pass, so that an escaped backslash is never
+	part of another escape sequence.  When strict, an undefined escape
+	sequence, which STOMP 1.2 makes a fatal error, gives EHDRESC.  When key,
+	common header keys are interned.
+*/
+func unescapeBytes(b []byte, strict, key bool) (string, error) {
+	i := bytes.IndexByte(b, '\\')
+	if i < 0 {
+		if key {
+			return headerKey(b), nil
+		}
+		return string(b), nil
+	}
+	r := make([]byte, 0, len(b))
+	r = append(r, b[:i]...)
+	for ; i < len(b); i++ {
+		if b[i] != '\\' {
+			r = append(r, b[i])
+			continue
+		}
+		if i+1 < len(b) {
+			switch b[i+1] {
+			case '\\':
+				r = append(r, '\\')
+				i++
+				continue
+			case 'n':
+				r = append(r, '\n')
+				i++
+				continue
+			case 'r':
+				r = append(r, '\r')
+				i++
+				continue
+			case 'c':
+				r = append(r, ':')
+				i++
+				continue
+			}
+		}
+		if strict {
+			return "", fmt.Errorf("%w: %q", EHDRESC, b)
+		}
+		r = append(r, b[i])
+	}
+	return string(r), nil
+}
@@ -219,15 +219,16 @@ func TestHBNoSend(t *testing.T) {
 			conn.SetLogger(l)
 		}
 		//
-		conn.log("TestHBNoSend start sleep")
-		conn.log("TestHBNoSend connect response",
-			conn.ConnectResponse.Command,
-			conn.ConnectResponse.Headers,
-			string(conn.ConnectResponse.Body))
-		conn.log(1, "Send", conn.SendTickerInterval(), "Receive",
-			conn.ReceiveTickerInterval())
+		conn.debug("TestHBNoSend start sleep")
+		conn.debug("TestHBNoSend connect response",
+			"command", conn.ConnectResponse.Command,
+			"headers", conn.ConnectResponse.Headers,
+			"body", string(conn.ConnectResponse.Body))
+		conn.debug("TestHBNoSend ticker intervals",
+			"send", conn.SendTickerInterval(),
+			"receive", conn.ReceiveTickerInterval())
 		time.Sleep(hbs * time.Second)
-		conn.log("TestHBNoSend end sleep")
+		conn.debug("TestHBNoSend end sleep")
 		//
 		conn.hbd.rdl.Lock()
 		if conn.Hbrf {
@@ -274,15 +275,16 @@ func TestHBNoReceive(t *testing.T) {
 			conn.SetLogger(l)
 		}
 		//
-		conn.log("TestHBNoReceive start sleep")
-		conn.log("TestHBNoReceive connect response",
-			conn.ConnectResponse.Command,
-			conn.ConnectResponse.Headers,
-			string(conn.ConnectResponse.Body))
-		conn.log(2, "Send", conn.SendTickerInterval(), "Receive",
-			conn.ReceiveTickerInterval())
+		conn.debug("TestHBNoReceive start sleep")
+		conn.debug("TestHBNoReceive connect response",
+			"command", conn.ConnectResponse.Command,
+			"headers", conn.ConnectResponse.Headers,
+			"body", string(conn.ConnectResponse.Body))
+		conn.debug("TestHBNoReceive ticker intervals",
+			"send", conn.SendTickerInterval(),
+			"receive", conn.ReceiveTickerInterval())
 		time.Sleep(hbs * time.Second)
-		conn.log("TestHBNoReceive end sleep")
+		conn.debug("TestHBNoReceive end sleep")
 		//
 		checkHBSend(t, conn, 2)
 		checkReceived(t, conn, false)
@@ -326,15 +328,16 @@ func TestHBSendReceive(t *testing.T) {
 			conn.SetLogger(l)
 		}
 		//
-		conn.log("TestHBSendReceive start sleep")
-		conn.log("TestHBSendReceive connect response",
-			conn.ConnectResponse.Command,
-			conn.ConnectResponse.Headers,
-			string(conn.ConnectResponse.Body))
-		conn.log(3, "Send", conn.SendTickerInterval(), "Receive",
-			conn.ReceiveTickerInterval())
+		conn.debug("TestHBSendReceive start sleep")
+		conn.debug("TestHBSendReceive connect response",
+			"command", conn.ConnectResponse.Command,
+			"headers", conn.ConnectResponse.Headers,
+			"body", string(conn.ConnectResponse.Body))
+		conn.debug("TestHBSendReceive ticker intervals",
+			"send", conn.SendTickerInterval(),
+			"receive", conn.ReceiveTickerInterval())
 		time.Sleep(hbs * time.Second)
-		conn.log("TestHBSendReceive end sleep")
+		conn.debug("TestHBSendReceive end sleep")
 		conn.hbd.rdl.Lock()
 		if conn.Hbrf {
 			t.Fatalf("TestHBSendReceive Error, dirty heart beat read detected")
@@ -385,15 +388,16 @@ func TestHBSendReceiveApollo(t *testing.T) {
 			conn.SetLogger(l)
 		}
 		//
-		conn.log("TestHBSendReceiveApollo start sleep")
-		conn.log("TestHBSendReceiveApollo connect response",
-			conn.ConnectResponse.Command,
-			conn.ConnectResponse.Headers,
-			string(conn.ConnectResponse.Body))
-		conn.log(4, "Send", conn.SendTickerInterval(), "Receive",
-			conn.ReceiveTickerInterval())
+		conn.debug("TestHBSendReceiveApollo start sleep")
+		conn.debug("TestHBSendReceiveApollo connect response",
+			"command", conn.ConnectResponse.Command,
+			"headers", conn.ConnectResponse.Headers,
+			"body", string(conn.ConnectResponse.Body))
+		conn.debug("TestHBSendReceiveApollo ticker intervals",
+			"send", conn.SendTickerInterval(),
+			"receive", conn.ReceiveTickerInterval())
 		time.Sleep(hbs * time.Second)
-		conn.log("TestHBSendReceiveApollo end sleep")
+		conn.debug("TestHBSendReceiveApollo end sleep")
 		conn.hbd.rdl.Lock()
 		if conn.Hbrf {
 			t.Fatalf("TestHBSendReceiveApollo Error, dirty heart beat read detected")
@@ -448,16 +452,17 @@ func TestHBSendReceiveRevApollo(t *testing.T) {
 			conn.SetLogger(l)
 		}
 		//
-		conn.log("TestHBSendReceiveRevApollo start sleep")
-		conn.log("TestHBSendReceiveRevApollo connect response",
-			conn.ConnectResponse.Command,
-			conn.ConnectResponse.Headers,
-			string(conn.ConnectResponse.Body))
-		conn.log(5, "Send", conn.SendTickerInterval(), "Receive",
-			conn.ReceiveTickerInterval())
+		conn.debug("TestHBSendReceiveRevApollo start sleep")
+		conn.debug("TestHBSendReceiveRevApollo connect response",
+			"command", conn.ConnectResponse.Command,
+			"headers", conn.ConnectResponse.Headers,
+			"body", string(conn.ConnectResponse.Body))
+		conn.debug("TestHBSendReceiveRevApollo ticker intervals",
+			"send", conn.SendTickerInterval(),
+			"receive", conn.ReceiveTickerInterval())
 		time.Sleep(hbs * time.Second)
 		//time.Sleep(30 * time.Second) // For experimentation
-		conn.log("TestHBSendReceiveRevApollo end sleep")
+		conn.debug("TestHBSendReceiveRevApollo end sleep")
 		conn.hbd.rdl.Lock()
 		if conn.Hbrf {
 			t.Fatalf("TestHBSendReceiveRevApollo Error, dirty heart beat read detected")
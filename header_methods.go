@@ -52,7 +52,8 @@ func (h Headers) Compare(other Headers) bool {
 }
 
 /*
-	Contains returns true if a set of Headers contains a key.
+	Contains returns true if a set of Headers contains a key.  For a repeated
+	key the first value is returned, as STOMP 1.2 requires.
 */
 func (h Headers) Contains(k string) (string, bool) {
 	for i := 0; i < len(h); i += 2 {
@@ -77,7 +78,8 @@ func (h Headers) ContainsKV(k string, v string) bool {
 
 /*
 	Value returns a header value for a specified key.  If the key is not present
-	an empty string is returned.
+	an empty string is returned.  For a repeated key the first value is
+	returned, as STOMP 1.2 requires.
 */
 func (h Headers) Value(k string) string {
 	for i := 0; i < len(h); i += 2 {
@@ -146,6 +148,21 @@ func (h Headers) Delete(k string) Headers {
 	return r
 }
 
+/*
+	DeleteAll removes every key and value pair for a key from a set of
+	Headers.  A repeated key is removed completely, so that
+	h.DeleteAll(k).Add(k, v) always gives v as the value.
+*/
+func (h Headers) DeleteAll(k string) Headers {
+	r := make(Headers, 0, len(h))
+	for i := 0; i+1 < len(h); i += 2 {
+		if h[i] != k {
+			r = append(r, h[i], h[i+1])
+		}
+	}
+	return r
+}
+
 /*
 	Size returns the size of Headers on the wire, in bytes.
 */
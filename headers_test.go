@@ -130,6 +130,15 @@ func TestHeadersAddDelete(t *testing.T) {
 			t.Fatalf("TestHeadersAddDelete Unexpected length Delete 2, got: [%v], expected: [%v]\n",
 				len(hn), ol-4)
 		}
+		// Repeated keys
+		hr := Headers{"ka", "v1", "kb", "vb", "ka", "v2"}
+		if hd := hr.Delete("ka"); !hd.Compare(Headers{"kb", "vb", "ka", "v2"}) {
+			t.Fatalf("TestHeadersAddDelete Unexpected Delete repeated, got: [%v]\n",
+				hd)
+		}
+		if hd := hr.DeleteAll("ka"); !hd.Compare(Headers{"kb", "vb"}) {
+			t.Fatalf("TestHeadersAddDelete Unexpected DeleteAll, got: [%v]\n", hd)
+		}
 	}
 }
 
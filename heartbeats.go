@@ -17,7 +17,7 @@
 package stompngo
 
 import (
-	"fmt"
+	"context"
 	"strconv"
 	"strings"
 	"time"
@@ -126,19 +126,19 @@ hbSend:
 	for {
 		select {
 		case <-ticker.C:
-			c.log("HeartBeat Send data")
+			c.debug("HeartBeat Send data")
 			// Send a heartbeat
 			f := Frame{"\n", Headers{}, NULLBUFF} // Heartbeat frame
-			r := make(chan error)
-			if e := c.writeWireData(wiredata{f, r}); e != nil {
+			r := make(chan error, 1)
+			if e := c.writeWireData(wiredata{frame: f, errchan: r}); e != nil {
 				c.Hbsf = true
 				break hbSend
 			}
-			e := <-r
+			e := c.awaitWrite(context.Background(), r)
 			//
 			c.hbd.sdl.Lock()
 			if e != nil {
-				fmt.Printf("Heartbeat Send Failure: %v\n", e)
+				c.error("Heartbeat Send Failure", "error", e)
 				c.Hbsf = true
 			} else {
 				c.Hbsf = false
@@ -152,7 +152,7 @@ hbSend:
 			break hbSend
 		} // End of select
 	} // End of for
-	c.log("Heartbeat Send Ends", time.Now())
+	c.debug("Heartbeat Send Ends")
 	return
 }
 
@@ -178,10 +178,12 @@ hbGet:
 			c.hbd.rdl.Lock()
 			flr := c.hbd.lr
 			ld := ct.UnixNano() - flr
-			c.log("HeartBeat Receive TIC", "TickerVal", ct.UnixNano(),
-				"LastReceive", flr, "Diff", ld)
+			if c.logs(LogDebug) {
+				c.debug("HeartBeat Receive TIC", "TickerVal", ct.UnixNano(),
+					"LastReceive", flr, "Diff", ld)
+			}
 			if ld > (c.hbd.rti + (c.hbd.rti / 5)) { // swag plus to be tolerant
-				c.log("HeartBeat Receive Read is dirty")
+				c.warn("HeartBeat Receive Read is dirty")
 				c.Hbrf = true // Flag possible dirty connection
 			} else {
 				c.Hbrf = false // Reset
@@ -197,6 +199,6 @@ hbGet:
 			break hbGet
 		} // End of select
 	} // End of for
-	c.log("Heartbeat Receive Ends", time.Now())
+	c.debug("Heartbeat Receive Ends")
 	return
 }
@@ -25,11 +25,15 @@ import (
 func TestMain(m *testing.M) {
 	flag.Parse()
 	packageInit()
-	os.Exit(m.Run())
+	rc := m.Run()
+	if fakeb != nil {
+		_ = fakeb.Close()
+	}
+	os.Exit(rc)
 }
 
-//
 func packageInit() {
+	fakeb = startFakeBroker()
 	_ = setTestBroker()
 	setHeartBeatFlags()
 }
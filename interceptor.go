@@ -0,0 +1,189 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+/*
+	OutboundInterceptor sees frames just before they are written.  See Use.
+*/
+type OutboundInterceptor interface {
+	Outbound(f *Frame) error
+}
+
+/*
+	InboundInterceptor sees frames just after they are read.  See Use.
+*/
+type InboundInterceptor interface {
+	Inbound(f *Frame) error
+}
+
+/*
+	InterceptFilter is implemented by interceptors that pick the frames they
+	see.  Intercepts is called with the frame command, which is empty for a
+	heart-beat.  Interceptors without it see every frame except CONNECT,
+	STOMP and heart-beats.
+*/
+type InterceptFilter interface {
+	Intercepts(command string) bool
+}
+
+/*
+	The interceptor chains of a connection.
+*/
+type icptChains struct {
+	out []OutboundInterceptor
+	in  []InboundInterceptor
+}
+
+/*
+	Use adds interceptors to the end of the connection's chains.  Each must
+	be an OutboundInterceptor, an InboundInterceptor, or both.  Anything else
+	gives EBADICPT, and nothing is added.  Interceptors for the CONNECT frame
+	are set with ConnectOptions.Interceptors.
+
+	Interceptors run in the order added.  Each may change the frame, and
+	returns:
+
+		nil       - pass the frame on
+		ESWALLOW  - drop the frame, and skip the rest of the chain
+		any other - veto the frame, and skip the rest of the chain
+
+	A vetoed outbound frame is not written, and the caller gets the error.  A
+	swallowed one is not written either, and the caller gets nil.  A vetoed
+	inbound frame is delivered with the error as MessageData.Error, and is
+	not verified, decrypted or decompressed.  A swallowed one is dropped.
+
+	Outbound frames have copied headers.  The body is the caller's: replace
+	it, do not change it in place.  SEND bodies are seen compressed and
+	encrypted, if they are, and streamed bodies are not seen.  Changes to a
+	heart-beat are ignored.
+
+	Interceptors run on the connection's writer and reader goroutines.  They
+	must not block, and must not send with the connection.
+
+	Example:
+		type stamper struct{}
+		func (stamper) Outbound(f *stompngo.Frame) error {
+			f.Headers = f.Headers.Add("app-id", "billing")
+			return nil
+		}
+		if e := c.Use(stamper{}); e != nil {
+			// Do something sane ...
+		}
+*/
+func (c *Connection) Use(is ...interface{}) error {
+	for _, i := range is {
+		_, o := i.(OutboundInterceptor)
+		_, n := i.(InboundInterceptor)
+		if !o && !n {
+			return EBADICPT
+		}
+	}
+	c.icptLock.Lock()
+	defer c.icptLock.Unlock()
+	for _, i := range is {
+		// Copy on write, chains already handed out are not changed
+		if o, ok := i.(OutboundInterceptor); ok {
+			c.icpts.out = append(c.icpts.out[:len(c.icpts.out):len(c.icpts.out)], o)
+		}
+		if n, ok := i.(InboundInterceptor); ok {
+			c.icpts.in = append(c.icpts.in[:len(c.icpts.in):len(c.icpts.in)], n)
+		}
+	}
+	return nil
+}
+
+/*
+	True if an interceptor sees frames with a command.
+*/
+func intercepts(i interface{}, cmd string) bool {
+	if f, ok := i.(InterceptFilter); ok {
+		return f.Intercepts(cmd)
+	}
+	switch cmd {
+	case "", CONNECT, STOMP:
+		return false
+	}
+	return true
+}
+
+/*
+	Run the outbound chain for wire data.  A changed frame replaces
+	d.frame, and is no longer written as a PreparedFrame.
+*/
+func (c *Connection) interceptOut(d *wiredata) error {
+	c.icptLock.RLock()
+	is := c.icpts.out
+	c.icptLock.RUnlock()
+	if len(is) == 0 {
+		return nil
+	}
+	f, hb, seen := d.frame, d.frame.Command == "\n", false
+	if hb {
+		f.Command = ""
+	}
+	cmd := f.Command
+	for _, i := range is {
+		if !intercepts(i, cmd) {
+			continue
+		}
+		if !seen {
+			f.Headers, seen = f.Headers.Clone(), true
+		}
+		if e := i.Outbound(&f); e != nil {
+			return e
+		}
+	}
+	if seen && !hb {
+		d.frame, d.pf = f, nil
+	}
+	return nil
+}
+
+/*
+	Run the inbound chain for a frame just read.
+*/
+func (c *Connection) interceptIn(f *Frame) error {
+	c.icptLock.RLock()
+	is := c.icpts.in
+	c.icptLock.RUnlock()
+	cmd := f.Command
+	for _, i := range is {
+		if !intercepts(i, cmd) {
+			continue
+		}
+		if e := i.Inbound(f); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+/*
+	Drop a swallowed inbound frame.  A streamed body is read and discarded
+	first.
+*/
+func (c *Connection) dropFrame(f Frame) error {
+	md := MessageData{Message: Message(f)}
+	md.Release()
+	bs := c.rbs
+	c.rbs = nil
+	if bs == nil {
+		return nil
+	}
+	_ = bs.Close()
+	return bs.wait()
+}
@@ -0,0 +1,158 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+type outIcpt func(f *Frame) error
+
+func (i outIcpt) Outbound(f *Frame) error { return i(f) }
+
+type inIcpt func(f *Frame) error
+
+func (i inIcpt) Inbound(f *Frame) error { return i(f) }
+
+// Sees every frame
+type allIcpt struct{ outIcpt }
+
+func (allIcpt) Intercepts(string) bool { return true }
+
+/*
+	Test the interceptor chains.
+*/
+func TestInterceptors(t *testing.T) {
+	veto := errors.New("vetoed")
+	var lock sync.Mutex
+	var cmds []string
+	stamp := allIcpt{func(f *Frame) error {
+		lock.Lock()
+		cmds = append(cmds, f.Command)
+		lock.Unlock()
+		f.Headers = f.Headers.Add("stamp", "yes")
+		return nil
+	}}
+	// ERROR frames on request, the connection is left open
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if v, _ := f.Value("action"); f.Command != SEND || v != "error" {
+				return false
+			}
+			ef := &fakebroker.Frame{Command: ERROR, Body: f.Body}
+			s.Send(ef.Add(HK_MESSAGE, "scripted"))
+			return true
+		}})
+	defer b.Close()
+	conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+		SPL_12), &ConnectOptions{Interceptors: []interface{}{stamp}})
+	if e != nil {
+		t.Fatalf("TestInterceptors CONNECT Failed: e:<%q>\n", e)
+	}
+	if e = conn.Use(stamp, 42); !errors.Is(e, EBADICPT) {
+		t.Fatalf("TestInterceptors Expected [%v], got [%v]\n", EBADICPT, e)
+	}
+	e = conn.Use(outIcpt(func(f *Frame) error {
+		switch f.Headers.Value("action") {
+		case "veto":
+			return veto
+		case "swallow":
+			return ESWALLOW
+		}
+		return nil
+	}), inIcpt(func(f *Frame) error {
+		switch string(f.Body) {
+		case "in-veto":
+			return veto
+		case "in-swallow":
+			return ESWALLOW
+		}
+		return nil
+	}))
+	if e != nil {
+		t.Fatalf("TestInterceptors Expected no Use error, got [%v]\n", e)
+	}
+	d := tdest("/queue/interceptors")
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "icpt"})
+	if e != nil {
+		t.Fatalf("TestInterceptors Expected no subscribe error, got [%v]\n", e)
+	}
+	h := Headers{HK_DESTINATION, d}
+	if e = conn.Send(h.Add("action", "veto"), "out-veto"); !errors.Is(e, veto) {
+		t.Fatalf("TestInterceptors Expected [%v], got [%v]\n", veto, e)
+	}
+	if e = conn.Send(h.Add("action", "swallow"), "out-swallow"); e != nil {
+		t.Fatalf("TestInterceptors Expected no send error, got [%v]\n", e)
+	}
+	p, e := conn.PrepareSend(h)
+	if e != nil {
+		t.Fatalf("TestInterceptors Expected no prepare error, got [%v]\n", e)
+	}
+	for _, s := range []string{"in-swallow", "in-veto", "last"} {
+		if e = conn.SendPrepared(p, []byte(s)); e != nil {
+			t.Fatalf("TestInterceptors Expected no send error, got [%v]\n", e)
+		}
+	}
+	if len(h) != 2 {
+		t.Fatalf("TestInterceptors Expected unchanged headers, got [%q]\n", h)
+	}
+	for _, want := range []string{"in-veto", "last"} {
+		md := <-sc
+		if md.Message.BodyString() != want ||
+			md.Message.Headers.Value("stamp") != "yes" ||
+			(want == "in-veto") != (md.Error == veto) {
+			t.Fatalf("TestInterceptors Expected [%s], got [%q] [%q] [%v]\n", want,
+				md.Message.Body, md.Message.Headers, md.Error)
+		}
+	}
+	// A vetoed ERROR is delivered with the veto, and is not a BrokerError
+	cb := make(chan *BrokerError, 2)
+	conn.OnBrokerError(func(be *BrokerError) { cb <- be })
+	for _, s := range []string{"in-swallow", "in-veto"} {
+		if e = conn.Send(h.Add("action", "error"), s); e != nil {
+			t.Fatalf("TestInterceptors Expected no send error, got [%v]\n", e)
+		}
+	}
+	md := <-conn.MessageData
+	if md.Message.Command != ERROR || md.Message.BodyString() != "in-veto" ||
+		md.Error != veto {
+		t.Fatalf("TestInterceptors Expected vetoed ERROR, got [%v] [%q] [%v]\n",
+			md.Message.Command, md.Message.Body, md.Error)
+	}
+	select {
+	case md = <-sc:
+		t.Fatalf("TestInterceptors Expected no subscription data, got [%v]\n",
+			md.Error)
+	default:
+	}
+	if len(cb) != 0 {
+		t.Fatalf("TestInterceptors Expected no callback, got [%v]\n", <-cb)
+	}
+	// No receipt, the reader may end before it is read
+	checkDisconnectError(t, conn.Disconnect(Headers{"noreceipt", "true"}))
+	lock.Lock()
+	got := strings.Join(cmds, ",")
+	lock.Unlock()
+	if want := "CONNECT,SUBSCRIBE,SEND,SEND,SEND,SEND,SEND,SEND,SEND,DISCONNECT"; got != want {
+		t.Fatalf("TestInterceptors Expected [%s], got [%s]\n", want, got)
+	}
+}
@@ -0,0 +1,207 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+/*
+	Logger is a leveled logger with key and value fields.  kv holds keys and
+	values in turn, as log/slog does, and a *slog.Logger is a Logger.
+
+	A connection adds its own fields to each entry: "session", "protocol" and
+	"endpoint".  A Logger that also implements LogLevelEnabler is asked first,
+	so that entries nobody wants are never built.  See SetLeveledLogger.
+*/
+type Logger interface {
+	Debug(msg string, kv ...interface{})
+	Info(msg string, kv ...interface{})
+	Warn(msg string, kv ...interface{})
+	Error(msg string, kv ...interface{})
+}
+
+/*
+	LogLevel is the severity of a log entry.
+*/
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+func (l LogLevel) String() string {
+	if l < LogDebug || l > LogError {
+		return "LogLevel(" + strconv.Itoa(int(l)) + ")"
+	}
+	return logLevelNames[l]
+}
+
+/*
+	LogLevelEnabler is implemented by Loggers that can say whether a level is
+	logged.
+*/
+type LogLevelEnabler interface {
+	Enabled(l LogLevel) bool
+}
+
+/*
+	NewStdLogger returns a Logger that writes entries of level min and above
+	to l, one line each:
+
+		INFO message key=value key=value
+*/
+func NewStdLogger(l *log.Logger, min LogLevel) Logger {
+	return &stdLogger{l: l, min: min}
+}
+
+type stdLogger struct {
+	l   *log.Logger
+	min LogLevel
+}
+
+func (s *stdLogger) Enabled(l LogLevel) bool { return l >= s.min }
+
+func (s *stdLogger) Debug(msg string, kv ...interface{}) { s.log(LogDebug, msg, kv, 3) }
+func (s *stdLogger) Info(msg string, kv ...interface{})  { s.log(LogInfo, msg, kv, 3) }
+func (s *stdLogger) Warn(msg string, kv ...interface{})  { s.log(LogWarn, msg, kv, 3) }
+func (s *stdLogger) Error(msg string, kv ...interface{}) { s.log(LogError, msg, kv, 3) }
+
+/*
+	Write one line.  depth is the log.Logger call depth of the caller to
+	report, for Lshortfile and Llongfile.
+*/
+func (s *stdLogger) log(l LogLevel, msg string, kv []interface{}, depth int) {
+	if l < s.min {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(l.String() + " " + msg)
+	for i := 0; i < len(kv); i += 2 {
+		k, v := fmt.Sprint(kv[i]), interface{}("")
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		} else {
+			k, v = "!BADKEY", kv[i] // As log/slog does
+		}
+		b.WriteString(" " + k + "=" + logValue(v))
+	}
+	_ = s.l.Output(depth, b.String())
+}
+
+/*
+	A field value for a log line.  Strings with spaces or quotes are quoted.
+*/
+func logValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+/*
+	SetLeveledLogger sets the Logger for this connection.  Set to nil to
+	disable logging.
+
+	Example:
+		// Warnings and errors only
+		l := log.New(os.Stderr, "", log.LstdFlags)
+		c.SetLeveledLogger(stompngo.NewStdLogger(l, stompngo.LogWarn))
+*/
+func (c *Connection) SetLeveledLogger(l Logger) {
+	c.logLock.Lock()
+	c.logger, c.stdlog = l, nil
+	c.logLock.Unlock()
+}
+
+/*
+	GetLeveledLogger returns the current connection Logger.
+*/
+func (c *Connection) GetLeveledLogger() Logger {
+	c.logLock.RLock()
+	defer c.logLock.RUnlock()
+	return c.logger
+}
+
+/*
+	The connection logger, if it logs a level.
+*/
+func (c *Connection) loggerFor(lv LogLevel) Logger {
+	c.logLock.RLock()
+	l := c.logger
+	c.logLock.RUnlock()
+	if l == nil {
+		return nil
+	}
+	if le, ok := l.(LogLevelEnabler); ok && !le.Enabled(lv) {
+		return nil
+	}
+	return l
+}
+
+/*
+	True if the connection logs a level.  For entries that are costly to
+	build.
+*/
+func (c *Connection) logs(lv LogLevel) bool {
+	return c.loggerFor(lv) != nil
+}
+
+/*
+	Log an entry, with the connection fields first.
+*/
+func (c *Connection) logAt(lv LogLevel, msg string, kv []interface{}) {
+	l := c.loggerFor(lv)
+	if l == nil {
+		return
+	}
+	c.sessLock.Lock()
+	s := c.session
+	c.sessLock.Unlock()
+	f := make([]interface{}, 0, len(kv)+6)
+	f = append(f, "session", s, "protocol", c.Protocol(), "endpoint",
+		c.endpoint)
+	f = append(f, kv...)
+	if sl, ok := l.(*stdLogger); ok {
+		sl.log(lv, msg, f, 4) // Report the caller of c.debug and friends
+		return
+	}
+	switch lv {
+	case LogDebug:
+		l.Debug(msg, f...)
+	case LogInfo:
+		l.Info(msg, f...)
+	case LogWarn:
+		l.Warn(msg, f...)
+	default:
+		l.Error(msg, f...)
+	}
+}
+
+func (c *Connection) debug(msg string, kv ...interface{}) { c.logAt(LogDebug, msg, kv) }
+func (c *Connection) info(msg string, kv ...interface{})  { c.logAt(LogInfo, msg, kv) }
+func (c *Connection) warn(msg string, kv ...interface{})  { c.logAt(LogWarn, msg, kv) }
+func (c *Connection) error(msg string, kv ...interface{}) { c.logAt(LogError, msg, kv) }
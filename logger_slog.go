@@ -0,0 +1,48 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.21
+// +build go1.21
+
+package stompngo
+
+import (
+	"context"
+	"log/slog"
+)
+
+/*
+	NewSlogLogger returns a Logger that writes to l.  A *slog.Logger is
+	already a Logger: this one also implements LogLevelEnabler, so that
+	entries below the handler level are never built.
+*/
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	*slog.Logger
+}
+
+var slogLevels = [...]slog.Level{slog.LevelDebug, slog.LevelInfo,
+	slog.LevelWarn, slog.LevelError}
+
+func (s slogLogger) Enabled(l LogLevel) bool {
+	if l < LogDebug || l > LogError {
+		l = LogError
+	}
+	return s.Logger.Enabled(context.Background(), slogLevels[l])
+}
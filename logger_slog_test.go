@@ -0,0 +1,47 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.21
+// +build go1.21
+
+package stompngo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+/*
+	Test the log/slog adapter.
+*/
+func TestSlogLogger(t *testing.T) {
+	var b bytes.Buffer
+	h := slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelWarn})
+	l := NewSlogLogger(slog.New(h))
+	le := l.(LogLevelEnabler)
+	if le.Enabled(LogInfo) || !le.Enabled(LogWarn) {
+		t.Fatalf("TestSlogLogger Expected LogWarn and above enabled\n")
+	}
+	l.Info("hidden")
+	l.Warn("shown", "k", "a b")
+	if got := b.String(); !strings.Contains(got, `level=WARN msg=shown k="a b"`) ||
+		strings.Contains(got, "hidden") {
+		t.Fatalf("TestSlogLogger Expected a WARN entry, got [%s]\n", got)
+	}
+	var _ Logger = slog.Default() // Already a Logger
+}
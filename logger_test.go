@@ -17,10 +17,17 @@
 package stompngo
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
 )
 
 /*
@@ -137,3 +144,94 @@ func TestLoggerMiscBytes0(t *testing.T) {
 	}
 
 }
+
+/*
+	A Logger that keeps its entries.
+*/
+type testLogger struct {
+	lock sync.Mutex
+	min  LogLevel
+	ents []string
+}
+
+func (l *testLogger) Enabled(lv LogLevel) bool { return lv >= l.min }
+
+func (l *testLogger) add(lv LogLevel, msg string, kv []interface{}) {
+	l.lock.Lock()
+	l.ents = append(l.ents, fmt.Sprint(lv, " ", msg, " ", kv))
+	l.lock.Unlock()
+}
+
+func (l *testLogger) Debug(msg string, kv ...interface{}) { l.add(LogDebug, msg, kv) }
+func (l *testLogger) Info(msg string, kv ...interface{})  { l.add(LogInfo, msg, kv) }
+func (l *testLogger) Warn(msg string, kv ...interface{})  { l.add(LogWarn, msg, kv) }
+func (l *testLogger) Error(msg string, kv ...interface{}) { l.add(LogError, msg, kv) }
+
+func (l *testLogger) entries() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return strings.Join(l.ents, "\n")
+}
+
+/*
+	Test the standard library log.Logger adapter.
+*/
+func TestStdLogger(t *testing.T) {
+	var b bytes.Buffer
+	l := NewStdLogger(log.New(&b, "", 0), LogInfo)
+	l.Debug("hidden", "k", "v")
+	l.Info("shown", "k", "v", "q", "a b", "n", 42, "odd")
+	want := "INFO shown k=v q=\"a b\" n=42 !BADKEY=odd\n"
+	if b.String() != want {
+		t.Fatalf("TestStdLogger Expected [%q], got [%q]\n", want, b.String())
+	}
+	if le, ok := l.(LogLevelEnabler); !ok || le.Enabled(LogDebug) ||
+		!le.Enabled(LogError) {
+		t.Fatalf("TestStdLogger Expected LogInfo and above enabled\n")
+	}
+}
+
+/*
+	Test the leveled connection logger, and its connection fields.
+*/
+func TestLeveledLogger(t *testing.T) {
+	b := fakebroker.New(nil)
+	defer b.Close()
+	tl := &testLogger{min: LogDebug}
+	conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+		SPL_12), &ConnectOptions{Logger: tl})
+	if e != nil {
+		t.Fatalf("TestLeveledLogger CONNECT Failed: e:<%q>\n", e)
+	}
+	if conn.GetLeveledLogger() != tl || conn.GetLogger() != nil {
+		t.Fatalf("TestLeveledLogger Expected the ConnectOptions Logger\n")
+	}
+	d := tdest("/queue/leveled.logger")
+	if e = conn.Send(Headers{HK_DESTINATION, d}, "logged"); e != nil {
+		t.Fatalf("TestLeveledLogger Expected no send error, got [%v]\n", e)
+	}
+	want := fmt.Sprint("DEBUG SEND start ", []interface{}{"session",
+		conn.Session(), "protocol", SPL_12, "endpoint", conn.Endpoint(),
+		"headers", Headers{HK_DESTINATION, d}})
+	if got := tl.entries(); !strings.Contains(got, want) {
+		t.Fatalf("TestLeveledLogger Expected [%s], got [%s]\n", want, got)
+	}
+	// Not enabled, not logged
+	tl.lock.Lock()
+	tl.min, tl.ents = LogWarn, nil
+	tl.lock.Unlock()
+	if e = conn.Send(Headers{HK_DESTINATION, d}, "not logged"); e != nil {
+		t.Fatalf("TestLeveledLogger Expected no send error, got [%v]\n", e)
+	}
+	if got := tl.entries(); got != "" {
+		t.Fatalf("TestLeveledLogger Expected no entries, got [%s]\n", got)
+	}
+	// A log.Logger
+	ll := log.New(ioutil.Discard, "", 0)
+	conn.SetLogger(ll)
+	if conn.GetLogger() != ll || conn.GetLeveledLogger() == nil {
+		t.Fatalf("TestLeveledLogger Expected the log.Logger\n")
+	}
+	conn.SetLeveledLogger(nil)
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+}
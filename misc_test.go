@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -186,7 +187,7 @@ func TestMiscNilHeaders(t *testing.T) {
 			t.Fatalf("TestMiscNilHeaders Expected [%v], got [nil]\n",
 				EHDRNIL)
 		}
-		if e != EHDRNIL {
+		if !errors.Is(e, EHDRNIL) {
 			t.Fatalf("TestMiscNilHeaders Expected [%v], got [%v]\n",
 				EHDRNIL, e)
 		}
@@ -311,7 +312,7 @@ func TestMiscBadHeaders(t *testing.T) {
 		if e == nil {
 			t.Fatalf("TestMiscBadHeaders Expected [%v], got [nil]\n", EHDRLEN)
 		}
-		if e != EHDRLEN {
+		if !errors.Is(e, EHDRLEN) {
 			t.Fatalf("TestMiscBadHeaders Expected [%v], got [%v]\n", EHDRLEN, e)
 		}
 		//
@@ -320,7 +321,7 @@ func TestMiscBadHeaders(t *testing.T) {
 		if e == nil {
 			t.Fatalf("TestMiscBadHeaders Expected [%v], got [nil]\n", EBADVERCLI)
 		}
-		if e != EBADVERCLI {
+		if !errors.Is(e, EBADVERCLI) {
 			t.Fatalf("TestMiscBadHeaders Expected [%v], got [%v]\n", EBADVERCLI, e)
 		}
 
@@ -339,7 +340,7 @@ func TestMiscBadHeaders(t *testing.T) {
 		if e == nil {
 			t.Fatalf("TestMiscBadHeaders Expected [%v], got [nil]\n", EHDRLEN)
 		}
-		if e != EHDRLEN {
+		if !errors.Is(e, EHDRLEN) {
 			t.Fatalf("TestMiscBadHeaders Expected [%v], got [%v]\n", EHDRLEN, e)
 		}
 		//
@@ -347,7 +348,7 @@ func TestMiscBadHeaders(t *testing.T) {
 		if e == nil {
 			t.Fatalf("TestMiscBadHeaders Expected [%v], got [nil]\n", EHDRLEN)
 		}
-		if e != EHDRLEN {
+		if !errors.Is(e, EHDRLEN) {
 			t.Fatalf("TestMiscBadHeaders Expected [%v], got [%v]\n", EHDRLEN, e)
 		}
 		//
@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -47,14 +48,24 @@ var _ = fmt.Println
 
 */
 func (c *Connection) Nack(h Headers) error {
-	c.log(NACK, "start", h, c.Protocol())
+	return c.NackContext(context.Background(), h)
+}
+
+/*
+	NackContext is Nack, giving up when ctx is done.  See ContextStomper.
+*/
+func (c *Connection) NackContext(ctx context.Context, h Headers) (e error) {
+	defer func() { e = newOpError("Nack", NACK, h, e) }()
+	if c.logs(LogDebug) {
+		c.debug("NACK start", "headers", h)
+	}
 	if !c.isConnected() {
 		return ECONBAD
 	}
 	if c.Protocol() == SPL_10 {
 		return EBADVERNAK
 	}
-	e := checkHeaders(h, c.Protocol())
+	e = checkHeaders(h, c.Protocol())
 	if e != nil {
 		return e
 	}
@@ -73,7 +84,9 @@ func (c *Connection) Nack(h Headers) error {
 		}
 	}
 
-	e = c.transmitCommon(NACK, h) // transmitCommon Clones() the headers
-	c.log(NACK, "end", h, c.Protocol())
+	e = c.transmitCommonContext(ctx, NACK, h)
+	if c.logs(LogDebug) {
+		c.debug("NACK end", "headers", h)
+	}
 	return e
 }
@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -37,7 +38,7 @@ func TestNackErrors(t *testing.T) {
 	for ti, tv := range nackList {
 		conn.protocol = tv.proto // Fake it
 		e = conn.Nack(tv.headers)
-		if e != tv.errval {
+		if !errors.Is(e, tv.errval) {
 			t.Fatalf("TestNackErrors[%d] NACK -%s- expected error [%v], got [%v]\n",
 				ti, tv.proto, tv.errval, e)
 		}
@@ -0,0 +1,134 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+/*
+	OpError is the error returned by Connection operations, and sent on
+	MessageData for read errors.  It wraps the underlying error, which may be
+	an Error constant, a *BrokerError, a *ProtocolError, a *FrameLimitError or
+	a network error.
+
+	Match with errors.Is and errors.As, not ==:
+		e := c.Send(h, m)
+		if errors.Is(e, stompngo.ECONBAD) {
+			// Reconnect ...
+		}
+		var oe *stompngo.OpError
+		if errors.As(e, &oe) {
+			log.Println(oe.Op, oe.Command, oe.Headers)
+		}
+*/
+type OpError struct {
+	Op      string  // The operation, e.g. "Send", or "read"
+	Command string  // The frame command, if any
+	Headers Headers // The frame headers, with SecretHeaders values redacted
+	Err     error   // The underlying error
+}
+
+/*
+	SecretHeaders are the header keys whose values never appear in an
+	OpError.  Keys match without regard to case.  Add application keys
+	before connecting, the list is not safe to change while in use.
+*/
+var SecretHeaders = []string{HK_LOGIN, HK_PASSCODE, "authorization",
+	"proxy-authorization", "token", "access-token", "auth-token", "api-key",
+	"x-api-key", "password", "secret"}
+
+const redacted = "<redacted>"
+
+func (e *OpError) Error() string {
+	s := "stompngo " + e.Op
+	if e.Command != "" {
+		s += " " + e.Command
+	}
+	return s + ": " + e.Err.Error()
+}
+
+/*
+	Unwrap returns the underlying error.
+*/
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+/*
+	Timeout reports whether the underlying error is a network timeout.
+*/
+func (e *OpError) Timeout() bool {
+	ne, ok := e.Err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+/*
+	True if e ends the connection: a read error, or ECONBAD.  Anything else
+	on a subscription channel is about one message.
+*/
+func isReadError(e error) bool {
+	var oe *OpError
+	if errors.As(e, &oe) && oe.Op == "read" {
+		return true
+	}
+	return errors.Is(e, ECONBAD)
+}
+
+/*
+	Wrap a non-nil error for op.  An error that is already an *OpError is
+	returned as is.
+*/
+func newOpError(op, cmd string, h Headers, e error) error {
+	if e == nil {
+		return nil
+	}
+	if _, ok := e.(*OpError); ok {
+		return e
+	}
+	return &OpError{Op: op, Command: cmd, Headers: redactHeaders(h), Err: e}
+}
+
+/*
+	A copy of h with secret header values replaced.
+*/
+func redactHeaders(h Headers) Headers {
+	if h == nil {
+		return nil
+	}
+	r := h.Clone()
+	for i := 0; i+1 < len(r); i += 2 {
+		if secretHeader(r[i]) {
+			r[i+1] = redacted
+		}
+	}
+	return r
+}
+
+/*
+	True if k is one of the SecretHeaders.
+*/
+func secretHeader(k string) bool {
+	for _, s := range SecretHeaders {
+		if strings.EqualFold(k, s) {
+			return true
+		}
+	}
+	return false
+}
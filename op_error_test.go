@@ -0,0 +1,111 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test that a Connect error is an OpError, with the login and passcode
+	redacted.
+*/
+func TestOpErrorConnect(t *testing.T) {
+	n, _ = openConn(t)
+	ch := login_headers.Add(HK_ACCEPT_VERSION, "3.14159").Add(HK_HOST, "localhost")
+	_, e = Connect(n, ch)
+	var oe *OpError
+	if !errors.Is(e, EBADVERCLI) || !errors.As(e, &oe) {
+		t.Fatalf("TestOpErrorConnect Expected OpError for [%v], got [%v]\n",
+			EBADVERCLI, e)
+	}
+	if oe.Op != "Connect" || oe.Command != CONNECT || oe.Err != EBADVERCLI {
+		t.Fatalf("TestOpErrorConnect Unexpected OpError [%#v]\n", oe)
+	}
+	if oe.Headers.Value(HK_PASSCODE) != redacted ||
+		oe.Headers.Value(HK_LOGIN) != redacted ||
+		oe.Headers.Value(HK_HOST) != "localhost" {
+		t.Fatalf("TestOpErrorConnect Expected login and passcode redacted, got [%v]\n",
+			oe.Headers)
+	}
+	if ch.Value(HK_PASSCODE) == redacted {
+		t.Fatalf("TestOpErrorConnect Caller headers changed\n")
+	}
+	_ = closeConn(t, n)
+}
+
+/*
+	Test that a read error reaches subscribers as an OpError.
+*/
+func TestOpErrorRead(t *testing.T) {
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if f.Command == SUBSCRIBE {
+				s.Close()
+				return true
+			}
+			return false
+		}})
+	defer b.Close()
+	n := b.Pipe()
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestOpErrorRead CONNECT Failed: e:<%q>\n", e)
+	}
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, "/queue/op.error",
+		HK_ID, "oeid"})
+	if e != nil {
+		t.Fatalf("TestOpErrorRead Expected no subscribe error, got [%v]\n", e)
+	}
+	md := <-sc
+	var oe *OpError
+	if !errors.As(md.Error, &oe) || oe.Op != "read" || !errors.Is(md.Error, io.EOF) {
+		t.Fatalf("TestOpErrorRead Expected read OpError, got [%v]\n", md.Error)
+	}
+	<-conn.rdrdc
+	e = conn.Send(Headers{HK_DESTINATION, "/queue/op.error"}, "late")
+	if !errors.Is(e, ECONBAD) || !errors.As(e, &oe) || oe.Op != "Send" ||
+		oe.Command != SEND {
+		t.Fatalf("TestOpErrorRead Expected Send OpError for [%v], got [%v]\n",
+			ECONBAD, e)
+	}
+	_ = closeConn(t, n)
+}
+
+/*
+	Test secret header matching, and adding to SecretHeaders.
+*/
+func TestOpErrorRedact(t *testing.T) {
+	defer func(s []string) { SecretHeaders = s }(SecretHeaders)
+	SecretHeaders = append(SecretHeaders[:len(SecretHeaders):len(SecretHeaders)],
+		"x-app-key")
+	h := Headers{"Authorization", "Bearer abc", "token", "t1", "X-App-Key", "k1",
+		HK_DESTINATION, "/queue/a"}
+	r := redactHeaders(h)
+	want := Headers{"Authorization", redacted, "token", redacted, "X-App-Key",
+		redacted, HK_DESTINATION, "/queue/a"}
+	if !r.Compare(want) {
+		t.Fatalf("TestOpErrorRedact Expected [%v], got [%v]\n", want, r)
+	}
+	if h.Value("token") != "t1" {
+		t.Fatalf("TestOpErrorRedact Caller headers changed\n")
+	}
+}
@@ -0,0 +1,168 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+)
+
+/*
+	PreparedFrame is a SEND frame with its headers checked, escaped and
+	serialized once.  It is for many sends with the same headers.  Only
+	content-length, when not supplied or suppressed, is written per send.
+
+	A PreparedFrame is never changed, and can be used by many goroutines.  It
+	is valid for connections at the protocol level it was prepared for.
+*/
+type PreparedFrame struct {
+	h     Headers // As given, for logging and errors
+	proto string  // Protocol level
+	hdr   []byte  // Command and header lines
+	acl   bool    // Add content-length
+	sclok bool    // Body ends at the first NUL
+}
+
+/*
+	PrepareSend prepares a SEND frame for SendPrepared.  The headers are
+	checked as Send checks them, and copied.
+
+	Example:
+		h := stompngo.Headers{stompngo.HK_DESTINATION, "/queue/mymessages"}
+		p, e := c.PrepareSend(h)
+		if e != nil {
+			// Do something sane ...
+		}
+		for _, m := range messages {
+			if e = c.SendPrepared(p, m); e != nil {
+				// Do something sane ...
+			}
+		}
+*/
+func (c *Connection) PrepareSend(h Headers) (*PreparedFrame, error) {
+	p, e := c.prepareSend(h)
+	return p, newOpError("PrepareSend", SEND, h, e)
+}
+
+func (c *Connection) prepareSend(h Headers) (*PreparedFrame, error) {
+	pl := c.Protocol()
+	if e := checkHeaders(h, pl); e != nil {
+		return nil, e
+	}
+	if _, ok := h.Contains(HK_DESTINATION); !ok {
+		return nil, EREQDSTSND
+	}
+	p := &PreparedFrame{h: h.Clone(), proto: pl}
+	_, sctok := h.Contains(HK_SUPPRESS_CT)
+	_, ctok := h.Contains(HK_CONTENT_TYPE)
+	_, p.sclok = h.Contains(HK_SUPPRESS_CL)
+	_, clok := h.Contains(HK_CONTENT_LENGTH)
+	p.acl = !p.sclok && !clok
+	//
+	var b bytes.Buffer
+	b.WriteString(SEND + "\n")
+	for i := 0; i < len(h); i += 2 {
+		k, v := h[i], h[i+1]
+		if pl > SPL_10 {
+			k, v = encode(k), encode(v)
+		}
+		b.WriteString(k + ":" + v + "\n")
+	}
+	if !sctok && !ctok {
+		b.WriteString(HK_CONTENT_TYPE + ":" + DFLT_CONTENT_TYPE + "\n")
+	}
+	p.hdr = b.Bytes()
+	return p, nil
+}
+
+/*
+	SendPrepared sends a STOMP MESSAGE with prepared headers.  The body is a
+	slice of bytes, which may be empty.  A PreparedFrame for another protocol
+	level gives EPREPPROTO.
+*/
+func (c *Connection) SendPrepared(p *PreparedFrame, b []byte) error {
+	return c.SendPreparedContext(context.Background(), p, b)
+}
+
+/*
+	SendPreparedContext is SendPrepared, giving up when ctx is done.  See
+	ContextStomper.
+*/
+func (c *Connection) SendPreparedContext(ctx context.Context, p *PreparedFrame, b []byte) (e error) {
+	defer func() { e = newOpError("SendPrepared", SEND, p.h, e) }()
+	if c.logs(LogDebug) {
+		c.debug("SEND prepared start", "headers", p.h)
+	}
+	if !c.isConnected() {
+		return ECONBAD
+	}
+	if p.proto != c.Protocol() {
+		return EPREPPROTO
+	}
+	if c.cry.active() {
+		return ECRYPTBODY // The body is sent as is
+	}
+	r, e := c.queueWireData(ctx, wiredata{frame: Frame{SEND, p.h, b}, pf: p})
+	if e != nil {
+		return e
+	}
+	e = c.awaitWrite(ctx, r)
+	if c.logs(LogDebug) {
+		c.debug("SEND prepared end", "headers", p.h)
+	}
+	return e // nil or not
+}
+
+/*
+	Physical write of a prepared frame with a body.  Returns the number of
+	bytes written.
+*/
+func (p *PreparedFrame) writeFrame(w *bufio.Writer, c *Connection, b []byte) (int64, error) {
+	if p.sclok {
+		if nz := bytes.IndexByte(b, 0); nz >= 0 {
+			b = b[0:nz]
+		}
+	}
+	if c.dld.wde && c.dld.wds {
+		_ = c.netconn.SetWriteDeadline(time.Now().Add(c.dld.wdld))
+	}
+	var e error
+	if c.eltd != nil {
+		st := time.Now().UnixNano()
+		_, e = w.Write(p.hdr)
+		c.eltd.wivh.ens += time.Now().UnixNano() - st
+		c.eltd.wivh.ec++
+	} else {
+		_, e = w.Write(p.hdr)
+	}
+	if c.checkWriteError(e) != nil {
+		return 0, e
+	}
+	n := int64(len(p.hdr))
+	if p.acl {
+		l := strconv.Itoa(len(b))
+		if e = c.writeHeader(w, HK_CONTENT_LENGTH, l); e != nil {
+			return n, e
+		}
+		n += int64(len(HK_CONTENT_LENGTH)+len(l)) + 2
+	}
+	m, e := c.writeFrameEnd(w, b, nil, 0)
+	return n + m, e
+}
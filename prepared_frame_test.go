@@ -0,0 +1,102 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test PrepareSend and SendPrepared.
+*/
+func TestPreparedFrame(t *testing.T) {
+	b := fakebroker.New(nil)
+	defer b.Close()
+	conn, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestPreparedFrame CONNECT Failed: e:<%q>\n", e)
+	}
+	d := tdest("/queue/prepared.frame")
+	if _, e = conn.PrepareSend(Headers{"k", "v"}); !errors.Is(e, EREQDSTSND) {
+		t.Fatalf("TestPreparedFrame Expected [%v], got [%v]\n", EREQDSTSND, e)
+	}
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "pfid"})
+	if e != nil {
+		t.Fatalf("TestPreparedFrame Expected no subscribe error, got [%v]\n", e)
+	}
+	h := Headers{HK_DESTINATION, d, "k", "a:b\nc\\d"}
+	p, e := conn.PrepareSend(h)
+	if e != nil {
+		t.Fatalf("TestPreparedFrame Expected no prepare error, got [%v]\n", e)
+	}
+	h[3] = "changed" // The PreparedFrame has its own copy
+	for i := 0; i < 3; i++ {
+		if e = conn.SendPrepared(p, []byte(fmt.Sprintf("body\x00%d", i))); e != nil {
+			t.Fatalf("TestPreparedFrame Expected no send error, got [%v]\n", e)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		md := <-sc
+		want := fmt.Sprintf("body\x00%d", i)
+		if md.Message.BodyString() != want ||
+			md.Message.Headers.Value("k") != "a:b\nc\\d" ||
+			md.Message.Headers.Value(HK_CONTENT_TYPE) != DFLT_CONTENT_TYPE {
+			t.Fatalf("TestPreparedFrame Expected [%q], got [%q] [%q]\n", want,
+				md.Message.Body, md.Message.Headers)
+		}
+	}
+	// Another protocol level
+	c11, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_11))
+	if e != nil {
+		t.Fatalf("TestPreparedFrame CONNECT Failed: e:<%q>\n", e)
+	}
+	if e = c11.SendPrepared(p, nil); !errors.Is(e, EPREPPROTO) {
+		t.Fatalf("TestPreparedFrame Expected [%v], got [%v]\n", EPREPPROTO, e)
+	}
+	checkDisconnectError(t, c11.Disconnect(empty_headers))
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+}
+
+/*
+	Test that sends do not change caller Headers, including spare capacity.
+*/
+func TestSendHeadersUnchanged(t *testing.T) {
+	b := fakebroker.New(nil)
+	defer b.Close()
+	conn, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestSendHeadersUnchanged CONNECT Failed: e:<%q>\n", e)
+	}
+	h := make(Headers, 4, 8)
+	copy(h, Headers{HK_DESTINATION, tdest("/queue/headers.unchanged"), "k:x",
+		"a\nb"})
+	want := h[:cap(h)].Clone()
+	if e = conn.Send(h, "body"); e != nil {
+		t.Fatalf("TestSendHeadersUnchanged Expected no send error, got [%v]\n", e)
+	}
+	if e = <-conn.SendBytesAsync(h, []byte("body")); e != nil {
+		t.Fatalf("TestSendHeadersUnchanged Expected no send error, got [%v]\n", e)
+	}
+	if got := h[:cap(h)]; !got.Compare(want) {
+		t.Fatalf("TestSendHeadersUnchanged Expected [%q], got [%q]\n", want, got)
+	}
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+}
@@ -0,0 +1,52 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+/*
+	ProtocolError reports a frame, or a connection state, that breaks the STOMP
+	protocol.  It matches EPROTOVIOL with errors.Is.
+
+	A ProtocolError from the broker ends the connection.  It is sent to every
+	subscription channel and to Connection.MessageData, like a read error.
+*/
+type ProtocolError struct {
+	Reason  string  // What is wrong
+	Command string  // The frame command, if any
+	Headers Headers // The frame headers, if any
+}
+
+func (e *ProtocolError) Error() string {
+	if e.Command == "" {
+		return string(EPROTOVIOL) + ": " + e.Reason
+	}
+	return string(EPROTOVIOL) + ": " + e.Reason + ", command:<" + e.Command + ">"
+}
+
+/*
+	Is reports whether target is EPROTOVIOL.
+*/
+func (e *ProtocolError) Is(target error) bool {
+	return target == EPROTOVIOL
+}
+
+/*
+	The error for a connection protocol level that is not supported.
+*/
+func (c *Connection) protocolLevelError(cmd string) error {
+	return &ProtocolError{Reason: "unsupported protocol level " + c.Protocol(),
+		Command: cmd}
+}
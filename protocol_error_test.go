@@ -0,0 +1,97 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test that a MESSAGE without a subscription header ends the connection
+	with a ProtocolError, and does not panic.
+*/
+func TestProtocolErrorMessage(t *testing.T) {
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if f.Command == SUBSCRIBE {
+				s.WriteRaw([]byte("MESSAGE\ndestination:/queue/proto.error\n" +
+					"message-id:1\n\nno subscription\x00"))
+				return true
+			}
+			return false
+		}})
+	defer b.Close()
+	n := b.Pipe()
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestProtocolErrorMessage CONNECT Failed: e:<%q>\n", e)
+	}
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, "/queue/proto.error",
+		HK_ID, "peid"})
+	if e != nil {
+		t.Fatalf("TestProtocolErrorMessage Expected no subscribe error, got [%v]\n", e)
+	}
+	var pe *ProtocolError
+	for _, md := range []MessageData{<-sc, <-conn.MessageData} {
+		if !errors.Is(md.Error, EPROTOVIOL) || !errors.As(md.Error, &pe) ||
+			pe.Command != MESSAGE {
+			t.Fatalf("TestProtocolErrorMessage Expected ProtocolError, got [%v]\n",
+				md.Error)
+		}
+	}
+	<-conn.rdrdc
+	if conn.Connected() {
+		t.Fatalf("TestProtocolErrorMessage Expected connection end\n")
+	}
+	_ = closeConn(t, n)
+}
+
+/*
+	Test that an unsupported protocol level is an error, and not a process
+	exit.
+*/
+func TestProtocolErrorLevel(t *testing.T) {
+	n, _ = openConn(t)
+	conn, e = Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestProtocolErrorLevel CONNECT Failed: e:<%q>\n", e)
+	}
+	conn.protoLock.Lock()
+	conn.protocol = "9.9"
+	conn.protoLock.Unlock()
+	_, e = conn.Subscribe(Headers{HK_DESTINATION, "/queue/proto.level"})
+	if !errors.Is(e, EPROTOVIOL) {
+		t.Fatalf("TestProtocolErrorLevel Expected subscribe [%v], got [%v]\n",
+			EPROTOVIOL, e)
+	}
+	e = conn.Unsubscribe(Headers{HK_DESTINATION, "/queue/proto.level",
+		HK_ID, "plid"})
+	if !errors.Is(e, EPROTOVIOL) {
+		t.Fatalf("TestProtocolErrorLevel Expected unsubscribe [%v], got [%v]\n",
+			EPROTOVIOL, e)
+	}
+	conn.protoLock.Lock()
+	conn.protocol = SPL_12
+	conn.protoLock.Unlock()
+	//
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	_ = closeConn(t, n)
+}
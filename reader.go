@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net"
@@ -47,24 +48,33 @@ readLoop:
 			f, e = c.readFrame()
 		}
 		//
-		logLock.Lock()
-		if c.logger != nil {
-			c.logx("RDR_RECEIVE_FRAME", f.Command, f.Headers, HexData(f.Body),
-				"RDR_RECEIVE_ERR", e)
+		if c.logs(LogDebug) {
+			c.debug("RDR_RECEIVE_FRAME", "command", f.Command, "headers", f.Headers,
+				"body", HexData(f.Body), "error", e)
 		}
-		logLock.Unlock()
 		if e != nil {
 			//debug.PrintStack()
 			f.Headers = append(f.Headers, "connection_read_error", e.Error())
-			md := MessageData{Message(f), e}
+			md := MessageData{Message: Message(f), Error: e}
 			c.handleReadError(md)
 			if e == io.EOF && !c.isConnected() {
-				c.log("RDR_SHUTDOWN_EOF", e)
+				c.debug("RDR_SHUTDOWN_EOF", "error", e)
 			} else {
-				c.log("RDR_CONN_GENL_ERR", e)
+				c.error("RDR_CONN_GENL_ERR", "error", e)
 			}
 			break readLoop
 		}
+		// Interceptors, a veto is delivered with the frame
+		ie := c.interceptIn(&f)
+		if ie == ESWALLOW {
+			if e = c.dropFrame(f); e == nil {
+				continue readLoop
+			}
+			f.Headers = append(f.Headers, "connection_read_error", e.Error())
+			c.handleReadError(MessageData{Message: Message(f), Error: e})
+			c.error("RDR_SWALLOW_ERR", "error", e)
+			break readLoop
+		}
 
 		if f.Command == "" {
 			continue readLoop
@@ -77,75 +87,123 @@ readLoop:
 
 		//*************************************************************************
 		// Replacement START
-		md := MessageData{m, e}
+		md := MessageData{Message: m, Error: ie}
 		switch f.Command {
 		//
 		case MESSAGE:
+			bs, delivered := c.rbs, false // A streamed body, see StompPlusStreamBody
+			c.rbs = nil
+			if bs != nil {
+				md.BodyReader = bs
+			}
 			sid, ok := f.Headers.Contains(HK_SUBSCRIPTION)
 			if !ok { // This should *NEVER* happen
-				panic(fmt.Sprintf("stompngo INTERNAL ERROR: command:<%s> headers:<%v>",
-					f.Command, f.Headers))
+				e = &ProtocolError{Reason: "no subscription header",
+					Command: f.Command, Headers: f.Headers}
+				break
 			}
 			c.subsLock.RLock()
 			ps, sok := c.subs[sid] // This is a map of pointers .....
+			c.subsLock.RUnlock()
+			if sok {
+				// Verify, decrypt and decompress without holding the lock
+				c.openMessage(&md, ps)
+			}
+			c.subsLock.RLock()
+			if sok && c.subs[sid] != ps {
+				sok = false // Unsubscribed meanwhile
+			}
 			//
 			if !sok {
 				// The sub can be gone under some timing conditions.  In that case
 				// we log it of possible, and continue (hope for the best).
-				c.log("RDR_NOSUB", sid, m.Command, m.Headers)
+				c.warn("RDR_NOSUB", "subscription", sid, "command", m.Command,
+					"headers", m.Headers)
 				goto csRUnlock
 			}
 			if ps.cs {
 				// The sub can also already be closed under some conditions.
 				// Again, we log that if possible, and continue
-				c.log("RDR_CLSUB", sid, m.Command, m.Headers)
+				if c.logs(LogDebug) {
+					c.debug("RDR_CLSUB", "subscription", sid, "command", m.Command,
+						"headers", m.Headers)
+				}
 				goto csRUnlock
 			}
 			// Handle subscription draining
 			switch ps.drav {
 			case false:
 				ps.md <- md
+				delivered = true
 			default:
 				ps.drmc++
 				if ps.drmc > ps.dra {
-					logLock.Lock()
-					if c.logger != nil {
-						c.logx("RDR_DROPM", ps.drmc, sid, m.Command,
-							m.Headers, HexData(m.Body))
+					if c.logs(LogDebug) {
+						c.debug("RDR_DROPM", "count", ps.drmc, "subscription", sid,
+							"command", m.Command, "headers", m.Headers,
+							"body", HexData(m.Body))
 					}
-					logLock.Unlock()
 				} else {
 					ps.md <- md
+					delivered = true
 				}
 			}
 		csRUnlock:
 			c.subsLock.RUnlock()
+			if !delivered {
+				md.Release() // Dropped, the pooled body can be reused now
+			}
+			if bs != nil {
+				// Nothing more can be read until the body is
+				if !delivered {
+					_ = bs.Close()
+				}
+				e = bs.wait()
+			}
 		//
 		case ERROR:
-			fallthrough
+			c.brokerError(m, ie)
 		//
 		case RECEIPT:
-			c.input <- md
+			if !c.completeReceipt(md) {
+				c.input <- md
+			}
 		//
 		default:
-			panic(fmt.Sprintf("Broker SEVERE ERROR, not STOMP? command:<%s> headers:<%v>",
-				f.Command, f.Headers))
+			e = &ProtocolError{Reason: "unexpected command", Command: f.Command,
+				Headers: f.Headers}
 		}
 		// Replacement END
 		//*************************************************************************
+		if e != nil {
+			// A broker protocol violation, or a streamed body read error: end the
+			// connection as for a read error
+			f.Headers = append(f.Headers, "connection_read_error", e.Error())
+			c.handleReadError(MessageData{Message: Message(f), Error: e})
+			c.error("RDR_PROTOCOL_ERR", "error", e)
+			break readLoop
+		}
 
 		select {
 		case _ = <-c.ssdc:
-			c.log("RDR_SHUTDOWN detected")
+			c.debug("RDR_SHUTDOWN detected")
 			break readLoop
 		default:
 		}
-		c.log("RDR_RELOOP")
+		c.debug("RDR_RELOOP")
+	}
+	if e == nil {
+		e = ECONBAD
 	}
+	c.failReceipts(e)
 	close(c.input)
+	close(c.rdrdc)
 	c.setConnected(false)
 	c.sysAbort()
-	c.log("RDR_SHUTDOWN", time.Now())
+	if c.ownsConn {
+		_ = c.netconn.Close()
+	}
+	c.debug("RDR_SHUTDOWN")
 }
 
 /*
@@ -158,58 +216,73 @@ readLoop:
 	if running against a non-compliant STOMP server.
 */
 func (c *Connection) readFrame() (f Frame, e error) {
-	var s string
+	f, e = c.readFrameFor(validCmds)
+	if e == EINVBCMD {
+		return f, fmt.Errorf("%w\n%s", EINVBCMD, HexData([]byte(f.Command)))
+	}
+	if e != nil || f.Command == "" {
+		return f, e
+	}
+	return f, checkHeaders(f.Headers, c.Protocol())
+}
+
+/*
+	Parse a single frame with one of the given commands.  A heart-beat gives
+	a frame with an empty command.  Lines may end with LF or CRLF.  Any other
+	command gives EINVBCMD.
+
+	Lines are parsed in place in the read buffer, in one pass.  Only the
+	command, the decoded header keys and values, and the body are allocated.
+*/
+func (c *Connection) readFrameFor(cmds map[string]bool) (f Frame, e error) {
 	var bx []byte
-	f = Frame{"", Headers{}, NULLBUFF}
+	f = Frame{"", nil, NULLBUFF}
 
 	// Read f.Command or line ends (maybe heartbeats)
 	c.setReadDeadline()
 
 	if c.eltd != nil {
 		st := time.Now().UnixNano()
-		// s, e = c.rdr.ReadString('\n')
-		bx, e = c.rdr.ReadBytes('\n')
-		s = string(bx)
+		bx, e = readLimitedLine(c.rdr, c.flim.MaxHeaderLine)
 		c.eltd.rcmd.ens += time.Now().UnixNano() - st
 		c.eltd.rcmd.ec++
-		// fmt.Println("DERCMD", s)
 	} else {
-		// s, e = c.rdr.ReadString('\n')
-		bx, e = c.rdr.ReadBytes('\n')
-		s = string(bx)
+		bx, e = readLimitedLine(c.rdr, c.flim.MaxHeaderLine)
 	}
 
 	if c.checkReadError(e) != nil {
 		return f, e
 	}
-	if s == "" {
+	if len(bx) == 0 {
 		return f, e
 	}
 	if c.hbd != nil {
 		c.updateHBReads()
 	}
-	f.Command = s[0 : len(s)-1]
-	if s == "\n" {
+	bx = trimEOL(bx)
+	if len(bx) == 0 {
 		return f, e
 	}
-	// fmt.Println("DERCMD2", f.Command)
+	f.Command = commandString(bx)
 	// Validate the command
-	if _, ok := validCmds[f.Command]; !ok {
-		ev := fmt.Errorf("%s\n%s", EINVBCMD, HexData([]byte(f.Command)))
-		return f, ev
+	if _, ok := cmds[f.Command]; !ok {
+		return f, EINVBCMD
 	}
 	// Read f.Headers
+	f.Headers = make(Headers, 0, 24) // 12 headers before any growth
+	hb := 0 // Header bytes
+	strict := escapedCommand(f.Command) && c.Protocol() == SPL_12
 	for {
 		c.setReadDeadline()
 
 		if c.eltd != nil {
 			st := time.Now().UnixNano()
-			s, e = c.rdr.ReadString('\n')
+			bx, e = readLimitedLine(c.rdr, c.flim.MaxHeaderLine)
 			c.eltd.rivh.ens += time.Now().UnixNano() - st
 			c.eltd.rivh.ec++
 
 		} else {
-			s, e = c.rdr.ReadString('\n')
+			bx, e = readLimitedLine(c.rdr, c.flim.MaxHeaderLine)
 		}
 
 		if c.checkReadError(e) != nil {
@@ -218,38 +291,60 @@ func (c *Connection) readFrame() (f Frame, e error) {
 		if c.hbd != nil {
 			c.updateHBReads()
 		}
-		if s == "\n" {
+		bx = trimEOL(bx)
+		if len(bx) == 0 {
 			break
 		}
-		s = s[0 : len(s)-1]
-		p := strings.SplitN(s, ":", 2)
-		if len(p) != 2 {
+		if len(f.Headers)/2 >= c.flim.MaxHeaders {
+			return f, &FrameLimitError{"MaxHeaders", c.flim.MaxHeaders}
+		}
+		if hb += len(bx); hb > c.flim.MaxHeaderBytes {
+			return f, &FrameLimitError{"MaxHeaderBytes", c.flim.MaxHeaderBytes}
+		}
+		i := bytes.IndexByte(bx, ':')
+		if i < 0 {
 			return f, EUNKHDR
 		}
 		// Always decode regardless of protocol level. See issue #47.
-		p[0] = decode(p[0])
-		p[1] = decode(p[1])
-		f.Headers = append(f.Headers, p[0], p[1])
-	}
-	//
-	e = checkHeaders(f.Headers, c.Protocol())
-	if e != nil {
-		return f, e
+		// Undefined escapes are fatal for 1.2, in escaped frames.
+		k, e := unescapeBytes(bx[:i], strict, true)
+		if e != nil {
+			return f, e
+		}
+		v, e := unescapeBytes(bx[i+1:], strict, false)
+		if e != nil {
+			return f, e
+		}
+		// Repeated keys are all kept.  Value and Contains use the first one.
+		f.Headers = append(f.Headers, k, v)
 	}
 	// Read f.Body
+	pool := c.pbodies && f.Command == MESSAGE
 	if v, ok := f.Headers.Contains(HK_CONTENT_LENGTH); ok {
 		l, e := strconv.Atoi(strings.TrimSpace(v))
 		if e != nil {
 			return f, e
 		}
+		if l < 0 {
+			return f, EBADFRM
+		}
+		if f.Command == MESSAGE {
+			// A streamed body is never held, so MaxBody does not apply
+			if c.rbs = c.streamFor(f.Headers, l); c.rbs != nil {
+				return f, nil
+			}
+		}
+		if l > c.flim.MaxBody {
+			return f, &FrameLimitError{"MaxBody", c.flim.MaxBody}
+		}
 		if l == 0 {
-			f.Body, e = readUntilNul(c)
+			f.Body, e = readUntilNul(c, pool)
 		} else {
-			f.Body, e = readBody(c, l)
+			f.Body, e = readBody(c, l, pool)
 		}
 	} else {
 		// content-length not present
-		f.Body, e = readUntilNul(c)
+		f.Body, e = readUntilNul(c, pool)
 	}
 	if c.checkReadError(e) != nil {
 		return f, e
@@ -288,7 +383,7 @@ func (c *Connection) checkReadError(e error) error {
 	if ne.Timeout() {
 		//c.log("is a timeout")
 		if c.dld.dns {
-			c.log("invoking read deadline callback")
+			c.debug("invoking read deadline callback")
 			c.dld.dlnotify(e, false)
 		}
 	}
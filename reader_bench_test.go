@@ -0,0 +1,320 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+	The string based frame parser this package used before the byte level
+	one, kept as the benchmark baseline.
+*/
+func (c *Connection) legacyReadFrameFor(cmds map[string]bool) (f Frame, e error) {
+	var s string
+	var bx []byte
+	f = Frame{"", Headers{}, NULLBUFF}
+
+	// Read f.Command or line ends (maybe heartbeats)
+	c.setReadDeadline()
+
+	if c.eltd != nil {
+		st := time.Now().UnixNano()
+		// s, e = c.rdr.ReadString('\n')
+		bx, e = readLimitedLine(c.rdr, c.flim.MaxHeaderLine)
+		s = string(bx)
+		c.eltd.rcmd.ens += time.Now().UnixNano() - st
+		c.eltd.rcmd.ec++
+		// fmt.Println("DERCMD", s)
+	} else {
+		// s, e = c.rdr.ReadString('\n')
+		bx, e = readLimitedLine(c.rdr, c.flim.MaxHeaderLine)
+		s = string(bx)
+	}
+
+	if c.checkReadError(e) != nil {
+		return f, e
+	}
+	if s == "" {
+		return f, e
+	}
+	if c.hbd != nil {
+		c.updateHBReads()
+	}
+	f.Command = strings.TrimSuffix(s[0:len(s)-1], "\r")
+	if f.Command == "" {
+		return f, e
+	}
+	// fmt.Println("DERCMD2", f.Command)
+	// Validate the command
+	if _, ok := cmds[f.Command]; !ok {
+		return f, EINVBCMD
+	}
+	// Read f.Headers
+	hb := 0 // Header bytes
+	strict := f.Command != CONNECTED && c.Protocol() == SPL_12
+	for {
+		c.setReadDeadline()
+
+		if c.eltd != nil {
+			st := time.Now().UnixNano()
+			bx, e = readLimitedLine(c.rdr, c.flim.MaxHeaderLine)
+			c.eltd.rivh.ens += time.Now().UnixNano() - st
+			c.eltd.rivh.ec++
+
+		} else {
+			bx, e = readLimitedLine(c.rdr, c.flim.MaxHeaderLine)
+		}
+		s = string(bx)
+
+		if c.checkReadError(e) != nil {
+			return f, e
+		}
+		if c.hbd != nil {
+			c.updateHBReads()
+		}
+		s = strings.TrimSuffix(s[0:len(s)-1], "\r")
+		if s == "" {
+			break
+		}
+		if len(f.Headers)/2 >= c.flim.MaxHeaders {
+			return f, &FrameLimitError{"MaxHeaders", c.flim.MaxHeaders}
+		}
+		if hb += len(s); hb > c.flim.MaxHeaderBytes {
+			return f, &FrameLimitError{"MaxHeaderBytes", c.flim.MaxHeaderBytes}
+		}
+		p := strings.SplitN(s, ":", 2)
+		if len(p) != 2 {
+			return f, EUNKHDR
+		}
+		// Always decode regardless of protocol level. See issue #47.
+		// Undefined escapes are fatal for 1.2.  CONNECTED is never escaped.
+		if p[0], e = unescape(p[0], strict); e != nil {
+			return f, e
+		}
+		if p[1], e = unescape(p[1], strict); e != nil {
+			return f, e
+		}
+		// Repeated keys are all kept.  Value and Contains use the first one.
+		f.Headers = append(f.Headers, p[0], p[1])
+	}
+	// Read f.Body
+	if v, ok := f.Headers.Contains(HK_CONTENT_LENGTH); ok {
+		l, e := strconv.Atoi(strings.TrimSpace(v))
+		if e != nil {
+			return f, e
+		}
+		if l < 0 {
+			return f, EBADFRM
+		}
+		if f.Command == MESSAGE {
+			// A streamed body is never held, so MaxBody does not apply
+			if c.rbs = c.streamFor(f.Headers, l); c.rbs != nil {
+				return f, nil
+			}
+		}
+		if l > c.flim.MaxBody {
+			return f, &FrameLimitError{"MaxBody", c.flim.MaxBody}
+		}
+		if l == 0 {
+			f.Body, e = legacyReadUntilNul(c)
+		} else {
+			f.Body, e = legacyReadBody(c, l)
+		}
+	} else {
+		// content-length not present
+		f.Body, e = legacyReadUntilNul(c)
+	}
+	if c.checkReadError(e) != nil {
+		return f, e
+	}
+	if c.hbd != nil {
+		c.updateHBReads()
+	}
+	// End of read loop - set no deadline
+	if c.dld.rde {
+		_ = c.netconn.SetReadDeadline(c.dld.t0)
+	}
+	return f, e
+}
+
+func legacyReadUntilNul(c *Connection) ([]uint8, error) {
+	c.setReadDeadline()
+	b, e := c.rdr.ReadBytes(0)
+	if c.checkReadError(e) != nil {
+		return b, e
+	}
+	if len(b) == 1 {
+		b = NULLBUFF
+	} else {
+		b = b[0 : len(b)-1]
+	}
+	return b, e
+}
+
+func legacyReadBody(c *Connection, l int) ([]uint8, error) {
+	b := make([]byte, l)
+	c.setReadDeadline()
+	n, e := io.ReadFull(c.rdr, b)
+	if n < l && n != 0 { // Short read, e is ErrUnexpectedEOF
+		return b[0 : n-1], e
+	}
+	if c.checkReadError(e) != nil { // Other erors
+		return b, e
+	}
+	_, _ = c.rdr.ReadByte() // trailing NUL
+	return b, e
+}
+
+/*
+	An endless stream of one frame.
+*/
+type frameLoop struct {
+	b []byte
+	i int
+}
+
+func (l *frameLoop) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		k := copy(p[n:], l.b[l.i:])
+		n += k
+		l.i = (l.i + k) % len(l.b)
+	}
+	return n, nil
+}
+
+/*
+	A typical broker MESSAGE.
+*/
+func benchFrame(body int, escaped bool) string {
+	v := "/queue/bench.a"
+	if escaped {
+		v = "/queue/bench\\ca\\nb"
+	}
+	return "MESSAGE\nsubscription:sub-0\nmessage-id:ID\\cbroker-41235-1:2:1:1:17\n" +
+		"destination:" + v + "\nack:ID\\cbroker-41235-1:2:1:1:17\n" +
+		"content-type:text/plain; charset=UTF-8\ntimestamp:1760000000000\n" +
+		"priority:4\nexpires:0\ncontent-length:" + strconv.Itoa(body) + "\n\n" +
+		strings.Repeat("b", body) + "\x00"
+}
+
+func benchConn(frame string, pool bool) *Connection {
+	return &Connection{rdr: bufio.NewReader(&frameLoop{b: []byte(frame)}),
+		dld: &deadlineData{}, flim: FrameLimits{}.resolve(), protocol: SPL_12,
+		pbodies: pool}
+}
+
+/*
+	Test that the byte level parser and the string based parser agree.
+*/
+func TestReadFrameLegacy(t *testing.T) {
+	for _, fs := range []string{benchFrame(0, false), benchFrame(300, true),
+		"MESSAGE\r\nsubscription:0\r\nk\\c:v\\\\n\r\n\r\nbody\x00",
+		"RECEIPT\nreceipt-id:r1\n\n\x00"} {
+		c, lc := benchConn(fs, false), benchConn(fs, false)
+		f, e := c.readFrameFor(validCmds)
+		lf, le := lc.legacyReadFrameFor(validCmds)
+		if e != nil || le != nil || f.Command != lf.Command ||
+			!f.Headers.Compare(lf.Headers) || !bytes.Equal(f.Body, lf.Body) {
+			t.Fatalf("TestReadFrameLegacy Expected [%q] [%v], got [%q] [%v]\n", lf, le,
+				f, e)
+		}
+	}
+}
+
+/*
+	Test that released bodies are reused.
+*/
+func TestReadFramePooled(t *testing.T) {
+	c := benchConn(benchFrame(700, false), true)
+	f, e := c.readFrameFor(validCmds)
+	if e != nil || len(f.Body) != 700 || cap(f.Body) != 1024 {
+		t.Fatalf("TestReadFramePooled Expected pooled body, got [%d] [%d] [%v]\n",
+			len(f.Body), cap(f.Body), e)
+	}
+	md := MessageData{Message: Message(f)}
+	md.Release()
+	if md.Message.Body != nil {
+		t.Fatalf("TestReadFramePooled Expected body dropped\n")
+	}
+	md.Release() // A second call does nothing
+	// Not pooled: too large, or not from getBody
+	putBody(make([]byte, 10, 1000))
+	putBody(make([]byte, 2<<bodyPoolMax))
+	if b := getBody(1 << bodyPoolMax); cap(b) != 1<<bodyPoolMax {
+		t.Fatalf("TestReadFramePooled Unexpected cap [%d]\n", cap(b))
+	}
+}
+
+func benchmarkRead(b *testing.B, frame string, legacy, pool bool) {
+	c := benchConn(frame, pool)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var f Frame
+		var e error
+		if legacy {
+			f, e = c.legacyReadFrameFor(validCmds)
+		} else {
+			f, e = c.readFrameFor(validCmds)
+		}
+		if e != nil {
+			b.Fatal(e)
+		}
+		if pool {
+			md := MessageData{Message: Message(f)}
+			md.Release()
+		}
+	}
+}
+
+func BenchmarkReadFrameLegacy(b *testing.B) {
+	benchmarkRead(b, benchFrame(256, false), true, false)
+}
+
+func BenchmarkReadFrame(b *testing.B) {
+	benchmarkRead(b, benchFrame(256, false), false, false)
+}
+
+func BenchmarkReadFramePooled(b *testing.B) {
+	benchmarkRead(b, benchFrame(256, false), false, true)
+}
+
+func BenchmarkReadFrameEscapedLegacy(b *testing.B) {
+	benchmarkRead(b, benchFrame(256, true), true, false)
+}
+
+func BenchmarkReadFrameEscaped(b *testing.B) {
+	benchmarkRead(b, benchFrame(256, true), false, false)
+}
+
+func BenchmarkReadFrameNoLengthLegacy(b *testing.B) {
+	f := strings.Replace(benchFrame(256, false), "content-length:256\n", "", 1)
+	benchmarkRead(b, f, true, false)
+}
+
+func BenchmarkReadFrameNoLength(b *testing.B) {
+	f := strings.Replace(benchFrame(256, false), "content-length:256\n", "", 1)
+	benchmarkRead(b, f, false, false)
+}
@@ -0,0 +1,265 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+)
+
+/*
+	Receipt is the future result of a frame sent with a receipt request.
+
+	It completes when the broker RECEIPT with a matching receipt-id arrives.
+	It fails with a *BrokerError when the broker sends an ERROR frame with a
+	matching receipt-id, and with the read error or ECONBAD when the
+	connection ends first.
+
+	RECEIPT and ERROR frames for a Receipt are not placed on
+	Connection.MessageData.
+*/
+type Receipt struct {
+	id   string
+	done chan struct{}
+	md   MessageData
+}
+
+/*
+	ID returns the receipt id.
+*/
+func (r *Receipt) ID() string {
+	return r.id
+}
+
+/*
+	Done returns a channel that is closed when the receipt completes or
+	fails.
+*/
+func (r *Receipt) Done() <-chan struct{} {
+	return r.done
+}
+
+/*
+	Wait waits for the receipt, giving up when ctx is done.  It returns the
+	RECEIPT frame, or the ERROR frame and a *BrokerError.
+
+	Example:
+		r, e := c.SendWithReceipt(h, "my message")
+		if e != nil {
+			// Do something sane ...
+		}
+		ctx, cf := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cf()
+		_, e = r.Wait(ctx)
+		if e != nil {
+			// Do something sane ...
+		}
+*/
+func (r *Receipt) Wait(ctx context.Context) (Message, error) {
+	select {
+	case <-r.done:
+		return r.md.Message, r.md.Error
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+/*
+	NewReceipt registers a Receipt with a generated id.  Add it to any frame
+	with a receipt header:
+		r, e := c.NewReceipt()
+		if e != nil {
+			// Do something sane ...
+		}
+		e = c.Ack(stompngo.Headers{stompngo.HK_ID, id,
+			stompngo.HK_RECEIPT, r.ID()})
+
+	If the frame is never sent, the Receipt stays pending until the connection
+	ends.
+*/
+func (c *Connection) NewReceipt() (*Receipt, error) {
+	return c.addReceipt(Uuid())
+}
+
+/*
+	AbortWithReceipt is Abort, with a receipt request.
+*/
+func (c *Connection) AbortWithReceipt(h Headers) (*Receipt, error) {
+	return c.withReceipt(h, c.Abort)
+}
+
+/*
+	AckWithReceipt is Ack, with a receipt request.
+*/
+func (c *Connection) AckWithReceipt(h Headers) (*Receipt, error) {
+	return c.withReceipt(h, c.Ack)
+}
+
+/*
+	BeginWithReceipt is Begin, with a receipt request.
+*/
+func (c *Connection) BeginWithReceipt(h Headers) (*Receipt, error) {
+	return c.withReceipt(h, c.Begin)
+}
+
+/*
+	CommitWithReceipt is Commit, with a receipt request.
+*/
+func (c *Connection) CommitWithReceipt(h Headers) (*Receipt, error) {
+	return c.withReceipt(h, c.Commit)
+}
+
+/*
+	NackWithReceipt is Nack, with a receipt request.
+*/
+func (c *Connection) NackWithReceipt(h Headers) (*Receipt, error) {
+	return c.withReceipt(h, c.Nack)
+}
+
+/*
+	SendWithReceipt is Send, with a receipt request.
+
+	Example:
+		h := stompngo.Headers{stompngo.HK_DESTINATION, "/queue/mymessages"}
+		r, e := c.SendWithReceipt(h, "My message")
+		if e != nil {
+			// Do something sane ...
+		}
+		<-r.Done() // The broker has the message
+*/
+func (c *Connection) SendWithReceipt(h Headers, b string) (*Receipt, error) {
+	return c.withReceipt(h, func(h Headers) error {
+		return c.Send(h, b)
+	})
+}
+
+/*
+	SendBytesWithReceipt is SendBytes, with a receipt request.
+*/
+func (c *Connection) SendBytesWithReceipt(h Headers, b []byte) (*Receipt, error) {
+	return c.withReceipt(h, func(h Headers) error {
+		return c.SendBytes(h, b)
+	})
+}
+
+/*
+	SubscribeWithReceipt is Subscribe, with a receipt request.
+*/
+func (c *Connection) SubscribeWithReceipt(h Headers) (<-chan MessageData, *Receipt, error) {
+	var s <-chan MessageData
+	r, e := c.withReceipt(h, func(h Headers) error {
+		var e error
+		s, e = c.Subscribe(h)
+		return e
+	})
+	return s, r, e
+}
+
+/*
+	UnsubscribeWithReceipt is Unsubscribe, with a receipt request.
+*/
+func (c *Connection) UnsubscribeWithReceipt(h Headers) (*Receipt, error) {
+	return c.withReceipt(h, c.Unsubscribe)
+}
+
+/*
+	Register a receipt, and call fn with headers that request it.  A receipt
+	header already in h is used as the id.
+*/
+func (c *Connection) withReceipt(h Headers, fn func(Headers) error) (*Receipt, error) {
+	if h == nil {
+		return nil, EHDRNIL
+	}
+	ch := h.Clone()
+	id, ok := ch.Contains(HK_RECEIPT)
+	if !ok {
+		id = Uuid()
+		ch = ch.Add(HK_RECEIPT, id)
+	}
+	r, e := c.addReceipt(id)
+	if e != nil {
+		return nil, e
+	}
+	if e = fn(ch); e != nil {
+		c.dropReceipt(id)
+		return nil, e
+	}
+	return r, nil
+}
+
+/*
+	Register a pending receipt.
+*/
+func (c *Connection) addReceipt(id string) (*Receipt, error) {
+	c.rcptLock.Lock()
+	defer c.rcptLock.Unlock()
+	if c.rcptErr != nil {
+		return nil, c.rcptErr
+	}
+	if _, ok := c.rcpts[id]; ok {
+		return nil, EDUPRID
+	}
+	r := &Receipt{id: id, done: make(chan struct{})}
+	c.rcpts[id] = r
+	return r, nil
+}
+
+/*
+	Forget a pending receipt.
+*/
+func (c *Connection) dropReceipt(id string) {
+	c.rcptLock.Lock()
+	delete(c.rcpts, id)
+	c.rcptLock.Unlock()
+}
+
+/*
+	Complete the receipt named by a RECEIPT or ERROR frame.  Returns false
+	when no Receipt waits for it.
+*/
+func (c *Connection) completeReceipt(md MessageData) bool {
+	id, ok := md.Message.Headers.Contains(HK_RECEIPT_ID)
+	if !ok {
+		return false
+	}
+	c.rcptLock.Lock()
+	r, ok := c.rcpts[id]
+	delete(c.rcpts, id)
+	c.rcptLock.Unlock()
+	if !ok {
+		return false
+	}
+	r.md = md
+	close(r.done)
+	return true
+}
+
+/*
+	Fail every pending receipt, and any registered later.
+*/
+func (c *Connection) failReceipts(e error) {
+	c.rcptLock.Lock()
+	defer c.rcptLock.Unlock()
+	if c.rcptErr != nil {
+		return
+	}
+	c.rcptErr = e
+	for id, r := range c.rcpts {
+		r.md.Error = e
+		close(r.done)
+		delete(c.rcpts, id)
+	}
+}
@@ -0,0 +1,127 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test that receipts are routed to their futures, and not to MessageData.
+*/
+func TestReceiptRouted(t *testing.T) {
+	for _, sp := range Protocols() {
+		n, _ = openConn(t)
+		conn, e = Connect(n, headersProtocol(login_headers, sp))
+		if e != nil {
+			t.Fatalf("TestReceiptRouted CONNECT Failed: e:<%q>\n", e)
+		}
+		d := tdest("/queue/receipt.routed." + sp)
+		sc, rs, e := conn.SubscribeWithReceipt(Headers{HK_DESTINATION, d})
+		if e != nil {
+			t.Fatalf("TestReceiptRouted Expected no subscribe error, got [%v]\n", e)
+		}
+		r, e := conn.SendWithReceipt(Headers{HK_DESTINATION, d,
+			HK_RECEIPT, "rcpt-" + sp}, "routed")
+		if e != nil {
+			t.Fatalf("TestReceiptRouted Expected no send error, got [%v]\n", e)
+		}
+		if r.ID() != "rcpt-"+sp {
+			t.Fatalf("TestReceiptRouted Expected id [%v], got [%v]\n", "rcpt-"+sp,
+				r.ID())
+		}
+		ctx, cf := context.WithTimeout(context.Background(), 5*time.Second)
+		for _, rv := range []*Receipt{rs, r} {
+			m, e := rv.Wait(ctx)
+			if e != nil || m.Command != RECEIPT ||
+				m.Headers.Value(HK_RECEIPT_ID) != rv.ID() {
+				t.Fatalf("TestReceiptRouted Expected RECEIPT for [%v], got [%v] [%v]\n",
+					rv.ID(), m, e)
+			}
+		}
+		cf()
+		checkReceivedMD(t, conn, sc, "receipt_routed")
+		checkReceived(t, conn, false)
+		// A caller supplied id must be unique among pending receipts
+		p, e := conn.NewReceipt()
+		if e != nil {
+			t.Fatalf("TestReceiptRouted Expected no error, got [%v]\n", e)
+		}
+		if _, e = conn.SendWithReceipt(Headers{HK_DESTINATION, d,
+			HK_RECEIPT, p.ID()}, "dup"); e != EDUPRID {
+			t.Fatalf("TestReceiptRouted Expected [%v], got [%v]\n", EDUPRID, e)
+		}
+		//
+		e = conn.Disconnect(empty_headers)
+		checkDisconnectError(t, e)
+		<-p.Done()
+		if _, e = p.Wait(context.Background()); e != ECONBAD {
+			t.Fatalf("TestReceiptRouted Expected [%v], got [%v]\n", ECONBAD, e)
+		}
+		_ = closeConn(t, n)
+	}
+}
+
+/*
+	Test that an ERROR frame for a receipt, and a dead connection, fail
+	pending receipts.
+*/
+func TestReceiptFailed(t *testing.T) {
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			return f.Command == SEND // No RECEIPT for SEND, ever
+		}})
+	defer b.Close()
+	n := b.Pipe()
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestReceiptFailed CONNECT Failed: e:<%q>\n", e)
+	}
+	rs, e := conn.SendWithReceipt(Headers{HK_DESTINATION, "/queue/receipt.failed"},
+		"lost")
+	if e != nil {
+		t.Fatalf("TestReceiptFailed Expected no send error, got [%v]\n", e)
+	}
+	select {
+	case <-rs.Done():
+		t.Fatalf("TestReceiptFailed Expected receipt pending\n")
+	default:
+	}
+	rc, e := conn.CommitWithReceipt(Headers{HK_TRANSACTION, "unknown"})
+	if e != nil {
+		t.Fatalf("TestReceiptFailed Expected no commit error, got [%v]\n", e)
+	}
+	m, e := rc.Wait(context.Background())
+	var be *BrokerError
+	if !errors.As(e, &be) || be.ReceiptID != rc.ID() || m.Command != ERROR {
+		t.Fatalf("TestReceiptFailed Expected ERROR and BrokerError, got [%v] [%v]\n",
+			m.Command, e)
+	}
+	// The broker closes the connection after ERROR
+	if _, e = rs.Wait(context.Background()); e == nil {
+		t.Fatalf("TestReceiptFailed Expected error for lost receipt, got nil\n")
+	}
+	if _, e = conn.NewReceipt(); e == nil {
+		t.Fatalf("TestReceiptFailed Expected error after connection end, got nil\n")
+	}
+	_ = closeConn(t, n)
+}
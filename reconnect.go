@@ -0,0 +1,730 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+/*
+	Dialer obtains a new network connection to a STOMP broker.
+*/
+type Dialer func() (net.Conn, error)
+
+/*
+	ContextDialer is a Dialer that gives up when ctx is done, for example
+	Failover.DialContext.
+*/
+type ContextDialer func(ctx context.Context) (net.Conn, error)
+
+/*
+	Reconnector is a STOMP client that survives the loss of its network
+	connection.
+
+	A Reconnector remembers the CONNECT headers and every active subscription.
+	When the underlying Connection shuts down for any reason other than
+	Disconnect, the Reconnector redials with backoff, connects with the
+	original headers, and re-issues each SUBSCRIBE with the original headers
+	and subscription id.  The channels returned by Subscribe stay the same
+	across reconnects.
+
+	A subscription the broker rejects with an ERROR frame when it is
+	re-issued is not re-issued again.  Its channel gets the *BrokerError, and
+	is closed.
+
+	Messages that were received but not acknowledged before a reconnect are
+	redelivered by the broker according to its own rules.  ACK or NACK of
+	such a message after a reconnect is likely to be rejected by the broker.
+
+	Example:
+		d := func() (net.Conn, error) {
+			return net.Dial(stompngo.NetProtoTCP, "localhost:61613")
+		}
+		h := stompngo.Headers{stompngo.HK_ACCEPT_VERSION, "1.2",
+			stompngo.HK_HOST, "localhost"}
+		r, e := stompngo.NewReconnector(d, h)
+		if e != nil {
+			// Do something sane ...
+		}
+		s, e := r.Subscribe(stompngo.Headers{stompngo.HK_DESTINATION, "/queue/q"})
+		// Use s, which survives reconnects
+*/
+type Reconnector struct {
+	dial    ContextDialer
+	ctx     context.Context    // Done when the Reconnector is finished
+	cancel  context.CancelFunc // Cancels ctx
+	ch      Headers            // CONNECT headers
+	bo      Backoff
+	scc     int               // Subscribe channel capacity
+	lock    sync.Mutex        // Protects all below
+	conn    *Connection       // Current connection
+	subs    map[string]*resub // Active subscriptions by id
+	order   []string          // Subscription ids in SUBSCRIBE order
+	pend    map[string]bool   // Subscription ids being subscribed
+	wg      sync.WaitGroup    // Running pumps
+	done    chan struct{}     // Closed when the Reconnector is finished
+	closing bool              // Disconnect called, or reconnect failed
+	recs    int64             // Successful reconnect count
+	err     error             // Reason for giving up, if any
+}
+
+type resub struct {
+	h    Headers          // SUBSCRIBE headers, always with an id
+	md   chan MessageData // Channel handed to the client
+	stop chan struct{}    // Closed on UNSUBSCRIBE, or when rejected
+	wg   sync.WaitGroup   // Running pumps for this subscription
+}
+
+/*
+	NewReconnector dials and connects, and returns a Reconnector for the
+	new connection.  The first connection attempt is not retried.
+*/
+func NewReconnector(d Dialer, h Headers) (*Reconnector, error) {
+	return NewReconnectorContext(func(context.Context) (net.Conn, error) {
+		return d()
+	}, h)
+}
+
+/*
+	NewReconnectorContext is NewReconnector with a ContextDialer.  A dial in
+	progress is cancelled by Disconnect.
+
+	Example:
+		f, e := stompngo.ParseFailover("failover:(tcp://a:61613,tcp://b:61613)")
+		if e != nil {
+			// Do something sane ...
+		}
+		r, e := stompngo.NewReconnectorContext(f.DialContext, h)
+*/
+func NewReconnectorContext(d ContextDialer, h Headers) (*Reconnector, error) {
+	if h == nil {
+		return nil, EHDRNIL
+	}
+	ctx, cf := context.WithCancel(context.Background())
+	r := &Reconnector{dial: d,
+		ctx:    ctx,
+		cancel: cf,
+		ch:     h.Clone(),
+		bo:     DefaultBackoff,
+		scc:    1,
+		subs:   make(map[string]*resub),
+		pend:   make(map[string]bool),
+		done:   make(chan struct{})}
+	c, e := r.connect()
+	if e != nil {
+		cf()
+		return nil, e
+	}
+	r.conn = c
+	go r.supervise()
+	return r, nil
+}
+
+/*
+	SetBackoff sets the delays used between reconnect attempts.
+*/
+func (r *Reconnector) SetBackoff(b Backoff) {
+	r.lock.Lock()
+	r.bo = b
+	r.lock.Unlock()
+}
+
+/*
+	SetSubChanCap sets a new subscribe channel capacity, to be used during future
+	SUBSCRIBE operations and by future connections.
+*/
+func (r *Reconnector) SetSubChanCap(nc int) {
+	r.lock.Lock()
+	r.scc = nc
+	r.conn.SetSubChanCap(nc)
+	r.lock.Unlock()
+}
+
+/*
+	Connection returns the current underlying connection.
+*/
+func (r *Reconnector) Connection() *Connection {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.conn
+}
+
+/*
+	Connected returns the current connection status.
+*/
+func (r *Reconnector) Connected() bool {
+	return r.Connection().Connected()
+}
+
+/*
+	Session returns the broker assigned session id of the current connection.
+*/
+func (r *Reconnector) Session() string {
+	return r.Connection().Session()
+}
+
+/*
+	Endpoint returns the broker address the current connection uses.
+*/
+func (r *Reconnector) Endpoint() string {
+	return r.Connection().Endpoint()
+}
+
+/*
+	Protocol returns the current connection protocol level.
+*/
+func (r *Reconnector) Protocol() string {
+	return r.Connection().Protocol()
+}
+
+/*
+	Reconnects returns the number of successful reconnects.
+*/
+func (r *Reconnector) Reconnects() int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.recs
+}
+
+/*
+	Err returns the reason reconnecting stopped, or nil.
+*/
+func (r *Reconnector) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+/*
+	Subscribe to a STOMP subscription.  See Connection.Subscribe.
+
+	If no "id" header is supplied one is generated, so that the same id can be
+	used after a reconnect.
+*/
+func (r *Reconnector) Subscribe(h Headers) (<-chan MessageData, error) {
+	return r.SubscribeContext(context.Background(), h)
+}
+
+/*
+	SubscribeContext is Subscribe, giving up when ctx is done.  See
+	Connection.SubscribeContext.
+*/
+func (r *Reconnector) SubscribeContext(ctx context.Context, h Headers) (<-chan MessageData, error) {
+	r.lock.Lock()
+	if r.closing {
+		r.lock.Unlock()
+		return nil, ECONBAD
+	}
+	c := r.conn
+	ch := h.Clone()
+	if _, ok := ch.Contains(HK_ID); !ok {
+		if c.Protocol() == SPL_10 {
+			ch = ch.Add(HK_ID, Sha1(ch.Value(HK_DESTINATION)))
+		} else {
+			ch = ch.Add(HK_ID, Uuid())
+		}
+	}
+	id := ch.Value(HK_ID)
+	if _, ok := r.subs[id]; ok || r.pend[id] {
+		r.lock.Unlock()
+		return nil, EDUPSID
+	}
+	r.pend[id] = true
+	var in <-chan MessageData
+	var e error
+	for {
+		r.lock.Unlock()
+		in, e = c.SubscribeContext(ctx, ch)
+		r.lock.Lock()
+		if r.closing || r.conn == c {
+			break
+		}
+		c = r.conn // Reconnected meanwhile, subscribe there
+	}
+	defer r.lock.Unlock()
+	delete(r.pend, id)
+	if e == nil && r.closing {
+		e = ECONBAD
+	}
+	if e != nil {
+		return nil, e
+	}
+	s := &resub{h: ch,
+		md:   make(chan MessageData, c.SubChanCap()),
+		stop: make(chan struct{})}
+	r.subs[id] = s
+	r.order = append(r.order, id)
+	r.startPump(c, s, in)
+	return s.md, nil
+}
+
+/*
+	Unsubscribe from a STOMP subscription.  See Connection.Unsubscribe.
+*/
+func (r *Reconnector) Unsubscribe(h Headers) error {
+	return r.UnsubscribeContext(context.Background(), h)
+}
+
+/*
+	UnsubscribeContext is Unsubscribe, giving up when ctx is done.  See
+	Connection.UnsubscribeContext.
+*/
+func (r *Reconnector) UnsubscribeContext(ctx context.Context, h Headers) error {
+	id, ok := h.Contains(HK_ID)
+	if !ok {
+		id = Sha1(h.Value(HK_DESTINATION)) // 1.0
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok = r.subs[id]; !ok {
+		return EBADSID
+	}
+	for {
+		c := r.conn
+		r.lock.Unlock()
+		e := c.UnsubscribeContext(ctx, h)
+		r.lock.Lock()
+		if _, ok = r.subs[id]; !ok {
+			return e // Rejected, or closed, meanwhile
+		}
+		if r.conn != c && !r.closing {
+			continue // Reconnected meanwhile, unsubscribe there
+		}
+		if e != nil && c.hasSubscription(id) {
+			return e // Nothing sent
+		}
+		r.drop(id)
+		return e
+	}
+}
+
+/*
+	Forget a subscription, and stop its pumps.  Lock must be held.
+*/
+func (r *Reconnector) drop(id string) {
+	close(r.subs[id].stop)
+	delete(r.subs, id)
+	for i, v := range r.order {
+		if v == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+/*
+	Disconnect from the STOMP broker, and stop reconnecting.  All subscription
+	channels are closed, and the network connection is closed.
+*/
+func (r *Reconnector) Disconnect(h Headers) error {
+	return r.DisconnectContext(context.Background(), h)
+}
+
+/*
+	DisconnectContext is Disconnect, giving up when ctx is done.  See
+	Connection.DisconnectContext.
+*/
+func (r *Reconnector) DisconnectContext(ctx context.Context, h Headers) error {
+	r.lock.Lock()
+	if r.closing {
+		r.lock.Unlock()
+		return ECONBAD
+	}
+	r.closing = true
+	c := r.conn
+	r.lock.Unlock()
+	e := c.DisconnectContext(ctx, h)
+	if e != nil && c.Connected() {
+		// Nothing sent, keep going
+		r.lock.Lock()
+		r.closing = false
+		r.lock.Unlock()
+		return e
+	}
+	_ = c.netconn.Close()
+	r.cancel()
+	close(r.done)
+	r.wg.Wait()
+	r.closeSubs(nil)
+	return e
+}
+
+/*
+	Send a STOMP MESSAGE on the current connection.  See Connection.Send.
+*/
+func (r *Reconnector) Send(h Headers, b string) error {
+	return r.Connection().Send(h, b)
+}
+
+/*
+	SendContext is Send, giving up when ctx is done.
+*/
+func (r *Reconnector) SendContext(ctx context.Context, h Headers, b string) error {
+	return r.Connection().SendContext(ctx, h, b)
+}
+
+/*
+	SendBytes sends a STOMP MESSAGE on the current connection.  See
+	Connection.SendBytes.
+*/
+func (r *Reconnector) SendBytes(h Headers, b []byte) error {
+	return r.Connection().SendBytes(h, b)
+}
+
+/*
+	SendBytesContext is SendBytes, giving up when ctx is done.
+*/
+func (r *Reconnector) SendBytesContext(ctx context.Context, h Headers, b []byte) error {
+	return r.Connection().SendBytesContext(ctx, h, b)
+}
+
+/*
+	Ack a STOMP MESSAGE on the current connection.  See Connection.Ack.
+*/
+func (r *Reconnector) Ack(h Headers) error {
+	return r.Connection().Ack(h)
+}
+
+/*
+	AckContext is Ack, giving up when ctx is done.
+*/
+func (r *Reconnector) AckContext(ctx context.Context, h Headers) error {
+	return r.Connection().AckContext(ctx, h)
+}
+
+/*
+	Nack a STOMP MESSAGE on the current connection.  See Connection.Nack.
+*/
+func (r *Reconnector) Nack(h Headers) error {
+	return r.Connection().Nack(h)
+}
+
+/*
+	NackContext is Nack, giving up when ctx is done.
+*/
+func (r *Reconnector) NackContext(ctx context.Context, h Headers) error {
+	return r.Connection().NackContext(ctx, h)
+}
+
+/*
+	Begin a STOMP transaction on the current connection.  See Connection.Begin.
+*/
+func (r *Reconnector) Begin(h Headers) error {
+	return r.Connection().Begin(h)
+}
+
+/*
+	BeginContext is Begin, giving up when ctx is done.
+*/
+func (r *Reconnector) BeginContext(ctx context.Context, h Headers) error {
+	return r.Connection().BeginContext(ctx, h)
+}
+
+/*
+	Commit a STOMP transaction on the current connection.  See
+	Connection.Commit.
+*/
+func (r *Reconnector) Commit(h Headers) error {
+	return r.Connection().Commit(h)
+}
+
+/*
+	CommitContext is Commit, giving up when ctx is done.
+*/
+func (r *Reconnector) CommitContext(ctx context.Context, h Headers) error {
+	return r.Connection().CommitContext(ctx, h)
+}
+
+/*
+	Abort a STOMP transaction on the current connection.  See Connection.Abort.
+*/
+func (r *Reconnector) Abort(h Headers) error {
+	return r.Connection().Abort(h)
+}
+
+/*
+	AbortContext is Abort, giving up when ctx is done.
+*/
+func (r *Reconnector) AbortContext(ctx context.Context, h Headers) error {
+	return r.Connection().AbortContext(ctx, h)
+}
+
+/*
+	Dial and connect with the original CONNECT headers.
+*/
+func (r *Reconnector) connect() (*Connection, error) {
+	n, e := r.dial(r.ctx)
+	if e != nil {
+		return nil, e
+	}
+	c, e := Connect(n, r.ch)
+	if e != nil {
+		_ = n.Close()
+		return nil, e
+	}
+	r.lock.Lock()
+	c.SetSubChanCap(r.scc)
+	r.lock.Unlock()
+	return c, nil
+}
+
+/*
+	Watch the current connection, and replace it when it shuts down.
+*/
+func (r *Reconnector) supervise() {
+	for {
+		c := r.Connection()
+		select {
+		case <-c.ssdc:
+		case <-r.done:
+			return
+		}
+		r.lock.Lock()
+		closing := r.closing
+		r.lock.Unlock()
+		if closing {
+			return
+		}
+		c.warn("RECONNECT connection lost")
+		_ = c.netconn.Close() // Wake up a reader that might still be blocked
+		if e := r.reconnect(); e != nil {
+			r.fail(e)
+			return
+		}
+	}
+}
+
+/*
+	Redial with backoff, and restore subscriptions.
+*/
+func (r *Reconnector) reconnect() error {
+	r.lock.Lock()
+	bo := r.bo
+	r.lock.Unlock()
+	for n := 0; !bo.Exhausted(n); n++ {
+		select {
+		case <-time.After(bo.Delay(n)):
+		case <-r.done:
+			return nil
+		}
+		c, e := r.connect()
+		if e != nil {
+			continue
+		}
+		if e = r.resubscribe(c); e != nil {
+			_ = c.netconn.Close()
+			r.lock.Lock()
+			closing := r.closing
+			r.lock.Unlock()
+			if closing {
+				return nil
+			}
+			c.error("RECONNECT resubscribe failed", "error", e)
+			continue
+		}
+		c.info("RECONNECT complete", "attempts", n+1)
+		return nil
+	}
+	return ERECONN
+}
+
+/*
+	Re-issue every SUBSCRIBE on a new connection, and make it the current
+	connection.  Each SUBSCRIBE asks for a receipt, so that an ERROR names the
+	subscription the broker rejected.  That one is dropped.
+
+	The lock is not held while subscribing, so subscriptions added meanwhile
+	are re-issued in turn, and those removed meanwhile are unsubscribed.
+*/
+func (r *Reconnector) resubscribe(c *Connection) error {
+	ins := make(map[*resub]<-chan MessageData)
+	for {
+		r.lock.Lock()
+		if r.closing {
+			r.lock.Unlock()
+			return ECONBAD
+		}
+		var ss []*resub
+		for _, id := range r.order {
+			if s := r.subs[id]; ins[s] == nil {
+				ss = append(ss, s)
+			}
+		}
+		if len(ss) == 0 {
+			break // Still locked
+		}
+		r.lock.Unlock()
+		for _, s := range ss {
+			in, rc, e := c.SubscribeWithReceipt(s.h)
+			if e != nil {
+				return e
+			}
+			select {
+			case <-rc.Done():
+			case <-r.done:
+				return ECONBAD
+			}
+			m, e := rc.Wait(context.Background())
+			var be *BrokerError
+			if errors.As(e, &be) {
+				r.reject(s, MessageData{Message: m, Error: e})
+			}
+			if e != nil {
+				return e
+			}
+			ins[s] = in
+		}
+	}
+	var stale []*resub
+	for s := range ins {
+		if r.subs[s.h.Value(HK_ID)] != s {
+			stale = append(stale, s)
+		}
+	}
+	for _, id := range r.order {
+		s := r.subs[id]
+		r.startPump(c, s, ins[s])
+	}
+	r.conn = c
+	r.recs++
+	r.lock.Unlock()
+	for _, s := range stale {
+		_ = c.Unsubscribe(Headers{HK_DESTINATION, s.h.Value(HK_DESTINATION),
+			HK_ID, s.h.Value(HK_ID)})
+	}
+	return nil
+}
+
+/*
+	Drop a subscription the broker rejected.  Its channel gets the ERROR,
+	and is closed.
+*/
+func (r *Reconnector) reject(s *resub, md MessageData) {
+	id := s.h.Value(HK_ID)
+	r.lock.Lock()
+	if r.subs[id] != s {
+		r.lock.Unlock()
+		return // Unsubscribed, or already closed
+	}
+	r.drop(id)
+	r.lock.Unlock()
+	s.wg.Wait()
+	select {
+	case s.md <- md:
+	default:
+	}
+	close(s.md)
+}
+
+/*
+	Start a pump for a subscription.
+*/
+func (r *Reconnector) startPump(c *Connection, s *resub, in <-chan MessageData) {
+	r.wg.Add(1)
+	s.wg.Add(1)
+	go r.pump(c, s, in)
+}
+
+/*
+	Give up: report the error on each subscription channel, and close them.
+*/
+func (r *Reconnector) fail(e error) {
+	r.lock.Lock()
+	if r.closing {
+		r.lock.Unlock()
+		return
+	}
+	r.closing = true
+	r.err = e
+	r.lock.Unlock()
+	r.cancel()
+	close(r.done)
+	r.wg.Wait()
+	r.closeSubs(e)
+}
+
+/*
+	Close all client subscription channels, optionally reporting an error
+	first.  Pumps must be finished.
+*/
+func (r *Reconnector) closeSubs(e error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, id := range r.order {
+		s := r.subs[id]
+		if e != nil {
+			select {
+			case s.md <- MessageData{Error: e}:
+			default:
+			}
+		}
+		close(s.md)
+	}
+	r.subs = make(map[string]*resub)
+	r.order = nil
+}
+
+/*
+	Move MessageData from one connection's subscription channel to the client
+	channel.  Connection level errors are not passed on, they are handled by
+	reconnecting.  Errors about one message, for example a *CryptoError, are.
+*/
+func (r *Reconnector) pump(c *Connection, s *resub, in <-chan MessageData) {
+	defer r.wg.Done()
+	defer s.wg.Done()
+	for {
+		select {
+		case md, ok := <-in:
+			if !ok || !r.forward(s, md) {
+				return
+			}
+		case <-c.rdrdc:
+			// The reader is gone, pass on anything already buffered.
+			for {
+				select {
+				case md, ok := <-in:
+					if !ok || !r.forward(s, md) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		case <-s.stop:
+			return
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *Reconnector) forward(s *resub, md MessageData) bool {
+	if isReadError(md.Error) {
+		return true
+	}
+	select {
+	case s.md <- md:
+		return true
+	case <-s.stop:
+	case <-r.done:
+	}
+	return false
+}
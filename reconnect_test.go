@@ -0,0 +1,358 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+	"github.com/gmallard/stompngo/senv"
+)
+
+/*
+	Test Backoff delay calculation.
+*/
+func TestReconnectBackoff(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond,
+		Multiplier: 2, MaxAttempts: 3}
+	for n, w := range []time.Duration{10, 20, 40, 50, 50} {
+		if d := b.Delay(n); d != w*time.Millisecond {
+			t.Fatalf("TestReconnectBackoff[%d] Expected [%v], got [%v]\n", n,
+				w*time.Millisecond, d)
+		}
+	}
+	if b.Exhausted(2) || !b.Exhausted(3) {
+		t.Fatalf("TestReconnectBackoff Expected exhaustion after 3 attempts\n")
+	}
+	// No Max, no overflow
+	b = Backoff{Initial: time.Second, Multiplier: 2}
+	for _, n := range []int{40, 100, 10000} {
+		if d := b.Delay(n); d != math.MaxInt64 {
+			t.Fatalf("TestReconnectBackoff[%d] Expected [%v], got [%v]\n", n,
+				time.Duration(math.MaxInt64), d)
+		}
+	}
+	// Odd values
+	for i, tv := range []struct {
+		b    Backoff
+		n    int
+		want time.Duration
+	}{
+		{Backoff{Initial: time.Second, Multiplier: math.NaN()}, 5, time.Second},
+		{Backoff{Initial: time.Second, Multiplier: math.Inf(1)}, 0, time.Second},
+		{Backoff{Initial: time.Second, Multiplier: math.Inf(1), Max: time.Minute},
+			1, time.Minute},
+		{Backoff{Multiplier: math.Inf(1)}, 3, 0},
+		{Backoff{Initial: -time.Second, Multiplier: 2}, 3, 0},
+		{Backoff{Initial: time.Second, Multiplier: 2}, math.MaxInt32, math.MaxInt64},
+	} {
+		if d := tv.b.Delay(tv.n); d != tv.want {
+			t.Fatalf("TestReconnectBackoff[%d] Expected [%v], got [%v]\n", i,
+				tv.want, d)
+		}
+	}
+}
+
+/*
+	Test that a subscription channel survives loss of the network connection.
+*/
+func TestReconnectResubscribe(t *testing.T) {
+	for _, sp := range Protocols() {
+		var lock sync.Mutex
+		var last net.Conn
+		d := func() (net.Conn, error) {
+			h, p := senv.HostAndPort()
+			n, e := net.Dial(NetProtoTCP, net.JoinHostPort(h, p))
+			lock.Lock()
+			last = n
+			lock.Unlock()
+			return n, e
+		}
+		r, e := NewReconnector(d, headersProtocol(login_headers, sp))
+		if e != nil {
+			t.Fatalf("TestReconnectResubscribe CONNECT Failed: e:<%q>\n", e)
+		}
+		r.SetBackoff(Backoff{Initial: 10 * time.Millisecond, MaxAttempts: 100})
+		//
+		qn := tdest("/queue/reconnect.resub." + sp)
+		sc, e := r.Subscribe(Headers{HK_DESTINATION, qn})
+		if e != nil {
+			t.Fatalf("TestReconnectResubscribe Expected no subscribe error, got [%v]\n", e)
+		}
+		for i, m := range []string{"before", "after"} {
+			if i == 1 {
+				lock.Lock()
+				_ = last.Close() // Lose the connection
+				lock.Unlock()
+				for r.Reconnects() == 0 {
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+			if e = r.Send(Headers{HK_DESTINATION, qn}, m); e != nil {
+				t.Fatalf("TestReconnectResubscribe Expected no send error, got [%v]\n", e)
+			}
+			select {
+			case md := <-sc:
+				if md.Error != nil || string(md.Message.Body) != m {
+					t.Fatalf("TestReconnectResubscribe Expected [%v], got [%v] [%v]\n",
+						m, string(md.Message.Body), md.Error)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("TestReconnectResubscribe Expected [%v], got timeout\n", m)
+			}
+		}
+		//
+		e = r.Disconnect(empty_headers)
+		checkDisconnectError(t, e)
+		if _, ok := <-sc; ok {
+			t.Fatalf("TestReconnectResubscribe Expected closed subscription channel\n")
+		}
+	}
+}
+
+/*
+	Test that subscription channels report and close when reconnecting fails.
+*/
+func TestReconnectExhausted(t *testing.T) {
+	var lock sync.Mutex
+	var last net.Conn
+	fails := false
+	d := func() (net.Conn, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if fails {
+			return nil, errors.New("dial refused")
+		}
+		h, p := senv.HostAndPort()
+		n, e := net.Dial(NetProtoTCP, net.JoinHostPort(h, p))
+		last = n
+		return n, e
+	}
+	r, e := NewReconnector(d, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestReconnectExhausted CONNECT Failed: e:<%q>\n", e)
+	}
+	r.SetBackoff(Backoff{Initial: time.Millisecond, MaxAttempts: 3})
+	sc, e := r.Subscribe(Headers{HK_DESTINATION,
+		tdest("/queue/reconnect.exhausted")})
+	if e != nil {
+		t.Fatalf("TestReconnectExhausted Expected no subscribe error, got [%v]\n", e)
+	}
+	lock.Lock()
+	fails = true
+	_ = last.Close()
+	lock.Unlock()
+	select {
+	case md := <-sc:
+		if md.Error != ERECONN {
+			t.Fatalf("TestReconnectExhausted Expected [%v], got [%v]\n", ERECONN,
+				md.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TestReconnectExhausted Expected error, got timeout\n")
+	}
+	if _, ok := <-sc; ok {
+		t.Fatalf("TestReconnectExhausted Expected closed subscription channel\n")
+	}
+	if r.Err() != ERECONN {
+		t.Fatalf("TestReconnectExhausted Expected [%v], got [%v]\n", ERECONN, r.Err())
+	}
+}
+
+/*
+	Test that errors about one message reach the subscription channel.
+*/
+func TestReconnectMessageError(t *testing.T) {
+	d := func() (net.Conn, error) {
+		h, p := senv.HostAndPort()
+		return net.Dial(NetProtoTCP, net.JoinHostPort(h, p))
+	}
+	r, e := NewReconnector(d, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestReconnectMessageError CONNECT Failed: e:<%q>\n", e)
+	}
+	veto := errors.New("vetoed")
+	e = r.Connection().Use(inIcpt(func(f *Frame) error {
+		if string(f.Body) == "bad" {
+			return veto
+		}
+		return nil
+	}))
+	if e != nil {
+		t.Fatalf("TestReconnectMessageError Expected no Use error, got [%v]\n", e)
+	}
+	qn := tdest("/queue/reconnect.msgerr")
+	sc, e := r.Subscribe(Headers{HK_DESTINATION, qn})
+	if e != nil {
+		t.Fatalf("TestReconnectMessageError Expected no subscribe error, got [%v]\n", e)
+	}
+	for _, tv := range []struct {
+		m    string
+		want error
+	}{{"bad", veto}, {"good", nil}} {
+		m := tv.m
+		if e = r.Send(Headers{HK_DESTINATION, qn}, m); e != nil {
+			t.Fatalf("TestReconnectMessageError Expected no send error, got [%v]\n", e)
+		}
+		var md MessageData
+		select {
+		case md = <-sc:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("TestReconnectMessageError Expected [%v], got timeout\n", m)
+		}
+		if md.Error != tv.want || md.Message.BodyString() != m {
+			t.Fatalf("TestReconnectMessageError Expected [%v], got [%v] [%v]\n", m,
+				md.Message.BodyString(), md.Error)
+		}
+	}
+	checkDisconnectError(t, r.Disconnect(empty_headers))
+}
+
+/*
+	Test that a subscription the broker rejects gets the BrokerError, and is
+	not re-issued after the reconnect.
+*/
+func TestReconnectRejected(t *testing.T) {
+	bad := tdest("/queue/reconnect.rejected")
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if d, _ := f.Value(HK_DESTINATION); f.Command == SUBSCRIBE && d == bad {
+				s.Error("access refused", f)
+				return true
+			}
+			return false
+		}})
+	defer b.Close()
+	d := func() (net.Conn, error) {
+		return b.Pipe(), nil
+	}
+	r, e := NewReconnector(d, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestReconnectRejected CONNECT Failed: e:<%q>\n", e)
+	}
+	r.SetBackoff(Backoff{Initial: 10 * time.Millisecond, MaxAttempts: 100})
+	good := tdest("/queue/reconnect.accepted")
+	gsc, e := r.Subscribe(Headers{HK_DESTINATION, good})
+	if e != nil {
+		t.Fatalf("TestReconnectRejected Expected no subscribe error, got [%v]\n", e)
+	}
+	bsc, e := r.Subscribe(Headers{HK_DESTINATION, bad})
+	if e != nil {
+		t.Fatalf("TestReconnectRejected Expected no subscribe error, got [%v]\n", e)
+	}
+	// The first ERROR is seen by both.  The rejected subscription closes
+	// after the reconnect.
+	var be *BrokerError
+	if md := <-gsc; !errors.As(md.Error, &be) {
+		t.Fatalf("TestReconnectRejected Expected BrokerError, got [%v]\n", md.Error)
+	}
+	n := 0
+	for md := range bsc {
+		if !errors.As(md.Error, &be) || be.Message != "access refused" {
+			t.Fatalf("TestReconnectRejected Expected BrokerError, got [%v]\n",
+				md.Error)
+		}
+		n++
+	}
+	if n == 0 {
+		t.Fatalf("TestReconnectRejected Expected errors, got none\n")
+	}
+	for r.Reconnects() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if e = r.Send(Headers{HK_DESTINATION, good}, "after"); e != nil {
+		t.Fatalf("TestReconnectRejected Expected no send error, got [%v]\n", e)
+	}
+	select {
+	case md := <-gsc:
+		if md.Error != nil || md.Message.BodyString() != "after" {
+			t.Fatalf("TestReconnectRejected Expected [after], got [%v] [%v]\n",
+				md.Message.BodyString(), md.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TestReconnectRejected Expected [after], got timeout\n")
+	}
+	if rc := r.Reconnects(); rc != 1 {
+		t.Fatalf("TestReconnectRejected Expected 1 reconnect, got [%d]\n", rc)
+	}
+	checkDisconnectError(t, r.Disconnect(empty_headers))
+}
+
+/*
+	Test that the Reconnector can be used while a SUBSCRIBE is stalled on the
+	network.
+*/
+func TestReconnectStalled(t *testing.T) {
+	release := make(chan struct{})
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if f.Command == SEND && string(f.Body) == "stall" {
+				<-release // Stop reading from the client
+			}
+			return false
+		}})
+	defer b.Close()
+	d := func() (net.Conn, error) {
+		return b.Pipe(), nil
+	}
+	r, e := NewReconnector(d, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestReconnectStalled CONNECT Failed: e:<%q>\n", e)
+	}
+	qn := tdest("/queue/reconnect.stalled")
+	// Stall on another destination, so that nothing is delivered
+	if e = r.Send(Headers{HK_DESTINATION, qn + ".stall"}, "stall"); e != nil {
+		t.Fatalf("TestReconnectStalled Expected no send error, got [%v]\n", e)
+	}
+	sh := Headers{HK_DESTINATION, qn, HK_ID, "stalled"}
+	done := make(chan error, 1)
+	go func() {
+		_, e := r.Subscribe(sh)
+		done <- e
+	}()
+	for {
+		r.lock.Lock()
+		p := r.pend["stalled"]
+		r.lock.Unlock()
+		if p {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, e = r.Subscribe(sh); !errors.Is(e, EDUPSID) {
+		t.Fatalf("TestReconnectStalled Expected [%v], got [%v]\n", EDUPSID, e)
+	}
+	if e = r.Unsubscribe(sh); !errors.Is(e, EBADSID) {
+		t.Fatalf("TestReconnectStalled Expected [%v], got [%v]\n", EBADSID, e)
+	}
+	if !r.Connected() {
+		t.Fatalf("TestReconnectStalled Expected [true], got [false]\n")
+	}
+	close(release)
+	if e = <-done; e != nil {
+		t.Fatalf("TestReconnectStalled Expected no subscribe error, got [%v]\n", e)
+	}
+	if e = r.Unsubscribe(sh); e != nil {
+		t.Fatalf("TestReconnectStalled Expected no unsubscribe error, got [%v]\n", e)
+	}
+	checkDisconnectError(t, r.Disconnect(empty_headers))
+}
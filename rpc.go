@@ -0,0 +1,332 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+/*
+	Requester sends request messages and waits for the matching replies.
+
+	It subscribes once to a private reply destination.  Each request carries
+	that destination in a reply-to header, and a correlation-id header.  A
+	reply is a MESSAGE on the reply destination with the same correlation-id.
+	Replies that match no waiting request are dropped.  A reply delivered
+	with an error, for example a *CryptoError, fails only its own request.
+
+	Requester is safe for concurrent use.
+*/
+type Requester struct {
+	c       *Connection
+	replyTo string
+	subid   string
+	temp    bool // A temporary reply queue, never subscribed to
+	lock    sync.Mutex
+	calls   map[string]chan MessageData
+	err     error // Set when the Requester stops
+	done    chan struct{}
+}
+
+/*
+	NewRequester subscribes to the reply destination replyTo, and returns a
+	Requester using it.
+
+	The reply destination must be private to this Requester.  What works
+	depends on the broker, for example "/temp-queue/replies" for RabbitMQ, or
+	a unique queue name for ActiveMQ.  If replyTo is empty, a unique queue name
+	is generated:
+		/queue/stompngo.reply.<uuid>
+
+	A "/temp-queue/" reply destination is not subscribed to.  RabbitMQ
+	creates the queue when a request names it in reply-to, and delivers the
+	replies with the reply destination as the subscription header.
+
+	Example:
+		rq, e := stompngo.NewRequester(c, "/temp-queue/replies")
+		if e != nil {
+			// Do something sane ...
+		}
+		defer rq.Close()
+		h := stompngo.Headers{stompngo.HK_DESTINATION, "/queue/rpc.service"}
+		m, e := rq.Request(ctx, h, "request body")
+		if e != nil {
+			// Do something sane ...
+		}
+		// Use m, the reply MESSAGE
+*/
+func NewRequester(c *Connection, replyTo string) (*Requester, error) {
+	if replyTo == "" {
+		replyTo = "/queue/stompngo.reply." + Uuid()
+	}
+	r := &Requester{c: c, replyTo: replyTo, subid: Uuid(),
+		calls: make(map[string]chan MessageData), done: make(chan struct{})}
+	var sc <-chan MessageData
+	var e error
+	if strings.HasPrefix(replyTo, tempQueuePrefix) {
+		r.subid, r.temp = replyTo, true
+		sc, e = c.tempQueueSubscription(replyTo)
+	} else {
+		sc, e = c.Subscribe(Headers{HK_DESTINATION, replyTo, HK_ID, r.subid})
+	}
+	if e != nil {
+		return nil, e
+	}
+	go r.dispatch(sc)
+	return r, nil
+}
+
+/*
+	ReplyTo returns the reply destination.
+*/
+func (r *Requester) ReplyTo() string {
+	return r.replyTo
+}
+
+/*
+	Request sends a request and waits for the reply, giving up when ctx is
+	done.
+
+	Headers MUST contain a "destination" header key.  The reply-to header is
+	always set to the Requester's reply destination.  If h has no
+	correlation-id header, one is generated.  Correlation ids must be unique
+	among requests in progress.
+*/
+func (r *Requester) Request(ctx context.Context, h Headers, b string) (Message, error) {
+	return r.RequestBytes(ctx, h, []byte(b))
+}
+
+/*
+	RequestBytes is Request with a []byte body.
+*/
+func (r *Requester) RequestBytes(ctx context.Context, h Headers, b []byte) (Message, error) {
+	if h == nil {
+		return Message{}, EHDRNIL
+	}
+	ch := h.DeleteAll(HK_REPLY_TO).Add(HK_REPLY_TO, r.replyTo)
+	cid, ok := ch.Contains(HK_CORRELATION_ID)
+	if !ok {
+		cid = Uuid()
+		ch = ch.Add(HK_CORRELATION_ID, cid)
+	}
+	rc := make(chan MessageData, 1)
+	r.lock.Lock()
+	if r.err != nil {
+		r.lock.Unlock()
+		return Message{}, r.err
+	}
+	if _, ok = r.calls[cid]; ok {
+		r.lock.Unlock()
+		return Message{}, EDUPCID
+	}
+	r.calls[cid] = rc
+	r.lock.Unlock()
+	defer func() {
+		r.lock.Lock()
+		delete(r.calls, cid)
+		r.lock.Unlock()
+	}()
+	//
+	if e := r.c.SendBytesContext(ctx, ch, b); e != nil {
+		return Message{}, e
+	}
+	select {
+	case md := <-rc:
+		return md.Message, md.Error
+	case <-r.done:
+		return Message{}, r.err
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+/*
+	Close unsubscribes from the reply destination.  Requests in progress fail
+	with EREQCLOSED.
+*/
+func (r *Requester) Close() error {
+	var e error
+	if r.temp {
+		r.c.dropSubscription(r.subid)
+	} else if r.c.Connected() {
+		// Replies are still drained while this runs
+		e = r.c.Unsubscribe(Headers{HK_DESTINATION, r.replyTo, HK_ID, r.subid})
+	}
+	r.stop(EREQCLOSED)
+	return e
+}
+
+/*
+	Route replies to waiting requests, until the subscription or the
+	Requester ends.
+*/
+func (r *Requester) dispatch(sc <-chan MessageData) {
+	for {
+		var md MessageData
+		var ok bool
+		select {
+		case md, ok = <-sc:
+		case <-r.done:
+			return
+		}
+		if !ok {
+			r.stop(ECONBAD)
+			return
+		}
+		if md.Error != nil && rpcStops(md.Error) {
+			r.stop(md.Error)
+			return
+		}
+		cid := md.Message.Headers.Value(HK_CORRELATION_ID)
+		r.lock.Lock()
+		rc, ok := r.calls[cid]
+		delete(r.calls, cid)
+		r.lock.Unlock()
+		if ok {
+			rc <- md
+		}
+	}
+}
+
+/*
+	True if a subscription error ends a Requester or Responder.  Errors about
+	one message do not.  A broker ERROR does, the broker closes the connection
+	after it.
+*/
+func rpcStops(e error) bool {
+	var be *BrokerError
+	return isReadError(e) || errors.As(e, &be)
+}
+
+/*
+	RabbitMQ temporary reply queues.
+*/
+const tempQueuePrefix = "/temp-queue/"
+
+/*
+	Register the subscription for a temporary reply queue, without a
+	SUBSCRIBE.  The id is the queue name, as used by the broker in the
+	subscription header of each reply.
+*/
+func (c *Connection) tempQueueSubscription(dest string) (<-chan MessageData, error) {
+	if !c.isConnected() {
+		return nil, ECONBAD
+	}
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+	if _, ok := c.subs[dest]; ok {
+		return nil, EDUPSID
+	}
+	sd := &subscription{md: make(chan MessageData, c.scc), id: dest,
+		am: AckModeAuto, cry: c.cry}
+	c.subs[dest] = sd
+	return sd.md, nil
+}
+
+/*
+	Stop the Requester, once.
+*/
+func (r *Requester) stop(e error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = e
+	close(r.done)
+}
+
+/*
+	ReplyFunc handles one request message for a Responder.  It returns the
+	headers and body of the reply.
+*/
+type ReplyFunc func(req Message) (Headers, []byte)
+
+/*
+	Responder answers request messages sent by a Requester, or by any client
+	using reply-to and correlation-id headers.
+*/
+type Responder struct {
+	c  *Connection
+	fn ReplyFunc
+}
+
+/*
+	NewResponder returns a Responder that answers requests with fn.
+
+	Example:
+		sc, e := c.Subscribe(stompngo.Headers{stompngo.HK_DESTINATION,
+			"/queue/rpc.service"})
+		if e != nil {
+			// Do something sane ...
+		}
+		rs := stompngo.NewResponder(c, func(req stompngo.Message) (stompngo.Headers, []byte) {
+			return stompngo.Headers{}, []byte("reply body")
+		})
+		e = rs.Serve(sc) // Until the subscription ends
+*/
+func NewResponder(c *Connection, fn ReplyFunc) *Responder {
+	return &Responder{c: c, fn: fn}
+}
+
+/*
+	Serve answers each request received on a subscription channel, until the
+	channel is closed or delivers a connection error.  Messages without a
+	reply-to header are passed to the ReplyFunc, and the reply is dropped.
+	Messages delivered with an error of their own, for example a
+	*CryptoError, are skipped.
+
+	Serve does not ACK requests.  With an ack mode other than "auto", the
+	ReplyFunc is responsible for that.
+*/
+func (r *Responder) Serve(sc <-chan MessageData) error {
+	for md := range sc {
+		if md.Error != nil {
+			if rpcStops(md.Error) {
+				return md.Error
+			}
+			continue
+		}
+		h, b := r.fn(md.Message)
+		if _, ok := md.Message.Headers.Contains(HK_REPLY_TO); !ok {
+			continue
+		}
+		if e := r.Reply(md.Message, h, b); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+/*
+	Reply sends a reply to a request message.  The destination is the
+	request reply-to, and the request correlation-id is copied.
+*/
+func (r *Responder) Reply(req Message, h Headers, b []byte) error {
+	rt, ok := req.Headers.Contains(HK_REPLY_TO)
+	if !ok {
+		return ENOREPLYTO
+	}
+	ch := h.DeleteAll(HK_DESTINATION).Add(HK_DESTINATION, rt)
+	if cid, ok := req.Headers.Contains(HK_CORRELATION_ID); ok {
+		ch = ch.DeleteAll(HK_CORRELATION_ID).Add(HK_CORRELATION_ID, cid)
+	}
+	return r.c.SendBytes(ch, b)
+}
@@ -0,0 +1,277 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test concurrent requests answered by a Responder.
+*/
+func TestRequestReply(t *testing.T) {
+	sn, _ := openConn(t)
+	sconn, e := Connect(sn, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestRequestReply CONNECT Failed: e:<%q>\n", e)
+	}
+	d := tdest("/queue/rpc.service")
+	sc, e := sconn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "rpcsvc"})
+	if e != nil {
+		t.Fatalf("TestRequestReply Expected no subscribe error, got [%v]\n", e)
+	}
+	rs := NewResponder(sconn, func(req Message) (Headers, []byte) {
+		return Headers{"svc", "echo"}, append([]byte("re:"), req.Body...)
+	})
+	se := make(chan error, 1)
+	go func() { se <- rs.Serve(sc) }()
+	//
+	n, _ = openConn(t)
+	conn, e = Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestRequestReply CONNECT Failed: e:<%q>\n", e)
+	}
+	rq, e := NewRequester(conn, "")
+	if e != nil {
+		t.Fatalf("TestRequestReply Expected no requester error, got [%v]\n", e)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cf := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cf()
+			b := "req" + strconv.Itoa(i)
+			m, e := rq.Request(ctx, Headers{HK_DESTINATION, d}, b)
+			if e != nil || string(m.Body) != "re:"+b || m.Headers.Value("svc") != "echo" {
+				errs <- "reply mismatch: " + b
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for s := range errs {
+		t.Fatalf("TestRequestReply %s\n", s)
+	}
+	//
+	if e = rs.Reply(Message{MESSAGE, Headers{}, nil}, Headers{}, nil); e != ENOREPLYTO {
+		t.Fatalf("TestRequestReply Expected [%v], got [%v]\n", ENOREPLYTO, e)
+	}
+	if e = rq.Close(); e != nil {
+		t.Fatalf("TestRequestReply Expected no close error, got [%v]\n", e)
+	}
+	if _, e = rq.Request(context.Background(), Headers{HK_DESTINATION, d}, "x"); e != EREQCLOSED {
+		t.Fatalf("TestRequestReply Expected [%v], got [%v]\n", EREQCLOSED, e)
+	}
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	_ = closeConn(t, n)
+	//
+	e = sconn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	if e = <-se; e != nil {
+		t.Fatalf("TestRequestReply Expected no serve error, got [%v]\n", e)
+	}
+	_ = closeConn(t, sn)
+}
+
+/*
+	Test a request with no responder.
+*/
+func TestRequestTimeout(t *testing.T) {
+	n, _ = openConn(t)
+	conn, e = Connect(n, headersProtocol(login_headers, SPL_11))
+	if e != nil {
+		t.Fatalf("TestRequestTimeout CONNECT Failed: e:<%q>\n", e)
+	}
+	rq, e := NewRequester(conn, tdest("/queue/rpc.replies."+Uuid()))
+	if e != nil {
+		t.Fatalf("TestRequestTimeout Expected no requester error, got [%v]\n", e)
+	}
+	ctx, cf := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cf()
+	_, e = rq.Request(ctx, Headers{HK_DESTINATION, tdest("/queue/rpc.nobody." + Uuid()),
+		HK_CORRELATION_ID, "c1"}, "hello")
+	if e != context.DeadlineExceeded {
+		t.Fatalf("TestRequestTimeout Expected [%v], got [%v]\n",
+			context.DeadlineExceeded, e)
+	}
+	// The connection ends, and so does the Requester
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	if _, e = rq.Request(context.Background(), Headers{HK_DESTINATION, "/queue/x"}, "x"); e == nil {
+		t.Fatalf("TestRequestTimeout Expected error after disconnect, got nil\n")
+	}
+	_ = closeConn(t, n)
+}
+
+/*
+	Test that errors about one message do not stop a Requester or Responder.
+*/
+func TestRequestMessageError(t *testing.T) {
+	veto := errors.New("vetoed")
+	vetoBody := func(body string) inIcpt {
+		return func(f *Frame) error {
+			if string(f.Body) == body {
+				return veto
+			}
+			return nil
+		}
+	}
+	b := fakebroker.New(nil)
+	defer b.Close()
+	sconn, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestRequestMessageError CONNECT Failed: e:<%q>\n", e)
+	}
+	conn, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestRequestMessageError CONNECT Failed: e:<%q>\n", e)
+	}
+	if e = sconn.Use(vetoBody("skip")); e != nil {
+		t.Fatalf("TestRequestMessageError Expected no Use error, got [%v]\n", e)
+	}
+	if e = conn.Use(vetoBody("re:fail")); e != nil {
+		t.Fatalf("TestRequestMessageError Expected no Use error, got [%v]\n", e)
+	}
+	d := tdest("/queue/rpc.msgerr")
+	sc, e := sconn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "rpcsvc"})
+	if e != nil {
+		t.Fatalf("TestRequestMessageError Expected no subscribe error, got [%v]\n", e)
+	}
+	rs := NewResponder(sconn, func(req Message) (Headers, []byte) {
+		return Headers{}, append([]byte("re:"), req.Body...)
+	})
+	se := make(chan error, 1)
+	go func() { se <- rs.Serve(sc) }()
+	rq, e := NewRequester(conn, "")
+	if e != nil {
+		t.Fatalf("TestRequestMessageError Expected no requester error, got [%v]\n", e)
+	}
+	for _, tv := range []struct {
+		b    string
+		want error
+	}{{"skip", context.DeadlineExceeded}, {"fail", veto}, {"ok", nil}} {
+		ctx, cf := context.WithTimeout(context.Background(), time.Second)
+		if tv.b == "skip" {
+			ctx, cf = context.WithTimeout(context.Background(), 100*time.Millisecond)
+		}
+		m, e := rq.Request(ctx, Headers{HK_DESTINATION, d}, tv.b)
+		cf()
+		if e != tv.want || e == nil && string(m.Body) != "re:"+tv.b {
+			t.Fatalf("TestRequestMessageError Expected [%v], got [%v] [%v]\n",
+				tv.want, e, string(m.Body))
+		}
+	}
+	_ = rq.Close()
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+	checkDisconnectError(t, sconn.Disconnect(empty_headers))
+	if e = <-se; e != nil {
+		t.Fatalf("TestRequestMessageError Expected no serve error, got [%v]\n", e)
+	}
+}
+
+/*
+	Test a temporary reply queue: no SUBSCRIBE is sent, and replies arrive
+	with the queue name as the subscription header.
+*/
+func TestRequestTempQueue(t *testing.T) {
+	const rt = "/temp-queue/replies"
+	var lock sync.Mutex
+	owner := map[string]*fakebroker.Session{} // Temporary queue owners
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			d, _ := f.Value(HK_DESTINATION)
+			switch f.Command {
+			case SUBSCRIBE, UNSUBSCRIBE:
+				if id, _ := f.Value(HK_ID); strings.HasPrefix(d, "/temp-queue/") ||
+					strings.HasPrefix(id, "/temp-queue/") {
+					t.Errorf("TestRequestTempQueue Unexpected [%v] [%v]\n",
+						f.Command, f.Headers)
+				}
+			case SEND:
+				lock.Lock()
+				defer lock.Unlock()
+				if r, ok := f.Value(HK_REPLY_TO); ok {
+					owner[r] = s
+				}
+				o, ok := owner[d]
+				if !ok {
+					return false
+				}
+				m := &fakebroker.Frame{Command: MESSAGE, Body: f.Body}
+				cid, _ := f.Value(HK_CORRELATION_ID)
+				m.Add(HK_DESTINATION, d).Add(HK_SUBSCRIPTION, d).
+					Add(HK_MESSAGE_ID, Uuid()).Add(HK_CORRELATION_ID, cid)
+				o.Send(m)
+				return true
+			}
+			return false
+		}})
+	defer b.Close()
+	sconn, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestRequestTempQueue CONNECT Failed: e:<%q>\n", e)
+	}
+	conn, e := Connect(b.Pipe(), headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestRequestTempQueue CONNECT Failed: e:<%q>\n", e)
+	}
+	d := tdest("/queue/rpc.tempq")
+	sc, e := sconn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "rpcsvc"})
+	if e != nil {
+		t.Fatalf("TestRequestTempQueue Expected no subscribe error, got [%v]\n", e)
+	}
+	rs := NewResponder(sconn, func(req Message) (Headers, []byte) {
+		return Headers{}, append([]byte("re:"), req.Body...)
+	})
+	go func() { _ = rs.Serve(sc) }()
+	rq, e := NewRequester(conn, rt)
+	if e != nil {
+		t.Fatalf("TestRequestTempQueue Expected no requester error, got [%v]\n", e)
+	}
+	if _, e = NewRequester(conn, rt); e != EDUPSID {
+		t.Fatalf("TestRequestTempQueue Expected [%v], got [%v]\n", EDUPSID, e)
+	}
+	for _, m := range []string{"one", "two"} {
+		ctx, cf := context.WithTimeout(context.Background(), 5*time.Second)
+		r, e := rq.Request(ctx, Headers{HK_DESTINATION, d}, m)
+		cf()
+		if e != nil || string(r.Body) != "re:"+m {
+			t.Fatalf("TestRequestTempQueue Expected [re:%v], got [%v] [%v]\n", m,
+				string(r.Body), e)
+		}
+	}
+	if e = rq.Close(); e != nil {
+		t.Fatalf("TestRequestTempQueue Expected no close error, got [%v]\n", e)
+	}
+	if conn.hasSubscription(rt) {
+		t.Fatalf("TestRequestTempQueue Expected no subscription after close\n")
+	}
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+	checkDisconnectError(t, sconn.Disconnect(empty_headers))
+}
@@ -16,6 +16,10 @@
 
 package stompngo
 
+import (
+	"context"
+)
+
 /*
 	Send a STOMP MESSAGE.
 
@@ -33,24 +37,35 @@ package stompngo
 
 */
 func (c *Connection) Send(h Headers, b string) error {
-	c.log(SEND, "start", h)
+	return c.SendContext(context.Background(), h, b)
+}
+
+/*
+	SendContext is Send, giving up when ctx is done.  See ContextStomper.
+*/
+func (c *Connection) SendContext(ctx context.Context, h Headers, b string) (e error) {
+	defer func() { e = newOpError("Send", SEND, h, e) }()
+	if c.logs(LogDebug) {
+		c.debug("SEND start", "headers", h)
+	}
 	if !c.isConnected() {
 		return ECONBAD
 	}
-	e := checkHeaders(h, c.Protocol())
+	e = checkHeaders(h, c.Protocol())
 	if e != nil {
 		return e
 	}
 	if _, ok := h.Contains(HK_DESTINATION); !ok {
 		return EREQDSTSND
 	}
-	ch := h.Clone()
-	f := Frame{SEND, ch, []uint8(b)}
-	r := make(chan error)
-	if e = c.writeWireData(wiredata{f, r}); e != nil {
+	f := Frame{SEND, h, []uint8(b)}
+	r, e := c.writeWireDataContext(ctx, f)
+	if e != nil {
 		return e
 	}
-	e = <-r
-	c.log(SEND, "end", ch)
+	e = c.awaitWrite(ctx, r)
+	if c.logs(LogDebug) {
+		c.debug("SEND end", "headers", h)
+	}
 	return e // nil or not
 }
@@ -0,0 +1,198 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+/*
+	SendFullPolicy is what SendAsync does when the asynchronous send buffer
+	is full.
+*/
+type SendFullPolicy int
+
+const (
+	// Wait for room in the buffer.
+	SendBlock SendFullPolicy = iota
+	// Fail the new send with ESENDFULL.
+	SendFailFast
+	// Fail the oldest buffered send with ESENDDROP, and buffer the new one.
+	SendDropOldest
+)
+
+/*
+	Default size of the asynchronous send buffer.
+*/
+const DefaultSendBuffer = 256
+
+/*
+	SendAsync sends a STOMP MESSAGE without waiting for the write.
+
+	The frame is put in a bounded buffer, and SendAsync returns at once.  The
+	returned channel gets exactly one value: nil once the frame is written, or
+	the error.  A full buffer is handled as ConnectOptions.SendFullPolicy
+	says.  Frames still buffered when the connection ends fail with ECONBAD.
+
+	Frames from one goroutine are written in order.  There is no order with
+	frames sent by Send and the other synchronous methods.
+
+	The headers and body are not copied.  Do not change them until the result
+	arrives.
+
+	Example:
+		h := stompngo.Headers{stompngo.HK_DESTINATION, "/queue/mymessages"}
+		r := c.SendAsync(h, "My message")
+		// Do other work ...
+		if e := <-r; e != nil {
+			// Do something sane ...
+		}
+*/
+func (c *Connection) SendAsync(h Headers, b string) <-chan error {
+	return c.sendAsync("SendAsync", h, []uint8(b))
+}
+
+/*
+	SendBytesAsync is SendAsync with a body that is a slice of bytes.
+*/
+func (c *Connection) SendBytesAsync(h Headers, b []byte) <-chan error {
+	return c.sendAsync("SendBytesAsync", h, b)
+}
+
+func (c *Connection) sendAsync(op string, h Headers, b []byte) <-chan error {
+	r := make(chan error, 1)
+	if c.logs(LogDebug) {
+		c.debug("SEND async start", "headers", h)
+	}
+	var e error
+	if !c.isConnected() {
+		e = ECONBAD
+	} else if e = checkHeaders(h, c.Protocol()); e == nil {
+		if _, ok := h.Contains(HK_DESTINATION); !ok {
+			e = EREQDSTSND
+		}
+	}
+	if e != nil {
+		r <- newOpError(op, SEND, h, e)
+		return r
+	}
+	f, e := c.sealFrame(Frame{SEND, h, b})
+	if e != nil {
+		r <- newOpError(op, SEND, h, e)
+		return r
+	}
+	c.aonce.Do(c.startAsync)
+	d := wiredata{frame: f, errchan: r}
+	if e = c.bufferAsync(d); e != nil {
+		r <- newOpError(op, SEND, h, e)
+	}
+	return r
+}
+
+/*
+	Put a frame in the asynchronous send buffer, as the full buffer policy
+	says.
+*/
+func (c *Connection) bufferAsync(d wiredata) error {
+	c.alock.RLock()
+	defer c.alock.RUnlock()
+	if c.aclosed {
+		return ECONBAD
+	}
+	switch c.apol {
+	case SendFailFast:
+		select {
+		case c.abuf <- d:
+		default:
+			return ESENDFULL
+		}
+	case SendDropOldest:
+		for {
+			select {
+			case c.abuf <- d:
+				return nil
+			default:
+			}
+			select {
+			case o := <-c.abuf:
+				failWrite(o, ESENDDROP)
+			default:
+			}
+		}
+	default:
+		select {
+		case c.abuf <- d:
+		case <-c.ssdc:
+			return ECONBAD
+		}
+	}
+	return nil
+}
+
+/*
+	Start the asynchronous send pump.
+*/
+func (c *Connection) startAsync() {
+	if c.asize <= 0 {
+		c.asize = DefaultSendBuffer
+	}
+	c.abuf = make(chan wiredata, c.asize)
+	go c.asyncPump()
+}
+
+/*
+	Hand buffered asynchronous frames to the writer, in order.  When the
+	writer ends, every frame not yet written fails with ECONBAD.
+*/
+func (c *Connection) asyncPump() {
+pumpLoop:
+	for {
+		select {
+		case d := <-c.abuf:
+			select {
+			case c.output <- d:
+			case <-c.wtrdc:
+				failWrite(d, ECONBAD)
+				break pumpLoop
+			}
+		case <-c.wtrdc:
+			break pumpLoop
+		}
+	}
+	// Senders still waiting for room leave on ssdc, which is closed first
+	c.alock.Lock()
+	c.aclosed = true
+	c.alock.Unlock()
+	for {
+		select {
+		case d := <-c.abuf:
+			failWrite(d, ECONBAD)
+		case d := <-c.output: // Taken by no writer
+			failWrite(d, ECONBAD)
+		default:
+			c.debug("ASYNC_SHUTDOWN")
+			return
+		}
+	}
+}
+
+/*
+	Report a write failure without blocking.  Every result channel holds one
+	value.
+*/
+func failWrite(d wiredata, e error) {
+	select {
+	case d.errchan <- e:
+	default:
+	}
+}
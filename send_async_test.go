@@ -0,0 +1,163 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	A broker that stops reading at a SEND with a "stall" body until release
+	is closed, or closes the session when close is true.
+*/
+func stallBroker(release chan struct{}, close bool) *fakebroker.Broker {
+	return fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if f.Command == SEND && string(f.Body) == "stall" {
+				<-release
+				if close {
+					s.Close()
+					time.Sleep(50 * time.Millisecond) // Read nothing more
+				}
+				return true
+			}
+			return false
+		}})
+}
+
+/*
+	Test that asynchronous sends are all written.
+*/
+func TestSendAsync(t *testing.T) {
+	b := fakebroker.New(nil)
+	defer b.Close()
+	conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+		SPL_12), &ConnectOptions{SendBuffer: 8})
+	if e != nil {
+		t.Fatalf("TestSendAsync CONNECT Failed: e:<%q>\n", e)
+	}
+	d := tdest("/queue/send.async")
+	if e = <-conn.SendAsync(Headers{}, "x"); !errors.Is(e, EREQDSTSND) {
+		t.Fatalf("TestSendAsync Expected [%v], got [%v]\n", EREQDSTSND, e)
+	}
+	rs := make([]<-chan error, 0, 100)
+	for i := 0; i < 100; i++ {
+		rs = append(rs, conn.SendBytesAsync(Headers{HK_DESTINATION, d},
+			[]byte(fmt.Sprintf("message %d", i))))
+	}
+	for _, r := range rs {
+		if e = <-r; e != nil {
+			t.Fatalf("TestSendAsync Expected no send error, got [%v]\n", e)
+		}
+	}
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	if qd := b.QueueDepth(d); qd != 100 {
+		t.Fatalf("TestSendAsync Expected [%d] messages, got [%d]\n", 100, qd)
+	}
+	if e = <-conn.SendAsync(Headers{HK_DESTINATION, d}, "x"); !errors.Is(e,
+		ECONBAD) {
+		t.Fatalf("TestSendAsync Expected [%v], got [%v]\n", ECONBAD, e)
+	}
+}
+
+/*
+	Test the full buffer policies.
+*/
+func TestSendAsyncFull(t *testing.T) {
+	big := strings.Repeat("full", 16*1024) // More than the broker buffers
+	for _, tv := range []struct {
+		pol  SendFullPolicy
+		want error
+	}{
+		{SendFailFast, ESENDFULL},
+		{SendDropOldest, ESENDDROP},
+	} {
+		release := make(chan struct{})
+		b := stallBroker(release, false)
+		conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+			SPL_12), &ConnectOptions{SendBuffer: 2, SendFullPolicy: tv.pol})
+		if e != nil {
+			t.Fatalf("TestSendAsyncFull CONNECT Failed: e:<%q>\n", e)
+		}
+		h := Headers{HK_DESTINATION, tdest("/queue/send.async.full")}
+		if e = <-conn.SendAsync(h, "stall"); e != nil {
+			t.Fatalf("TestSendAsyncFull Expected no send error, got [%v]\n", e)
+		}
+		// Sends only fail once the writer, the pump and the buffer are all busy
+		var rs []<-chan error
+		got := 0
+		for i := 0; i < 20 && got == 0; i++ {
+			rs = append(rs, conn.SendAsync(h, big))
+			for _, r := range rs {
+				select {
+				case e = <-r:
+					if !errors.Is(e, tv.want) {
+						t.Fatalf("TestSendAsyncFull Expected [%v], got [%v]\n",
+							tv.want, e)
+					}
+					got++
+				default:
+				}
+			}
+		}
+		if got != 1 {
+			t.Fatalf("TestSendAsyncFull Expected one [%v], got [%d]\n", tv.want,
+				got)
+		}
+		close(release)
+		e = conn.Disconnect(empty_headers)
+		checkDisconnectError(t, e)
+		_ = b.Close()
+	}
+}
+
+/*
+	Test that buffered asynchronous sends fail when the connection ends.
+*/
+func TestSendAsyncShutdown(t *testing.T) {
+	release := make(chan struct{})
+	b := stallBroker(release, true)
+	defer b.Close()
+	conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+		SPL_12), &ConnectOptions{SendBuffer: 4})
+	if e != nil {
+		t.Fatalf("TestSendAsyncShutdown CONNECT Failed: e:<%q>\n", e)
+	}
+	h := Headers{HK_DESTINATION, tdest("/queue/send.async.shutdown")}
+	if e = <-conn.SendAsync(h, "stall"); e != nil {
+		t.Fatalf("TestSendAsyncShutdown Expected no send error, got [%v]\n", e)
+	}
+	big := strings.Repeat("shutdown", 8*1024)
+	var rs []<-chan error
+	for i := 0; i < 6; i++ {
+		rs = append(rs, conn.SendAsync(h, big))
+	}
+	close(release)
+	for _, r := range rs {
+		if e = <-r; e == nil {
+			t.Fatalf("TestSendAsyncShutdown Expected a send error\n")
+		}
+	}
+	<-conn.rdrdc
+}
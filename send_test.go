@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -45,7 +46,7 @@ func TestSendBasic(t *testing.T) {
 		if e == nil {
 			t.Fatalf("TestSendBasic Expected error, got [nil]\n")
 		}
-		if e != EREQDSTSND {
+		if !errors.Is(e, EREQDSTSND) {
 			t.Fatalf("TestSendBasic Expected [%v], got [%v]\n", EREQDSTSND, e)
 		}
 		checkReceived(t, conn, false)
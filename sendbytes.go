@@ -16,6 +16,10 @@
 
 package stompngo
 
+import (
+	"context"
+)
+
 /*
 	Send a STOMP MESSAGE.
 
@@ -33,24 +37,35 @@ package stompngo
 
 */
 func (c *Connection) SendBytes(h Headers, b []byte) error {
-	c.log(SEND, "start", h)
+	return c.SendBytesContext(context.Background(), h, b)
+}
+
+/*
+	SendBytesContext is SendBytes, giving up when ctx is done.  See ContextStomper.
+*/
+func (c *Connection) SendBytesContext(ctx context.Context, h Headers, b []byte) (e error) {
+	defer func() { e = newOpError("SendBytes", SEND, h, e) }()
+	if c.logs(LogDebug) {
+		c.debug("SEND start", "headers", h)
+	}
 	if !c.isConnected() {
 		return ECONBAD
 	}
-	e := checkHeaders(h, c.Protocol())
+	e = checkHeaders(h, c.Protocol())
 	if e != nil {
 		return e
 	}
 	if _, ok := h.Contains(HK_DESTINATION); !ok {
 		return EREQDSTSND
 	}
-	ch := h.Clone()
-	f := Frame{SEND, ch, b}
-	r := make(chan error)
-	if e = c.writeWireData(wiredata{f, r}); e != nil {
+	f := Frame{SEND, h, b}
+	r, e := c.writeWireDataContext(ctx, f)
+	if e != nil {
 		return e
 	}
-	e = <-r
-	c.log(SEND, "end", ch)
+	e = c.awaitWrite(ctx, r)
+	if c.logs(LogDebug) {
+		c.debug("SEND end", "headers", h)
+	}
 	return e // nil or not
 }
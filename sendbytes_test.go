@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -46,7 +47,7 @@ func TestSendBytesBasic(t *testing.T) {
 		if e == nil {
 			t.Fatalf("TestSendBytesBasic Expected error, got [nil]\n")
 		}
-		if e != EREQDSTSND {
+		if !errors.Is(e, EREQDSTSND) {
 			t.Fatalf("TestSendBytesBasic Expected [%v], got [%v]\n", EREQDSTSND, e)
 		}
 		checkReceived(t, conn, false)
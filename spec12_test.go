@@ -0,0 +1,102 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+/*
+	Read one frame from s, at protocol level p.
+*/
+func specFrame(s, p string) (Frame, error) {
+	c := &Connection{rdr: bufio.NewReader(strings.NewReader(s)),
+		dld: &deadlineData{}, flim: FrameLimits{}.resolve(), protocol: p}
+	return c.readFrame()
+}
+
+/*
+	Test the STOMP 1.2 specification examples for line ends, repeated
+	headers and header escapes.
+*/
+func TestSpec12Frames(t *testing.T) {
+	for i, tv := range []struct {
+		p, frame string
+		want     Headers
+		body     string
+	}{
+		// "Augmented BNF": lines may end with CRLF
+		{SPL_12, "MESSAGE\r\nsubscription:0\r\nmessage-id:007\r\n" +
+			"destination:/queue/a\r\ncontent-type:text/plain\r\n\r\n" +
+			"hello queue a\x00",
+			Headers{HK_SUBSCRIPTION, "0", HK_MESSAGE_ID, "007",
+				HK_DESTINATION, "/queue/a", HK_CONTENT_TYPE, "text/plain"},
+			"hello queue a"},
+		// "Repeated Header Entries": the first one wins, the rest are history
+		{SPL_12, "MESSAGE\nsubscription:0\nmessage-id:1\nfoo:World\n" +
+			"foo:Hello\n\n\x00",
+			Headers{HK_SUBSCRIPTION, "0", HK_MESSAGE_ID, "1", "foo", "World",
+				"foo", "Hello"},
+			""},
+		// "Value Encoding": \r \n \c \\, decoded in one pass
+		{SPL_12, "MESSAGE\r\nsubscription:0\r\nmessage-id:2\r\n" +
+			"a\\cb:c\\\\n\\r\\n\\c\\\\\r\n\r\n\x00",
+			Headers{HK_SUBSCRIPTION, "0", HK_MESSAGE_ID, "2",
+				"a:b", "c\\n\r\n:\\"},
+			""},
+		// Undefined escapes are only fatal for 1.2
+		{SPL_11, "MESSAGE\nsubscription:0\nmessage-id:3\nk:a\\tb\n\n\x00",
+			Headers{HK_SUBSCRIPTION, "0", HK_MESSAGE_ID, "3", "k", "a\\tb"},
+			""},
+	} {
+		f, e := specFrame(tv.frame, tv.p)
+		if e != nil {
+			t.Fatalf("TestSpec12Frames[%d] Expected no error, got [%v]\n", i, e)
+		}
+		if f.Command != MESSAGE || !f.Headers.Compare(tv.want) ||
+			string(f.Body) != tv.body {
+			t.Fatalf("TestSpec12Frames[%d] Expected [%q] [%q], got [%q] [%q]\n", i,
+				tv.want, tv.body, f.Headers, f.Body)
+		}
+	}
+	// Repeated keys: Value and Contains give the first value
+	f, _ := specFrame("MESSAGE\nsubscription:0\nfoo:World\nfoo:Hello\n\n\x00",
+		SPL_12)
+	if v, ok := f.Headers.Contains("foo"); !ok || v != "World" ||
+		f.Headers.Value("foo") != "World" {
+		t.Fatalf("TestSpec12Frames Expected first value, got [%q]\n", f.Headers)
+	}
+	if h := f.Headers.DeleteAll("foo").Add("foo", "Bye"); h.Value("foo") != "Bye" {
+		t.Fatalf("TestSpec12Frames Expected replaced value, got [%q]\n", h)
+	}
+	// A heart-beat may be CRLF
+	if f, e := specFrame("\r\n", SPL_12); e != nil || f.Command != "" {
+		t.Fatalf("TestSpec12Frames Expected heart-beat, got [%q] [%v]\n", f, e)
+	}
+	// "Undefined escape sequences such as \t MUST be treated as a fatal
+	// protocol error."
+	for _, s := range []string{"k:a\\tb", "k\\t:v", "k:v\\"} {
+		_, e := specFrame("MESSAGE\nsubscription:0\n"+s+"\n\n\x00", SPL_12)
+		if !errors.Is(e, EHDRESC) {
+			t.Fatalf("TestSpec12Frames Expected [%v] for [%q], got [%v]\n", EHDRESC,
+				s, e)
+		}
+	}
+}
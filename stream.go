@@ -0,0 +1,241 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Bytes copied per write deadline when streaming a body
+const streamChunk = 64 * 1024
+
+/*
+	SendReader sends a STOMP MESSAGE with a body of exactly length bytes read
+	from r.  The body is copied straight to the network writer, and is never
+	held in memory.  Over a WebSocketConn a long body is sent as a fragmented
+	WebSocket message.
+
+	Headers MUST contain a "destination" header key.  A content-length header
+	of length is always sent.
+
+	If r fails or ends early, the frame can not be completed, and the
+	connection is closed.
+
+	Example:
+		f, e := os.Open("big.dat")
+		if e != nil {
+			// Do something sane ...
+		}
+		fi, _ := f.Stat()
+		h := stompngo.Headers{stompngo.HK_DESTINATION, "/queue/files"}
+		e = c.SendReader(h, f, fi.Size())
+		if e != nil {
+			// Do something sane ...
+		}
+*/
+func (c *Connection) SendReader(h Headers, r io.Reader, length int64) error {
+	return c.SendReaderContext(context.Background(), h, r, length)
+}
+
+/*
+	SendReaderContext is SendReader, giving up when ctx is done.  If ctx is
+	done after the frame reached the writer, r is still read until the frame
+	is written.
+*/
+func (c *Connection) SendReaderContext(ctx context.Context, h Headers, r io.Reader, length int64) (e error) {
+	defer func() { e = newOpError("SendReader", SEND, h, e) }()
+	if c.logs(LogDebug) {
+		c.debug("SEND start reader", "headers", h, "length", length)
+	}
+	if !c.isConnected() {
+		return ECONBAD
+	}
+	e = checkHeaders(h, c.Protocol())
+	if e != nil {
+		return e
+	}
+	if _, ok := h.Contains(HK_DESTINATION); !ok {
+		return EREQDSTSND
+	}
+	if r == nil || length < 0 {
+		return ESTRMLEN
+	}
+	if c.cry.active() {
+		return ECRYPTBODY
+	}
+	ch := h.DeleteAll(HK_CONTENT_LENGTH).DeleteAll(HK_SUPPRESS_CL)
+	ch = ch.Add(HK_CONTENT_LENGTH, strconv.FormatInt(length, 10))
+	rc, e := c.queueWireData(ctx, wiredata{frame: Frame{SEND, ch, NULLBUFF},
+		body: r, bodylen: length})
+	if e != nil {
+		return e
+	}
+	e = c.awaitWrite(ctx, rc)
+	if c.logs(LogDebug) {
+		c.debug("SEND end reader", "headers", ch)
+	}
+	return e // nil or not
+}
+
+/*
+	Copy a streamed body to the network writer.
+*/
+func (c *Connection) writeBodyFrom(r io.Reader, n int64) error {
+	for n > 0 {
+		if c.dld.wde && c.dld.wds {
+			_ = c.netconn.SetWriteDeadline(time.Now().Add(c.dld.wdld))
+		}
+		l := n
+		if l > streamChunk {
+			l = streamChunk
+		}
+		k, e := io.CopyN(c.wtr, r, l)
+		n -= k
+		if e == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+/*
+	A MESSAGE body streamed from the connection.  The reader goroutine waits
+	until it is consumed or closed.
+*/
+type bodyStream struct {
+	c    *Connection
+	lock sync.Mutex
+	n    int64         // Bytes left
+	err  error         // Read error, ends the connection
+	done chan struct{} // Closed when the body and its NUL are consumed
+}
+
+/*
+	The stream for a MESSAGE body of l bytes, or nil if the subscription does
+	not stream bodies that large.
+*/
+func (c *Connection) streamFor(h Headers, l int) *bodyStream {
+	sid, ok := h.Contains(HK_SUBSCRIPTION)
+	if !ok {
+		return nil
+	}
+	c.subsLock.RLock()
+	ps, ok := c.subs[sid]
+	c.subsLock.RUnlock()
+	if !ok || ps.sbt <= 0 || int64(l) < ps.sbt {
+		return nil
+	}
+	return &bodyStream{c: c, n: int64(l), done: make(chan struct{})}
+}
+
+/*
+	Read reads the MESSAGE body from the network connection.
+*/
+func (s *bodyStream) Read(b []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.n == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > s.n {
+		b = b[:s.n]
+	}
+	s.c.setReadDeadline()
+	n, e := s.c.rdr.Read(b)
+	s.n -= int64(n)
+	if n > 0 && s.c.hbd != nil {
+		s.c.updateHBReads()
+	}
+	if e == io.EOF {
+		e = io.ErrUnexpectedEOF
+	}
+	if e != nil {
+		s.finish(s.c.checkReadError(e))
+		return n, e
+	}
+	if s.n == 0 {
+		_, e = s.c.rdr.ReadByte() // trailing NUL
+		s.finish(s.c.checkReadError(e))
+	}
+	return n, nil
+}
+
+/*
+	Close discards any unread part of the body, and lets the connection
+	read the next frame.
+*/
+func (s *bodyStream) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil || s.n == 0 {
+		return nil
+	}
+	s.c.setReadDeadline()
+	k, e := io.CopyN(ioutil.Discard, s.c.rdr, s.n)
+	s.n -= k
+	if e == nil {
+		_, e = s.c.rdr.ReadByte() // trailing NUL
+	}
+	if e == io.EOF {
+		e = io.ErrUnexpectedEOF
+	}
+	s.finish(s.c.checkReadError(e))
+	return nil
+}
+
+/*
+	End the stream.  Called with the lock held.
+*/
+func (s *bodyStream) finish(e error) {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	s.err = e
+	if e == nil {
+		s.err = io.EOF
+	}
+	close(s.done)
+}
+
+/*
+	Wait until the body is consumed or closed, or the connection is shut
+	down.  Returns the read error that ends the connection, if any.
+*/
+func (s *bodyStream) wait() error {
+	select {
+	case <-s.done:
+	case <-s.c.ssdc:
+		return nil // The reader sees the shutdown next
+	}
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
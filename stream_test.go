@@ -0,0 +1,112 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+/*
+	Test SendReader, and streamed bodies on a subscription.
+*/
+func TestStreamBodies(t *testing.T) {
+	n, _ = openConn(t)
+	conn, e = Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestStreamBodies CONNECT Failed: e:<%q>\n", e)
+	}
+	d := tdest("/queue/stream.bodies")
+	if _, e = conn.Subscribe(Headers{HK_DESTINATION, d,
+		StompPlusStreamBody, "x"}); !errors.Is(e, ESTRMSIZE) {
+		t.Fatalf("TestStreamBodies Expected [%v], got [%v]\n", ESTRMSIZE, e)
+	}
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "sbid",
+		StompPlusStreamBody, "1024"})
+	if e != nil {
+		t.Fatalf("TestStreamBodies Expected no subscribe error, got [%v]\n", e)
+	}
+	big := bytes.Repeat([]byte("stream\x00"), 150000) // NULs need content-length
+	for _, b := range [][]byte{big, []byte("small"), big, []byte("after")} {
+		e = conn.SendReader(Headers{HK_DESTINATION, d, HK_CONTENT_LENGTH, "1"},
+			bytes.NewReader(b), int64(len(b)))
+		if e != nil {
+			t.Fatalf("TestStreamBodies Expected no send error, got [%v]\n", e)
+		}
+	}
+	// Consumed
+	md := <-sc
+	if md.Error != nil || md.BodyReader == nil || len(md.Message.Body) != 0 {
+		t.Fatalf("TestStreamBodies Expected body stream, got [%v] [%v]\n",
+			md.Error, md.BodyReader)
+	}
+	b, e := ioutil.ReadAll(md.BodyReader)
+	if e != nil || !bytes.Equal(b, big) {
+		t.Fatalf("TestStreamBodies Expected [%d] bytes, got [%d] [%v]\n", len(big),
+			len(b), e)
+	}
+	// Small enough to be read as usual
+	md = <-sc
+	if md.BodyReader != nil || md.Message.BodyString() != "small" {
+		t.Fatalf("TestStreamBodies Expected small body, got [%q]\n",
+			md.Message.Body)
+	}
+	// Closed after a partial read
+	md = <-sc
+	if _, e = io.ReadFull(md.BodyReader, make([]byte, 10)); e != nil {
+		t.Fatalf("TestStreamBodies Expected no read error, got [%v]\n", e)
+	}
+	_ = md.BodyReader.Close()
+	md = <-sc
+	if md.BodyReader != nil || md.Message.BodyString() != "after" {
+		t.Fatalf("TestStreamBodies Expected body after close, got [%q]\n",
+			md.Message.Body)
+	}
+	//
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	_ = closeConn(t, n)
+}
+
+/*
+	Test that a SendReader body that ends early closes the connection.
+*/
+func TestStreamShortReader(t *testing.T) {
+	n, _ = openConn(t)
+	conn, e = Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestStreamShortReader CONNECT Failed: e:<%q>\n", e)
+	}
+	h := Headers{HK_DESTINATION, tdest("/queue/stream.short")}
+	if e = conn.SendReader(h, nil, 1); !errors.Is(e, ESTRMLEN) {
+		t.Fatalf("TestStreamShortReader Expected [%v], got [%v]\n", ESTRMLEN, e)
+	}
+	e = conn.SendReader(h, strings.NewReader("short"), 10)
+	if !errors.Is(e, io.ErrUnexpectedEOF) {
+		t.Fatalf("TestStreamShortReader Expected [%v], got [%v]\n",
+			io.ErrUnexpectedEOF, e)
+	}
+	<-conn.rdrdc
+	if conn.Connected() {
+		t.Fatalf("TestStreamShortReader Expected connection end\n")
+	}
+	_ = n.Close() // Already closed by the writer
+}
@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	//"os"
@@ -41,7 +42,7 @@ func TestSubNoHeader(t *testing.T) {
 			t.Fatalf("TestSubNoHeader[%d] proto:%s expected:%v got:nil\n",
 				ti, tv.proto, tv.exe)
 		}
-		if e != tv.exe {
+		if !errors.Is(e, tv.exe) {
 			t.Fatalf("TestSubNoHeader[%d] proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe, e)
 		}
@@ -67,7 +68,7 @@ func TestSubNoID(t *testing.T) {
 		conn.protocol = tv.proto // Cheat, fake all protocols
 		ud := tdest(tv.subh.Value(HK_DESTINATION))
 		_, e = conn.Subscribe(Headers{HK_DESTINATION, ud})
-		if e != tv.exe {
+		if !errors.Is(e, tv.exe) {
 			t.Fatalf("TestSubNoID[%d] proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe, e)
 		}
@@ -97,7 +98,7 @@ func TestSubPlain(t *testing.T) {
 			t.Fatalf("TestSubPlain[%d] SUBSCRIBE, proto:[%s], channel is nil\n",
 				ti, tv.proto)
 		}
-		if e != tv.exe1 {
+		if !errors.Is(e, tv.exe1) {
 			t.Fatalf("TestSubPlain[%d] SUBSCRIBE, proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe1, e)
 		}
@@ -105,7 +106,7 @@ func TestSubPlain(t *testing.T) {
 		// UNSUBSCRIBE Phase
 		sh = fixHeaderDest(tv.unsubh) // destination fixed if needed
 		e = conn.Unsubscribe(sh)
-		if e != tv.exe2 {
+		if !errors.Is(e, tv.exe2) {
 			t.Fatalf("TestSubPlain[%d] UNSUBSCRIBE, proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe2, e)
 		}
@@ -136,14 +137,14 @@ func TestSubNoTwice(t *testing.T) {
 			t.Fatalf("TestSubNoTwice[%d] SUBSCRIBE1, proto:[%s], channel is nil\n",
 				ti, tv.proto)
 		}
-		if e != tv.exe1 {
+		if !errors.Is(e, tv.exe1) {
 			t.Fatalf("TestSubNoTwice[%d] SUBSCRIBE1, proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe1, e)
 		}
 
 		// SUBSCRIBE Phase 2
 		sc, e = conn.Subscribe(sh)
-		if e != tv.exe2 {
+		if !errors.Is(e, tv.exe2) {
 			t.Fatalf("TestSubNoTwice[%d] SUBSCRIBE2, proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe2, e)
 		}
@@ -183,7 +184,7 @@ func TestSubRoundTrip(t *testing.T) {
 			t.Fatalf("TestSubRoundTrip[%d] SUBSCRIBE, proto:[%s], channel is nil\n",
 				ti, tv.proto)
 		}
-		if e != tv.exe1 {
+		if !errors.Is(e, tv.exe1) {
 			t.Fatalf("TestSubRoundTrip[%d] SUBSCRIBE, proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe1, e)
 		}
@@ -198,7 +199,7 @@ func TestSubRoundTrip(t *testing.T) {
 
 		// UNSUBSCRIBE Phase
 		e = conn.Unsubscribe(sh)
-		if e != tv.exe2 {
+		if !errors.Is(e, tv.exe2) {
 			t.Fatalf("TestSubRoundTrip[%d] UNSUBSCRIBE, proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe2, e)
 		}
@@ -231,7 +232,7 @@ func TestSubAckModes(t *testing.T) {
 					ti, tv.proto)
 			}
 		}
-		if e != tv.exe {
+		if !errors.Is(e, tv.exe) {
 			t.Fatalf("TestSubAckModes[%d] SUBSCRIBE, proto:%s expected:%v got:%v\n",
 				ti, tv.proto, tv.exe, e)
 		}
@@ -17,8 +17,8 @@
 package stompngo
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"strconv"
 )
 
@@ -56,7 +56,36 @@ var _ = fmt.Println
 
 */
 func (c *Connection) Subscribe(h Headers) (<-chan MessageData, error) {
-	c.log(SUBSCRIBE, "start", h, c.Protocol())
+	return c.SubscribeContext(context.Background(), h)
+}
+
+/*
+	SubscribeContext is Subscribe, giving up when ctx is done.  See
+	ContextStomper.
+
+	When ctx is done the subscription is removed.  If the SUBSCRIBE frame
+	was already on its way to the broker, an UNSUBSCRIBE follows it.
+*/
+func (c *Connection) SubscribeContext(ctx context.Context, h Headers) (s <-chan MessageData, e error) {
+	defer func() { e = newOpError("Subscribe", SUBSCRIBE, h, e) }()
+	return c.subscribe(ctx, h, c.cry)
+}
+
+/*
+	SubscribeCrypto is Subscribe, with its own message crypto.  MESSAGEs are
+	verified and decrypted with cr in place of ConnectOptions.Crypto.  A nil
+	cr means no crypto for this subscription.  See Crypto.
+*/
+func (c *Connection) SubscribeCrypto(h Headers, cr *Crypto) (s <-chan MessageData, e error) {
+	defer func() { e = newOpError("Subscribe", SUBSCRIBE, h, e) }()
+	if cr != nil && cr.Keys == nil {
+		return nil, ENOKEY
+	}
+	return c.subscribe(context.Background(), h, cr)
+}
+
+func (c *Connection) subscribe(ctx context.Context, h Headers, cr *Crypto) (<-chan MessageData, error) {
+	c.debug("SUBSCRIBE start", "headers", h)
 	if !c.isConnected() {
 		return nil, ECONBAD
 	}
@@ -68,26 +97,65 @@ func (c *Connection) Subscribe(h Headers) (<-chan MessageData, error) {
 	if e != nil {
 		return nil, e
 	}
+	if _, ok := h.Contains(StompPlusStreamBody); ok && cr.active() {
+		return nil, ECRYPTBODY
+	}
 	ch := h.Clone()
 	if _, ok := ch.Contains(HK_ACK); !ok {
 		ch = append(ch, HK_ACK, AckModeAuto)
 	}
-	sub, e, ch := c.establishSubscription(ch)
+	sub, e, ch := c.establishSubscription(ch, cr)
 	if e != nil {
 		return nil, e
 	}
 	//
 	f := Frame{SUBSCRIBE, ch, NULLBUFF}
 	//
-	r := make(chan error)
-	if e = c.writeWireData(wiredata{f, r}); e != nil {
+	r, e := c.writeWireDataContext(ctx, f)
+	if e != nil {
+		c.dropSubscription(sub.id)
+		return nil, e
+	}
+	e = c.awaitWrite(ctx, r)
+	if e != nil && e == ctx.Err() {
+		c.dropSubscription(sub.id)
+		go c.abandonSubscription(r, sub.id)
 		return nil, e
 	}
-	e = <-r
-	c.log(SUBSCRIBE, "end", ch, c.Protocol())
+	c.debug("SUBSCRIBE end", "headers", ch)
 	return sub.md, e
 }
 
+/*
+	Check for an active subscription.
+*/
+func (c *Connection) hasSubscription(id string) bool {
+	c.subsLock.RLock()
+	defer c.subsLock.RUnlock()
+	_, ok := c.subs[id]
+	return ok
+}
+
+/*
+	Remove a subscription that was never completed.
+*/
+func (c *Connection) dropSubscription(id string) {
+	c.subsLock.Lock()
+	delete(c.subs, id)
+	c.subsLock.Unlock()
+}
+
+/*
+	Undo a SUBSCRIBE that was abandoned after it was queued for writing.
+*/
+func (c *Connection) abandonSubscription(r chan error, id string) {
+	if e := <-r; e != nil {
+		return
+	}
+	c.debug("SUBSCRIBE abandoned", "id", id)
+	_ = c.transmitCommon(UNSUBSCRIBE, Headers{HK_ID, id})
+}
+
 /*
 	Check SUBSCRIBE specific requirements.
 */
@@ -96,6 +164,11 @@ func (c *Connection) checkSubscribeHeaders(h Headers) error {
 		return EREQDSTSUB
 	}
 	//
+	if sb, ok := h.Contains(StompPlusStreamBody); ok {
+		if n, e := strconv.ParseInt(sb, 10, 64); e != nil || n < 0 {
+			return ESTRMSIZE
+		}
+	}
 	am, ok := h.Contains(HK_ACK)
 	//
 	switch c.Protocol() {
@@ -114,7 +187,7 @@ func (c *Connection) checkSubscribeHeaders(h Headers) error {
 			}
 		}
 	default:
-		log.Fatalf("Internal protocol level error:<%s>\n", c.Protocol())
+		return c.protocolLevelError(SUBSCRIBE)
 	}
 	return nil
 }
@@ -122,9 +195,9 @@ func (c *Connection) checkSubscribeHeaders(h Headers) error {
 /*
 	Handle subscribe id.
 */
-func (c *Connection) establishSubscription(h Headers) (*subscription, error, Headers) {
-	c.log(SUBSCRIBE, "start establishSubscription")
-	defer c.log(SUBSCRIBE, "end establishSubscription")
+func (c *Connection) establishSubscription(h Headers, cr *Crypto) (*subscription, error, Headers) {
+	c.debug("SUBSCRIBE start establishSubscription")
+	defer c.debug("SUBSCRIBE end establishSubscription")
 	//
 	id, hid := h.Contains(HK_ID)
 	uuid1 := Uuid()
@@ -159,6 +232,7 @@ func (c *Connection) establishSubscription(h Headers) (*subscription, error, Hea
 	sd.drmc = 0                           // Current drain count
 	sd.md = make(chan MessageData, c.scc) // Make subscription MD channel
 	sd.am = h.Value(HK_ACK)               // Set subscription ack mode
+	sd.cry = cr                           // Message crypto, or none
 	//
 	if !hid {
 		// No caller supplied ID.  This STOMP client package supplies one.  It is the
@@ -175,15 +249,19 @@ func (c *Connection) establishSubscription(h Headers) (*subscription, error, Hea
 			sd.id = uuid1
 			h = h.Add(HK_ID, uuid1)
 		default:
-			log.Fatalf("Internal protocol level error:<%s>\n", c.Protocol())
+			return nil, c.protocolLevelError(SUBSCRIBE), h
 		}
 	}
 
+	// STOMP Protocol Enhancement, checked by checkSubscribeHeaders
+	if sb, ok := h.Contains(StompPlusStreamBody); ok {
+		sd.sbt, _ = strconv.ParseInt(sb, 10, 64)
+	}
 	// STOMP Protocol Enhancement
 	if dc, okda := h.Contains(StompPlusDrainAfter); okda {
 		n, e := strconv.ParseInt(dc, 10, 0)
 		if e != nil {
-			log.Printf("sng_drafter conversion error: %v\n", e)
+			c.warn("sng_drafter conversion error", "error", e)
 		} else {
 			sd.drav = true   // Drain after value is OK
 			sd.dra = uint(n) // Drain after count
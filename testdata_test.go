@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 
+	"github.com/gmallard/stompngo/fakebroker"
 	"github.com/gmallard/stompngo/senv"
 )
 
@@ -147,10 +148,14 @@ var (
 		{"ERROR\nbadcon:badmsg\n\nbad message\x00", nil},
 		{"CONNECTED\n\n\x00", nil},
 		{"CONNECTED\n\nconnbody\x00", EBDYDATA},
-		{"CONNECTED\n\nconnbadbody", EBDYDATA},
+		{"CONNECTED\n\nconnbadbody", EBADFRM},
 		{"CONNECTED\nk1:v1\nk2:v2\n\nconnbody\x00", EBDYDATA},
-		{"CONNECTED\nk1:v1\nk2:v2\n\nconnbody", EBDYDATA},
+		{"CONNECTED\nk1:v1\nk2:v2\n\nconnbody", EBADFRM},
 		{"CONNECTED\nk1:v1\nk2:v2\n\n\x00", nil},
+		{"\n\r\n\nCONNECTED\nversion:1.2\n\n\x00", nil},
+		{"CONNECTED\r\nversion:1.2\r\n\r\n\x00", nil},
+		{"MESSAGE\n\n\x00", EUNKFRM},
+		{"\x15\x03\x03\x00\x02\x02\x46", EBADSSLP},
 	}
 	verChecks = []verData{
 		{Headers{HK_ACCEPT_VERSION, SPL_11}, Headers{HK_VERSION, SPL_11}, nil},
@@ -951,15 +956,17 @@ var (
 	brokerid         int
 	tm               = "A Test Message."
 	tlg              = log.New(os.Stderr, "TLG|", log.Ldate|log.Lmicroseconds)
+	fakeb            *fakebroker.Broker // nil when testing a real broker
 )
 
 //=============================================================================
 //= for use by all const ======================================================
 //=============================================================================
 const (
-	TEST_ANYBROKER = iota
-	TEST_AMQ       = iota
-	TEST_RMQ       = iota
-	TEST_ARTEMIS   = iota
-	TEST_APOLLO    = iota
+	TEST_ANYBROKER  = iota
+	TEST_AMQ        = iota
+	TEST_RMQ        = iota
+	TEST_ARTEMIS    = iota
+	TEST_APOLLO     = iota
+	TEST_FAKEBROKER = iota
 )
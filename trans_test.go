@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"errors"
 	"log"
 	"testing"
 )
@@ -50,7 +51,7 @@ func TestTransErrors(t *testing.T) {
 				t.Fatalf("TestTransErrors[%d/%s] %s expected error[%d], got %v\n", pi,
 					sp, tv.action, ti, e)
 			}
-			if e != tv.te {
+			if !errors.Is(e, tv.te) {
 				t.Fatalf("TestTransErrors[%d/%s] %s expected[%d]: %v, got %v\n", pi,
 					sp, tv.action, ti, tv.te, e)
 			}
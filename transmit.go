@@ -16,16 +16,26 @@
 
 package stompngo
 
+import (
+	"context"
+)
+
 /*
 	Common transmit data for many stomp API calls.
 */
 func (c *Connection) transmitCommon(v string, h Headers) error {
-	ch := h.Clone()
-	f := Frame{v, ch, NULLBUFF}
-	r := make(chan error)
-	if e := c.writeWireData(wiredata{f, r}); e != nil {
+	return c.transmitCommonContext(context.Background(), v, h)
+}
+
+/*
+	Common transmit data for many stomp API calls, giving up when ctx is done.
+	The headers are not copied: nothing on the way to the wire changes them.
+*/
+func (c *Connection) transmitCommonContext(ctx context.Context, v string, h Headers) error {
+	f := Frame{v, h, NULLBUFF}
+	r, e := c.writeWireDataContext(ctx, f)
+	if e != nil {
 		return e
 	}
-	e := <-r
-	return e
+	return c.awaitWrite(ctx, r)
 }
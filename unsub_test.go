@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"errors"
 	//"fmt"
 	"log"
 
@@ -42,7 +43,7 @@ func TestUnSubNoHeader(t *testing.T) {
 			t.Fatalf("TestUnSubNoHeader[%d] proto:%s expected:%q got:nil\n",
 				ti, sp, tv.exe)
 		}
-		if e != tv.exe {
+		if !errors.Is(e, tv.exe) {
 			t.Fatalf("TestUnSubNoHeader[%d] proto:%s expected:%q got:%q\n",
 				ti, sp, tv.exe, e)
 		}
@@ -69,7 +70,7 @@ func TestUnSubNoID(t *testing.T) {
 	for ti, tv := range unsubNoHeaderDataList {
 		conn.protocol = tv.proto // Cheat, fake all protocols
 		e = conn.Unsubscribe(empty_headers)
-		if e != tv.exe {
+		if !errors.Is(e, tv.exe) {
 			t.Fatalf("TestUnSubNoHeader[%d] proto:%s expected:%q got:%q\n",
 				ti, sp, tv.exe, e)
 		}
@@ -111,7 +112,7 @@ func TestUnSubBool(t *testing.T) {
 				t.Fatalf("TestUnSubBool[%d] SUBSCRIBE, proto:[%s], channel is nil\n",
 					ti, tv.proto)
 			}
-			if e != tv.exe1 {
+			if !errors.Is(e, tv.exe1) {
 				t.Fatalf("TestUnSubBool[%d] SUBSCRIBE NEQCHECK proto:%s expected:%v got:%q\n",
 					ti, tv.proto, tv.exe1, e)
 			}
@@ -121,7 +122,7 @@ func TestUnSubBool(t *testing.T) {
 		// UNSCRIBE Phase
 		sh := fixHeaderDest(tv.unsubh) // destination fixed if needed
 		e = conn.Unsubscribe(sh)
-		if e != tv.exe2 {
+		if !errors.Is(e, tv.exe2) {
 			t.Fatalf("TestUnSubBool[%d] UNSUBSCRIBE NEQCHECK proto:%s expected:%v got:%q\n",
 				ti, tv.proto, tv.exe2, e)
 		}
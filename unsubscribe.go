@@ -17,6 +17,7 @@
 package stompngo
 
 import (
+	"context"
 	"strconv"
 	"time"
 )
@@ -38,12 +39,24 @@ import (
 
 */
 func (c *Connection) Unsubscribe(h Headers) error {
-	c.log(UNSUBSCRIBE, "start", h)
+	return c.UnsubscribeContext(context.Background(), h)
+}
+
+/*
+	UnsubscribeContext is Unsubscribe, giving up when ctx is done.  See
+	ContextStomper.
+
+	Once the UNSUBSCRIBE frame is on its way to the broker the subscription is
+	removed, even if ctx is done before the write completes.
+*/
+func (c *Connection) UnsubscribeContext(ctx context.Context, h Headers) (e error) {
+	defer func() { e = newOpError("Unsubscribe", UNSUBSCRIBE, h, e) }()
+	c.debug("UNSUBSCRIBE start", "headers", h)
 	// fmt.Printf("Unsub Headers: %v\n", h)
 	if !c.isConnected() {
 		return ECONBAD
 	}
-	e := checkHeaders(h, c.Protocol())
+	e = checkHeaders(h, c.Protocol())
 	if e != nil {
 		return e
 	}
@@ -69,7 +82,7 @@ func (c *Connection) Unsubscribe(h Headers) error {
 			return EUNODSID
 		}
 	default:
-		panic("unsubscribe version not supported: " + c.Protocol())
+		return c.protocolLevelError(UNSUBSCRIBE)
 	}
 	//
 	shaid := Sha1(h.Value(HK_DESTINATION)) // Special for 1.0
@@ -99,27 +112,31 @@ func (c *Connection) Unsubscribe(h Headers) error {
 		usekey = shaid
 		usesp = s10
 	default:
-		panic("unsubscribe version not supported: " + c.Protocol())
+		return c.protocolLevelError(UNSUBSCRIBE)
 	}
 
 	sdn, ok := h.Contains(StompPlusDrainNow) // STOMP Protocol Extension
 
 	if !ok {
-		e = c.transmitCommon(UNSUBSCRIBE, h) // transmitCommon Clones() the headers
+		r, e := c.writeWireDataContext(ctx, Frame{UNSUBSCRIBE, h, NULLBUFF})
 		if e != nil {
 			return e
 		}
+		e = c.awaitWrite(ctx, r)
+		if e != nil && e != ctx.Err() {
+			return e
+		}
 
 		c.subsLock.Lock()
 		delete(c.subs, usekey)
 		c.subsLock.Unlock()
-		c.log(UNSUBSCRIBE, "end", h)
-		return nil
+		c.debug("UNSUBSCRIBE end", "headers", h)
+		return e
 	}
 	//
 	// STOMP Protocol Extension
 	//
-	c.log("sngdrnow extension detected")
+	c.debug("sngdrnow extension detected")
 	idn, err := strconv.ParseInt(sdn, 10, 64)
 	if err != nil {
 		idn = 100 // 100 milliseconds if bad parameter
@@ -136,18 +153,21 @@ forsel:
 				break forsel
 			}
 			dmc++
-			c.log("\nsngdrnow DROP", dmc, mi.Message.Command, mi.Message.Headers)
+			c.debug("sngdrnow DROP", "count", dmc, "command", mi.Message.Command,
+				"headers", mi.Message.Headers)
 		// case _ = <-ticker.C:
 		case <-time.After(ival):
-			c.log("sngdrnow extension BREAK")
+			c.debug("sngdrnow extension BREAK")
+			break forsel
+		case <-ctx.Done():
 			break forsel
 		}
 	}
 	//
-	c.log("sngdrnow extension at very end")
+	c.debug("sngdrnow extension at very end")
 	c.subsLock.Lock()
 	delete(c.subs, usekey)
 	c.subsLock.Unlock()
-	c.log(UNSUBSCRIBE, "endsngdrnow", h)
+	c.debug("UNSUBSCRIBE endsngdrnow", "headers", h)
 	return nil
 }
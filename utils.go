@@ -37,53 +37,70 @@ func encode(s string) string {
 }
 
 /*
-	Decode a string per STOMP 1.1+ specifications.
+	Decode a string per STOMP 1.1+ specifications.  Undefined escape sequences
+	are left as is.
 */
 func decode(s string) string {
-	r := s
-	for _, tr := range codecValues {
-		if strings.Index(r, tr.encoded) >= 0 {
-			r = strings.Replace(r, tr.encoded, tr.decoded, -1)
-		}
-	}
+	r, _ := unescape(s, false)
 	return r
 }
 
+/*
+	Decode a string in one pass.  See unescapeBytes.
+*/
+func unescape(s string, strict bool) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	return unescapeBytes([]byte(s), strict, false)
+}
+
 /*
 	A network helper.  Read from the wire until a 0x00 byte is encountered.
 */
-func readUntilNul(c *Connection) ([]uint8, error) {
+func readUntilNul(c *Connection, pool bool) ([]uint8, error) {
 	var b []byte
 	var e error
 	c.setReadDeadline()
 
 	if c.eltd != nil {
 		st := time.Now().UnixNano()
-		b, e = c.rdr.ReadBytes(0)
+		b, e = readLimitedNul(c.rdr, c.flim.MaxBody)
 		c.eltd.run.ens += time.Now().UnixNano() - st
 		c.eltd.run.ec++
 
 	} else {
-		b, e = c.rdr.ReadBytes(0)
+		b, e = readLimitedNul(c.rdr, c.flim.MaxBody)
 	}
 
 	if c.checkReadError(e) != nil {
 		return b, e
 	}
 	if len(b) == 1 {
-		b = NULLBUFF
-	} else {
-		b = b[0 : len(b)-1]
+		return NULLBUFF, e
 	}
-	return b, e
+	// b is in the read buffer
+	r := newBody(len(b)-1, pool)
+	copy(r, b)
+	return r, e
+}
+
+/*
+	A body buffer, from the pool if wanted.
+*/
+func newBody(n int, pool bool) []byte {
+	if pool {
+		return getBody(n)
+	}
+	return make([]byte, n)
 }
 
 /*
 	A network helper.  Read a full message body with a known length that is
 	> 0.  Then read the trailing 'null' byte expected for STOMP frames.
 */
-func readBody(c *Connection, l int) ([]uint8, error) {
-	b := make([]byte, l)
+func readBody(c *Connection, l int, pool bool) ([]uint8, error) {
+	b := newBody(l, pool)
 	c.setReadDeadline()
 	var n int
 	var e error
@@ -97,7 +114,7 @@ func readBody(c *Connection, l int) ([]uint8, error) {
 	}
 
 	if n < l && n != 0 { // Short read, e is ErrUnexpectedEOF
-		c.log("SHORT READ", n, l, e)
+		c.warn("SHORT READ", "read", n, "length", l, "error", e)
 		return b[0 : n-1], e
 	}
 	if c.checkReadError(e) != nil { // Other erors
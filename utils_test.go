@@ -26,6 +26,7 @@ import (
 	"strings"
 	"testing"
 	//
+	"github.com/gmallard/stompngo/fakebroker"
 	"github.com/gmallard/stompngo/senv"
 )
 
@@ -256,7 +257,9 @@ func fixHeaderDest(h Headers) Headers {
 */
 func setTestBroker() int {
 	brokerid = TEST_ANYBROKER
-	if os.Getenv("STOMP_AMQ") != "" {
+	if fakeb != nil {
+		brokerid = TEST_FAKEBROKER
+	} else if os.Getenv("STOMP_AMQ") != "" {
 		brokerid = TEST_AMQ
 	} else if os.Getenv("STOMP_RMQ") != "" {
 		brokerid = TEST_RMQ
@@ -268,6 +271,26 @@ func setTestBroker() int {
 	return brokerid
 }
 
+/*
+   Test helper.  Start the in-process fake broker, unless a real broker has
+   been configured with STOMP_HOST or STOMP_PORT.  The fake broker address
+   is exported as STOMP_HOST and STOMP_PORT, so all tests find it through
+   senv as usual.
+*/
+func startFakeBroker() *fakebroker.Broker {
+	if os.Getenv("STOMP_HOST") != "" || os.Getenv("STOMP_PORT") != "" {
+		return nil
+	}
+	b, e := fakebroker.Start(nil)
+	if e != nil {
+		log.Fatalf("Fake broker start failed: %v\n", e)
+	}
+	h, p, _ := net.SplitHostPort(b.Addr())
+	_ = os.Setenv("STOMP_HOST", h)
+	_ = os.Setenv("STOMP_PORT", p)
+	return b
+}
+
 /*
    Test helper.  Set long heartbeat test flag.
 */
@@ -0,0 +1,612 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+	WebSocketOptions are optional settings for DialWebSocket.  A nil
+	*WebSocketOptions gives the defaults.
+*/
+type WebSocketOptions struct {
+	// Dial and handshake timeout.  Zero means no timeout.
+	Timeout time.Duration
+	// TLS configuration for wss URLs.  Default: verify the URL host name.
+	TLSConfig *tls.Config
+	// Additional handshake request headers, e.g. Origin or Authorization.
+	Header http.Header
+	// Subprotocols offered, in order of preference.  Default:
+	// WebSocketSubprotocols.
+	Subprotocols []string
+	// Largest message received, in bytes.  Zero means room for one frame
+	// within the FrameLimits given to ConnectWithOptions.  A negative value
+	// means no limit.  A larger message ends the connection with EWSMSGSIZE.
+	MaxMessage int
+}
+
+/*
+	STOMP WebSocket subprotocols, in the default order of preference.
+*/
+var WebSocketSubprotocols = []string{"v12.stomp", "v11.stomp", "v10.stomp"}
+
+/*
+	WebSocketConn is a STOMP over WebSocket connection.  It is a net.Conn, and
+	is used with Connect like any other network connection.
+
+	Each STOMP frame written is sent as one WebSocket message: a text message
+	when the frame is valid UTF-8, and a binary message otherwise.  A frame
+	is held in memory until it is complete, except that a frame with a
+	content-length header is sent as a fragmented binary message once more
+	than wsFragment bytes of it are held, so that bodies streamed by
+	SendReader are not held whole.  Heart-beats
+	are sent as single EOL messages.  Text and binary messages received are
+	passed to the reader as a byte stream.  Pings are answered, and a close
+	message ends the stream.  Messages larger than the limit are refused
+	before they are read, see WebSocketOptions.MaxMessage.
+*/
+type WebSocketConn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	client  bool   // Mask outbound frames
+	subp    string // Negotiated subprotocol
+	rlock   sync.Mutex
+	rbuf    []byte // Received message data not yet read
+	rerr    error
+	max     int64 // Largest message received, zero when not yet set
+	mlen    int64 // Length of the message being received
+	wlock   sync.Mutex
+	pending []byte // Written data not yet a complete STOMP frame
+	scan    wsScan // Progress through pending
+	rem     int    // Bytes still to send of a fragmented message
+	closed  sync.Once
+}
+
+/*
+	Progress of wsFrameLen through pending data, so that written data is
+	looked at only once.
+*/
+type wsScan struct {
+	i    int  // Next byte to look at
+	body int  // Start of the body, zero while in the headers
+	cl   int  // The content-length header value
+	clok bool // A content-length header was found
+}
+
+/*
+	WebSocket opcodes.
+*/
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+/*
+	Pending data held for an incomplete frame with a known length before it
+	is sent as the start of a fragmented message.
+*/
+const wsFragment = 64 * 1024
+
+/*
+	DialWebSocket performs the RFC 6455 handshake with a ws or wss URL, and
+	returns the connection for use with Connect.
+
+	Example:
+		n, e := stompngo.DialWebSocket("wss://broker.example.com:15673/ws", nil)
+		if e != nil {
+			// Do something sane ...
+		}
+		h := stompngo.Headers{stompngo.HK_ACCEPT_VERSION, "1.2",
+			stompngo.HK_HOST, "/"}
+		c, e := stompngo.Connect(n, h)
+		if e != nil {
+			// Do something sane ...
+		}
+		// Use c
+		e = c.Disconnect(stompngo.Headers{})
+		e = n.Close()
+*/
+func DialWebSocket(rawurl string, o *WebSocketOptions) (*WebSocketConn, error) {
+	if o == nil {
+		o = &WebSocketOptions{}
+	}
+	u, e := url.Parse(rawurl)
+	if e != nil {
+		return nil, fmt.Errorf("%w: %v", EBADURL, urlParseError(e))
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: %s", EBADURL, redactedURL(u))
+	}
+	p := u.Port()
+	switch u.Scheme {
+	case "ws":
+		if p == "" {
+			p = "80"
+		}
+	case "wss":
+		if p == "" {
+			p = "443"
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", EURLSCHEME, u.Scheme)
+	}
+	hap := net.JoinHostPort(u.Hostname(), p)
+	d := &net.Dialer{Timeout: o.Timeout}
+	var n net.Conn
+	if u.Scheme == "wss" {
+		tc := &tls.Config{}
+		if o.TLSConfig != nil {
+			tc = o.TLSConfig.Clone()
+		}
+		if tc.ServerName == "" {
+			tc.ServerName = u.Hostname()
+		}
+		n, e = tls.DialWithDialer(d, NetProtoTCP, hap, tc)
+	} else {
+		n, e = d.Dial(NetProtoTCP, hap)
+	}
+	if e != nil {
+		return nil, e
+	}
+	if o.Timeout > 0 {
+		_ = n.SetDeadline(time.Now().Add(o.Timeout))
+	}
+	w, e := wsHandshake(n, u, o)
+	if e != nil {
+		_ = n.Close()
+		return nil, e
+	}
+	_ = n.SetDeadline(time.Time{})
+	return w, nil
+}
+
+/*
+	Client side of the opening handshake.
+*/
+func wsHandshake(n net.Conn, u *url.URL, o *WebSocketOptions) (*WebSocketConn, error) {
+	kb := make([]byte, 16)
+	if _, e := io.ReadFull(rand.Reader, kb); e != nil {
+		return nil, e
+	}
+	key := base64.StdEncoding.EncodeToString(kb)
+	sp := o.Subprotocols
+	if len(sp) == 0 {
+		sp = WebSocketSubprotocols
+	}
+	rq := &http.Request{Method: "GET", URL: u, Host: u.Host,
+		Header: http.Header{}, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1}
+	for k, v := range o.Header {
+		rq.Header[k] = v
+	}
+	rq.Header.Set("Upgrade", "websocket")
+	rq.Header.Set("Connection", "Upgrade")
+	rq.Header.Set("Sec-WebSocket-Key", key)
+	rq.Header.Set("Sec-WebSocket-Version", "13")
+	rq.Header.Set("Sec-WebSocket-Protocol", strings.Join(sp, ", "))
+	if e := rq.Write(n); e != nil {
+		return nil, e
+	}
+	br := bufio.NewReader(n)
+	rs, e := http.ReadResponse(br, rq)
+	if e != nil {
+		return nil, e
+	}
+	_ = rs.Body.Close()
+	if rs.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("%w: %s", EWSHANDSHAKE, rs.Status)
+	}
+	if !strings.EqualFold(rs.Header.Get("Upgrade"), "websocket") ||
+		!wsHeaderHas(rs.Header, "Connection", "upgrade") ||
+		rs.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		return nil, fmt.Errorf("%w: invalid upgrade response", EWSHANDSHAKE)
+	}
+	subp := rs.Header.Get("Sec-WebSocket-Protocol")
+	if subp != "" && !hasValue(sp, subp) {
+		return nil, fmt.Errorf("%w: subprotocol %s", EWSHANDSHAKE, subp)
+	}
+	w := newWebSocketConn(n, br, true)
+	w.subp = subp
+	switch {
+	case o.MaxMessage > 0:
+		w.max = int64(o.MaxMessage)
+	case o.MaxMessage < 0:
+		w.max = int64(noFrameLimit)
+	}
+	return w, nil
+}
+
+/*
+	Wrap a network connection that has completed the handshake.
+*/
+func newWebSocketConn(n net.Conn, br *bufio.Reader, client bool) *WebSocketConn {
+	return &WebSocketConn{conn: n, br: br, client: client}
+}
+
+/*
+	Set the message size limit from the connection frame limits, unless it
+	is already set.
+*/
+func (w *WebSocketConn) limitFrom(l FrameLimits) {
+	w.rlock.Lock()
+	if w.max == 0 {
+		w.max = wsMessageMax(l)
+	}
+	w.rlock.Unlock()
+}
+
+/*
+	The largest message one frame within resolved limits needs: the command
+	line, the headers, the blank line, the body and the NUL.
+*/
+func wsMessageMax(l FrameLimits) int64 {
+	return int64(l.MaxHeaderLine) + int64(l.MaxHeaderBytes) +
+		int64(l.MaxBody) + 8
+}
+
+/*
+	The Sec-WebSocket-Accept value for a key.
+*/
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+/*
+	Check for a token in a comma separated header value.
+*/
+func wsHeaderHas(h http.Header, k, t string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(k)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+	Subprotocol returns the subprotocol chosen by the server, or the empty
+	string if it chose none.
+*/
+func (w *WebSocketConn) Subprotocol() string {
+	return w.subp
+}
+
+/*
+	Read reads received message data.
+*/
+func (w *WebSocketConn) Read(p []byte) (int, error) {
+	w.rlock.Lock()
+	defer w.rlock.Unlock()
+	for len(w.rbuf) == 0 {
+		if w.rerr != nil {
+			return 0, w.rerr
+		}
+		op, b, e := w.readFrame()
+		if e != nil {
+			w.rerr = e
+			return 0, e
+		}
+		switch op {
+		case wsContinuation, wsText, wsBinary:
+			w.rbuf = b
+		case wsPing:
+			if e = w.writeFrame(wsPong, b); e != nil {
+				w.rerr = e
+			}
+		case wsPong:
+		case wsClose:
+			if len(b) > 2 {
+				b = b[:2]
+			}
+			_ = w.writeFrame(wsClose, b)
+			w.rerr = io.EOF
+		default:
+			w.rerr = fmt.Errorf("%w: opcode %d", EWSPROTO, op)
+		}
+	}
+	n := copy(p, w.rbuf)
+	w.rbuf = w.rbuf[n:]
+	return n, nil
+}
+
+/*
+	Read one WebSocket frame.
+*/
+func (w *WebSocketConn) readFrame() (byte, []byte, error) {
+	var h [8]byte
+	if _, e := io.ReadFull(w.br, h[:2]); e != nil {
+		return 0, nil, e
+	}
+	op := h[0] & 0x0f
+	masked := h[1]&0x80 != 0
+	l := uint64(h[1] & 0x7f)
+	switch l {
+	case 126:
+		if _, e := io.ReadFull(w.br, h[:2]); e != nil {
+			return 0, nil, e
+		}
+		l = uint64(binary.BigEndian.Uint16(h[:2]))
+	case 127:
+		if _, e := io.ReadFull(w.br, h[:8]); e != nil {
+			return 0, nil, e
+		}
+		l = binary.BigEndian.Uint64(h[:8])
+	}
+	if op >= wsClose && l > 125 || l > 1<<31 {
+		return 0, nil, fmt.Errorf("%w: frame length %d", EWSPROTO, l)
+	}
+	if op < wsClose {
+		if op != wsContinuation {
+			w.mlen = 0
+		}
+		w.mlen += int64(l)
+		max := w.max
+		if max == 0 {
+			max = wsMessageMax(FrameLimits{}.resolve())
+		}
+		if w.mlen > max {
+			_ = w.writeFrame(wsClose, []byte{0x03, 0xf1}) // 1009, message too big
+			return 0, nil, fmt.Errorf("%w: %d bytes, limit %d", EWSMSGSIZE,
+				w.mlen, max)
+		}
+	}
+	var mk [4]byte
+	if masked {
+		if _, e := io.ReadFull(w.br, mk[:]); e != nil {
+			return 0, nil, e
+		}
+	}
+	b := make([]byte, l)
+	if _, e := io.ReadFull(w.br, b); e != nil {
+		return 0, nil, e
+	}
+	if masked {
+		wsMask(b, mk)
+	}
+	return op, b, nil
+}
+
+/*
+	Write buffers data until it holds a complete STOMP frame or heart-beat,
+	and sends each one as a WebSocket message.  A long frame with a
+	content-length header is sent in fragments as it is written.  On error
+	the count is of the bytes of p that were sent.
+*/
+func (w *WebSocketConn) Write(p []byte) (int, error) {
+	w.wlock.Lock()
+	defer w.wlock.Unlock()
+	held := len(w.pending)
+	w.pending = append(w.pending, p...)
+	sent := 0
+	var e error
+	for len(w.pending) > 0 {
+		if w.rem > 0 {
+			l := len(w.pending)
+			if l > w.rem {
+				l = w.rem
+			}
+			if e = w.writeFragmentLocked(wsContinuation, l == w.rem, w.pending[:l]); e != nil {
+				break
+			}
+			w.pending = w.pending[l:]
+			w.rem -= l
+			sent += l
+			continue
+		}
+		l := wsFrameLen(w.pending, &w.scan)
+		if l == 0 {
+			if w.scan.body == 0 || !w.scan.clok || len(w.pending) < wsFragment {
+				break
+			}
+			// The frame length is known, start a fragmented message
+			l = len(w.pending)
+			if e = w.writeFragmentLocked(wsBinary, false, w.pending); e != nil {
+				break
+			}
+			w.rem = w.scan.body + w.scan.cl + 1 - l
+			w.pending = w.pending[l:]
+			w.scan = wsScan{}
+			sent += l
+			continue
+		}
+		op := byte(wsText)
+		if !utf8.Valid(w.pending[:l]) {
+			op = wsBinary
+		}
+		if e = w.writeFrameLocked(op, w.pending[:l]); e != nil {
+			break
+		}
+		w.pending = w.pending[l:]
+		w.scan = wsScan{}
+		sent += l
+	}
+	if e != nil {
+		w.pending, w.scan, w.rem = nil, wsScan{}, 0
+		if sent -= held; sent < 0 {
+			sent = 0
+		}
+		return sent, e
+	}
+	if len(w.pending) == 0 {
+		w.pending = nil
+	}
+	return len(p), nil
+}
+
+/*
+	Length of the first complete STOMP frame or heart-beat in b, or zero if
+	b does not hold one yet.  s holds the progress of earlier calls for the
+	same frame, b may only have grown since.
+*/
+func wsFrameLen(b []byte, s *wsScan) int {
+	if s.i == 0 {
+		switch {
+		case len(b) == 0:
+			return 0
+		case b[0] == '\n':
+			return 1
+		case b[0] == '\r' && len(b) > 1 && b[1] == '\n':
+			return 2
+		}
+	}
+	for s.body == 0 {
+		j := bytes.IndexByte(b[s.i:], '\n')
+		if j < 0 {
+			return 0
+		}
+		line := bytes.TrimSuffix(b[s.i:s.i+j], []byte("\r"))
+		first := s.i == 0
+		s.i += j + 1
+		if len(line) == 0 {
+			s.body = s.i
+			break
+		}
+		if !first && !s.clok && bytes.HasPrefix(line, []byte(HK_CONTENT_LENGTH+":")) {
+			if n, e := strconv.Atoi(string(line[len(HK_CONTENT_LENGTH)+1:])); e == nil && n >= 0 {
+				s.cl, s.clok = n, true
+			}
+		}
+	}
+	if s.clok {
+		if len(b) < s.body+s.cl+1 {
+			return 0
+		}
+		return s.body + s.cl + 1
+	}
+	j := bytes.IndexByte(b[s.i:], 0)
+	if j < 0 {
+		s.i = len(b)
+		return 0
+	}
+	return s.i + j + 1
+}
+
+func (w *WebSocketConn) writeFrame(op byte, b []byte) error {
+	w.wlock.Lock()
+	defer w.wlock.Unlock()
+	return w.writeFrameLocked(op, b)
+}
+
+/*
+	Write one unfragmented WebSocket frame.
+*/
+func (w *WebSocketConn) writeFrameLocked(op byte, b []byte) error {
+	return w.writeFragmentLocked(op, true, b)
+}
+
+/*
+	Write one WebSocket frame, the last of its message when fin is set.
+	Client frames are masked.
+*/
+func (w *WebSocketConn) writeFragmentLocked(op byte, fin bool, b []byte) error {
+	f := make([]byte, 0, len(b)+14)
+	if fin {
+		op |= 0x80
+	}
+	f = append(f, op)
+	var mb byte
+	if w.client {
+		mb = 0x80
+	}
+	switch l := len(b); {
+	case l < 126:
+		f = append(f, mb|byte(l))
+	case l <= 0xffff:
+		f = append(f, mb|126, byte(l>>8), byte(l))
+	default:
+		f = append(f, mb|127)
+		var lb [8]byte
+		binary.BigEndian.PutUint64(lb[:], uint64(l))
+		f = append(f, lb[:]...)
+	}
+	if !w.client {
+		_, e := w.conn.Write(append(f, b...))
+		return e
+	}
+	var mk [4]byte
+	if _, e := io.ReadFull(rand.Reader, mk[:]); e != nil {
+		return e
+	}
+	f = append(f, mk[:]...)
+	s := len(f)
+	f = append(f, b...)
+	wsMask(f[s:], mk)
+	_, e := w.conn.Write(f)
+	return e
+}
+
+/*
+	Mask or unmask payload data in place.
+*/
+func wsMask(b []byte, mk [4]byte) {
+	for i := range b {
+		b[i] ^= mk[i&3]
+	}
+}
+
+/*
+	Close sends a close message, and closes the network connection.
+*/
+func (w *WebSocketConn) Close() error {
+	var e error
+	w.closed.Do(func() {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		_ = w.writeFrame(wsClose, []byte{0x03, 0xe8}) // 1000, normal closure
+		e = w.conn.Close()
+	})
+	return e
+}
+
+// LocalAddr returns the local network address.
+func (w *WebSocketConn) LocalAddr() net.Addr { return w.conn.LocalAddr() }
+
+// RemoteAddr returns the remote network address.
+func (w *WebSocketConn) RemoteAddr() net.Addr { return w.conn.RemoteAddr() }
+
+// SetDeadline sets the network connection deadlines.
+func (w *WebSocketConn) SetDeadline(t time.Time) error { return w.conn.SetDeadline(t) }
+
+// SetReadDeadline sets the network connection read deadline.
+func (w *WebSocketConn) SetReadDeadline(t time.Time) error { return w.conn.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the network connection write deadline.
+func (w *WebSocketConn) SetWriteDeadline(t time.Time) error { return w.conn.SetWriteDeadline(t) }
@@ -0,0 +1,271 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test helper.  An httptest server that upgrades to WebSocket, and serves
+	STOMP from a fake broker.
+*/
+func wsServer(t *testing.T, b *fakebroker.Broker, subp string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !wsHeaderHas(r.Header, "Connection", "upgrade") ||
+			!wsHeaderHas(r.Header, "Sec-WebSocket-Protocol", subp) {
+			http.Error(w, "not a STOMP websocket request", http.StatusBadRequest)
+			return
+		}
+		n, rw, e := w.(http.Hijacker).Hijack()
+		if e != nil {
+			t.Errorf("wsServer Hijack failed: [%v]\n", e)
+			return
+		}
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + wsAccept(r.Header.Get("Sec-WebSocket-Key")) +
+			"\r\nSec-WebSocket-Protocol: " + subp + "\r\n\r\n")
+		_ = rw.Flush()
+		b.ServeConn(newWebSocketConn(n, rw.Reader, false))
+	}))
+}
+
+/*
+	Test finding STOMP frame boundaries in written data.
+*/
+func TestWebSocketFrameLen(t *testing.T) {
+	for i, tv := range []struct {
+		d string
+		l int
+	}{
+		{"", 0},
+		{"\n", 1},
+		{"\r\nSEND", 2},
+		{"SEND\ndestination:/q", 0},
+		{"SEND\ndestination:/q\n\nabc", 0},
+		{"SEND\ndestination:/q\n\nabc\x00", 25},
+		{"SEND\ndestination:/q\n\nabc\x00\n", 25},
+		{"SEND\r\ndestination:/q\r\n\r\nabc\x00", 28},
+		{"SEND\ncontent-length:5\n\na\x00b\x00c\x00", 29},
+		{"SEND\ncontent-length:5\n\na\x00b", 0},
+		{"SEND\ncontent-length:x\n\na\x00b", 25},
+	} {
+		if l := wsFrameLen([]byte(tv.d), &wsScan{}); l != tv.l {
+			t.Fatalf("TestWebSocketFrameLen[%d] Expected [%v], got [%v]\n", i,
+				tv.l, l)
+		}
+		// The same data, one byte at a time
+		var sc wsScan
+		l := 0
+		for j := 1; j <= len(tv.d) && l == 0; j++ {
+			l = wsFrameLen([]byte(tv.d[:j]), &sc)
+		}
+		if l != tv.l {
+			t.Fatalf("TestWebSocketFrameLen[%d] Expected [%v] by byte, got [%v]\n",
+				i, tv.l, l)
+		}
+	}
+}
+
+/*
+	Test STOMP over WebSocket against the fake broker.
+*/
+func TestWebSocketConnect(t *testing.T) {
+	b := fakebroker.New(nil)
+	defer b.Close()
+	s := wsServer(t, b, "v12.stomp")
+	defer s.Close()
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/ws"
+	n, e := DialWebSocket(u, nil)
+	if e != nil {
+		t.Fatalf("TestWebSocketConnect Expected no dial error, got [%v]\n", e)
+	}
+	if n.Subprotocol() != "v12.stomp" {
+		t.Fatalf("TestWebSocketConnect Expected [v12.stomp], got [%v]\n",
+			n.Subprotocol())
+	}
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestWebSocketConnect CONNECT Failed: e:<%q>\n", e)
+	}
+	d := "/queue/websocket.binary"
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "ws1"})
+	if e != nil {
+		t.Fatalf("TestWebSocketConnect Expected no subscribe error, got [%v]\n", e)
+	}
+	// Binary data, with a NUL, spanning several writer buffers
+	want := bytes.Repeat([]byte{0, 0xff, 'a', '\n'}, 4096)
+	for i := 0; i < 2; i++ {
+		if e = conn.SendBytes(Headers{HK_DESTINATION, d}, want); e != nil {
+			t.Fatalf("TestWebSocketConnect Expected no send error, got [%v]\n", e)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		md := <-sc
+		if md.Error != nil {
+			t.Fatalf("TestWebSocketConnect Expected no read error, got [%v]\n",
+				md.Error)
+		}
+		if !bytes.Equal(md.Message.Body, want) {
+			t.Fatalf("TestWebSocketConnect[%d] Body mismatch, length [%v]\n", i,
+				len(md.Message.Body))
+		}
+	}
+	e = conn.Disconnect(empty_headers)
+	checkDisconnectError(t, e)
+	_ = closeConn(t, n)
+	//
+	if _, e = DialWebSocket(u, &WebSocketOptions{Subprotocols: []string{"v11.stomp"}}); e == nil {
+		t.Fatalf("TestWebSocketConnect Expected handshake error, got nil\n")
+	}
+	if _, e = DialWebSocket(s.URL, nil); e == nil {
+		t.Fatalf("TestWebSocketConnect Expected scheme error, got nil\n")
+	}
+	_, e = DialWebSocket("ws://guest:secret@/ws", nil)
+	if !errors.Is(e, EBADURL) || strings.Contains(e.Error(), "secret") {
+		t.Fatalf("TestWebSocketConnect Expected redacted [%v], got [%v]\n", EBADURL, e)
+	}
+	_, e = DialWebSocket("ws://guest:secret@host:port/ws", nil)
+	if !errors.Is(e, EBADURL) || strings.Contains(e.Error(), "secret") {
+		t.Fatalf("TestWebSocketConnect Expected redacted [%v], got [%v]\n", EBADURL, e)
+	}
+}
+
+/*
+	Test helper.  A network connection that fails after a number of writes.
+*/
+type wsFailConn struct {
+	net.Conn
+	writes int
+}
+
+func (f *wsFailConn) Write(b []byte) (int, error) {
+	if f.writes == 0 {
+		return 0, errors.New("write failed")
+	}
+	f.writes--
+	return len(b), nil
+}
+
+/*
+	Test that a long body is sent as a fragmented message, and the count
+	written on error.
+*/
+func TestWebSocketStream(t *testing.T) {
+	b := fakebroker.New(nil)
+	defer b.Close()
+	s := wsServer(t, b, "v12.stomp")
+	defer s.Close()
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/ws"
+	n, e := DialWebSocket(u, nil)
+	if e != nil {
+		t.Fatalf("TestWebSocketStream Expected no dial error, got [%v]\n", e)
+	}
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestWebSocketStream CONNECT Failed: e:<%q>\n", e)
+	}
+	d := "/queue/websocket.stream"
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "ws1"})
+	if e != nil {
+		t.Fatalf("TestWebSocketStream Expected no subscribe error, got [%v]\n", e)
+	}
+	want := bytes.Repeat([]byte("0123456789abcdef"), 3*wsFragment/16)
+	e = conn.SendReader(Headers{HK_DESTINATION, d}, bytes.NewReader(want),
+		int64(len(want)))
+	if e != nil {
+		t.Fatalf("TestWebSocketStream Expected no send error, got [%v]\n", e)
+	}
+	md := <-sc
+	if md.Error != nil || !bytes.Equal(md.Message.Body, want) {
+		t.Fatalf("TestWebSocketStream Body mismatch, length [%v] [%v]\n",
+			len(md.Message.Body), md.Error)
+	}
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+	_ = n.Close()
+	//
+	w := newWebSocketConn(&wsFailConn{writes: 1}, nil, true)
+	if c, e := w.Write([]byte("SEND\n")); c != 5 || e != nil {
+		t.Fatalf("TestWebSocketStream Expected [5], got [%v] [%v]\n", c, e)
+	}
+	c, e := w.Write([]byte("\n\x00\n\n"))
+	if c != 2 || e == nil {
+		t.Fatalf("TestWebSocketStream Expected [2] and an error, got [%v] [%v]\n", c, e)
+	}
+}
+
+/*
+	Test that a message over the size limit ends the connection.
+*/
+func TestWebSocketMaxMessage(t *testing.T) {
+	b := fakebroker.New(nil)
+	defer b.Close()
+	s := wsServer(t, b, "v12.stomp")
+	defer s.Close()
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/ws"
+	n, e := DialWebSocket(u, &WebSocketOptions{MaxMessage: 1024})
+	if e != nil {
+		t.Fatalf("TestWebSocketMaxMessage Expected no dial error, got [%v]\n", e)
+	}
+	conn, e := Connect(n, headersProtocol(login_headers, SPL_12))
+	if e != nil {
+		t.Fatalf("TestWebSocketMaxMessage CONNECT Failed: e:<%q>\n", e)
+	}
+	d := "/queue/websocket.max"
+	sc, e := conn.Subscribe(Headers{HK_DESTINATION, d, HK_ID, "ws1"})
+	if e != nil {
+		t.Fatalf("TestWebSocketMaxMessage Expected no subscribe error, got [%v]\n", e)
+	}
+	for _, l := range []int{512, 2048} {
+		if e = conn.SendBytes(Headers{HK_DESTINATION, d}, make([]byte, l)); e != nil {
+			t.Fatalf("TestWebSocketMaxMessage Expected no send error, got [%v]\n", e)
+		}
+	}
+	if md := <-sc; md.Error != nil || len(md.Message.Body) != 512 {
+		t.Fatalf("TestWebSocketMaxMessage Expected [512] bytes, got [%v] [%v]\n",
+			len(md.Message.Body), md.Error)
+	}
+	if md := <-sc; !errors.Is(md.Error, EWSMSGSIZE) {
+		t.Fatalf("TestWebSocketMaxMessage Expected [%v], got [%v]\n", EWSMSGSIZE,
+			md.Error)
+	}
+	_ = n.Close()
+	// The default comes from the connection frame limits
+	n, e = DialWebSocket(u, nil)
+	if e != nil {
+		t.Fatalf("TestWebSocketMaxMessage Expected no dial error, got [%v]\n", e)
+	}
+	conn, e = ConnectWithOptions(n, headersProtocol(login_headers, SPL_12),
+		&ConnectOptions{FrameLimits: FrameLimits{MaxBody: 100}})
+	if e != nil {
+		t.Fatalf("TestWebSocketMaxMessage CONNECT Failed: e:<%q>\n", e)
+	}
+	if w := wsMessageMax(conn.flim); n.max != w {
+		t.Fatalf("TestWebSocketMaxMessage Expected [%v], got [%v]\n", w, n.max)
+	}
+	checkDisconnectError(t, conn.Disconnect(empty_headers))
+	_ = n.Close()
+}
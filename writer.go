@@ -19,6 +19,8 @@ package stompngo
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"io"
 	"net"
 
 	// "bytes"
@@ -40,51 +42,180 @@ func (c *Connection) writeWireData(wd wiredata) error {
 	return nil
 }
 
+/*
+	Hand a frame to the logical network writer, giving up when ctx is done.
+	Returns the channel the write result will arrive on.  The channel is
+	buffered, so the writer never blocks if the result is abandoned.
+*/
+func (c *Connection) writeWireDataContext(ctx context.Context, f Frame) (chan error, error) {
+	return c.queueWireData(ctx, wiredata{frame: f})
+}
+
+/*
+	Hand wire data to the logical network writer, giving up when ctx is done.
+	The result channel is supplied here.
+*/
+func (c *Connection) queueWireData(ctx context.Context, wd wiredata) (chan error, error) {
+	if wd.body == nil && wd.pf == nil {
+		var e error
+		if wd.frame, e = c.sealFrame(wd.frame); e != nil {
+			return nil, e
+		}
+	}
+	r := make(chan error, 1)
+	wd.errchan = r
+	select {
+	case c.output <- wd:
+	case <-c.ssdc:
+		return nil, ECONBAD
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r, nil
+}
+
+/*
+	Wait for the result of a frame write, giving up when ctx is done.  The
+	frame is still written in that case.  A frame the writer never took gives
+	ECONBAD.
+*/
+func (c *Connection) awaitWrite(ctx context.Context, r chan error) error {
+	select {
+	case e := <-r:
+		return e
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.wtrdc:
+		select {
+		case e := <-r: // Written before the writer ended
+			return e
+		default:
+			return ECONBAD
+		}
+	}
+}
+
 /*
 	Logical network writer.  Read wiredata structures from the communication
-	channel, and put the frame on the wire.
+	channel, and put the frame on the wire.  With write batching, frames that
+	are already waiting are written together, with one flush.
 */
 func (c *Connection) writer() {
 writerLoop:
 	for {
 		select {
 		case d := <-c.output:
-			c.log("WTR_WIREWRITE start")
+			c.debug("WTR_WIREWRITE start")
+			var st int64
 			if c.eltd != nil {
-				st := time.Now().UnixNano()
+				st = time.Now().UnixNano()
+			}
+			if c.wbatch > 0 {
+				d = c.batchWrite(d)
+			} else {
 				c.wireWrite(d)
+			}
+			if c.eltd != nil {
 				c.eltd.wov.ens += time.Now().UnixNano() - st
 				c.eltd.wov.ec++
-			} else {
-				c.wireWrite(d)
 			}
-			logLock.Lock()
-			if c.logger != nil {
-				c.logx("WTR_WIREWRITE COMPLETE", d.frame.Command, d.frame.Headers,
-					HexData(d.frame.Body))
+			if c.logs(LogDebug) {
+				c.debug("WTR_WIREWRITE COMPLETE", "command", d.frame.Command,
+					"headers", d.frame.Headers, "body", HexData(d.frame.Body))
 			}
-			logLock.Unlock()
 			if d.frame.Command == DISCONNECT {
 				break writerLoop // we are done with this connection
 			}
 		case _ = <-c.ssdc:
-			c.log("WTR_WIREWRITE shutdown S received")
+			c.debug("WTR_WIREWRITE shutdown S received")
 			break writerLoop
 		case _ = <-c.wtrsdc:
-			c.log("WTR_WIREWRITE shutdown W received")
+			c.debug("WTR_WIREWRITE shutdown W received")
 			break writerLoop
 		}
 	} // of for
 	//
 	c.setConnected(false)
 	c.sysAbort()
-	c.log("WTR_SHUTDOWN", time.Now())
+	close(c.wtrdc)
+	c.debug("WTR_SHUTDOWN")
 }
 
 /*
 	Connection logical write.
 */
 func (c *Connection) wireWrite(d wiredata) {
+	e := c.bufferWrite(&d)
+	if e == nil {
+		e = c.checkWriteError(c.wtr.Flush())
+	}
+	c.wroteFrame(d, e)
+}
+
+/*
+	Batched logical write.  Frames already queued, up to the batch size, are
+	written behind d, waiting at most the linger time for more.  There is one
+	flush, and each caller gets the result for its own frame.  Returns the
+	last frame written.
+*/
+func (c *Connection) batchWrite(d wiredata) wiredata {
+	var tc <-chan time.Time // nil, no linger
+	if c.wlinger > 0 {
+		t := time.NewTimer(c.wlinger)
+		defer t.Stop()
+		tc = t.C
+	}
+	c.wbuf = c.wbuf[:0]
+	c.wbe = c.wbe[:0]
+batchLoop:
+	for {
+		e := c.bufferWrite(&d)
+		c.wbuf = append(c.wbuf, d)
+		c.wbe = append(c.wbe, e)
+		if (e != nil && e != ESWALLOW) || d.frame.Command == DISCONNECT ||
+			len(c.wbuf) >= c.wbatch {
+			break
+		}
+		select {
+		case d = <-c.output:
+			continue
+		default:
+		}
+		if tc == nil {
+			break // Nothing more is queued
+		}
+		select {
+		case d = <-c.output:
+		case <-tc:
+			break batchLoop
+		case <-c.ssdc:
+			break batchLoop
+		case <-c.wtrsdc:
+			break batchLoop
+		}
+	}
+	fe := c.checkWriteError(c.wtr.Flush())
+	for i, d := range c.wbuf {
+		e := c.wbe[i]
+		if e == nil {
+			e = fe
+		}
+		c.wroteFrame(d, e)
+		c.wbuf[i] = wiredata{} // Do not hold frames
+	}
+	if c.logs(LogDebug) {
+		c.debug("WTR_WIREWRITE batch", "frames", len(c.wbuf))
+	}
+	return d
+}
+
+/*
+	Write a frame to the buffered writer, without a flush.
+*/
+func (c *Connection) bufferWrite(d *wiredata) error {
+	if e := c.interceptOut(d); e != nil {
+		return e // Nothing written
+	}
 	f := &d.frame
 	// fmt.Printf("WWD01 f:[%v]\n", f)
 	switch f.Command {
@@ -101,95 +232,87 @@ func (c *Connection) wireWrite(d wiredata) {
 		} else {
 			_, e = c.wtr.WriteString(f.Command)
 		}
-
-		if e != nil {
-			if e.(net.Error).Timeout() {
-				if c.dld.dns {
-					c.dld.dlnotify(e, true)
-				}
-			}
-			d.errchan <- e
-			return
-		}
+		d.wlen = 1
+		return c.checkWriteError(e)
 	default: // Other frames
-		if e := f.writeFrame(c.wtr, c); e != nil {
-			d.errchan <- e
-			return
+		var e error
+		if d.pf != nil {
+			d.wlen, e = d.pf.writeFrame(c.wtr, c, f.Body)
+		} else {
+			d.wlen, e = f.writeFrame(c.wtr, c, d.body, d.bodylen)
 		}
-		if e := c.wtr.Flush(); e != nil {
-			d.errchan <- e
-			return
+		if e != nil && d.body != nil {
+			// A partial frame is on the wire, nothing more can follow it
+			_ = c.netconn.Close()
 		}
+		return e
+	}
+}
+
+/*
+	Account for a frame write, and report the result to the caller.  A
+	swallowed frame is not counted.
+*/
+func (c *Connection) wroteFrame(d wiredata, e error) {
+	if e == ESWALLOW {
+		d.errchan <- nil
+		return
 	}
-	if e := c.wtr.Flush(); e != nil {
+	if e != nil {
 		d.errchan <- e
 		return
 	}
-	//
 	if c.hbd != nil {
 		c.hbd.sdl.Lock()
 		c.hbd.ls = time.Now().UnixNano() // Latest good send
 		c.hbd.sdl.Unlock()
 	}
-	c.mets.tfw++                // Frame written count
-	c.mets.tbw += f.Size(false) // Bytes written count
+	c.mets.tfw++         // Frame written count
+	c.mets.tbw += d.wlen // Bytes written count
 	//
 	d.errchan <- nil
-	return
 }
 
 /*
-	Physical frame write to the wire.
-*/
-func (f *Frame) writeFrame(w *bufio.Writer, c *Connection) error {
+	Physical frame write to the wire.  A non-nil br supplies a body of bl
+	bytes in place of f.Body.  Returns the number of bytes written.
 
-	var sctok bool
+	The frame is never changed.  Headers are escaped as they are written, and
+	missing content-type and content-length headers follow f.Headers.
+*/
+func (f *Frame) writeFrame(w *bufio.Writer, c *Connection, br io.Reader, bl int64) (int64, error) {
 	// Content type.  Always add it if the client does not suppress and does not
 	// supply it.
-	_, sctok = f.Headers.Contains(HK_SUPPRESS_CT)
+	_, sctok := f.Headers.Contains(HK_SUPPRESS_CT)
+	act := false
 	if !sctok {
-		if _, ctok := f.Headers.Contains(HK_CONTENT_TYPE); !ctok {
-			f.Headers = append(f.Headers, HK_CONTENT_TYPE,
-				DFLT_CONTENT_TYPE)
-		}
+		_, ctok := f.Headers.Contains(HK_CONTENT_TYPE)
+		act = !ctok
 	}
-
-	var sclok bool
 	// Content length - Always add it if client does not suppress it and
 	// does not supply it.
-	_, sclok = f.Headers.Contains(HK_SUPPRESS_CL)
+	_, sclok := f.Headers.Contains(HK_SUPPRESS_CL)
+	acl := false
 	if !sclok {
-		if _, clok := f.Headers.Contains(HK_CONTENT_LENGTH); !clok {
-			f.Headers = append(f.Headers, HK_CONTENT_LENGTH, strconv.Itoa(len(f.Body)))
-		}
+		_, clok := f.Headers.Contains(HK_CONTENT_LENGTH)
+		acl = !clok
 	}
 	// Encode the headers if needed
-	if c.Protocol() > SPL_10 && f.Command != CONNECT {
-		for i := 0; i < len(f.Headers); i += 2 {
-			f.Headers[i] = encode(f.Headers[i])
-			f.Headers[i+1] = encode(f.Headers[i+1])
-		}
-	}
+	enc := c.Protocol() > SPL_10 && escapedCommand(f.Command)
 
+	body := f.Body
 	if sclok {
-		nz := bytes.IndexByte(f.Body, 0)
-		// fmt.Printf("WDBG41 ok:%v\n", nz)
-		if nz == 0 {
-			f.Body = []byte{}
-			// fmt.Printf("WDBG42 body:%v bodystring: %v\n", f.Body, string(f.Body))
-		} else if nz > 0 {
-			f.Body = f.Body[0:nz]
-			// fmt.Printf("WDBG43 body:%v bodystring: %v\n", f.Body, string(f.Body))
+		if nz := bytes.IndexByte(body, 0); nz >= 0 {
+			body = body[0:nz]
 		}
 	}
 
+	// Writes start
+	var n int64
+	// Write the frame Command
 	if c.dld.wde && c.dld.wds {
 		_ = c.netconn.SetWriteDeadline(time.Now().Add(c.dld.wdld))
 	}
-
-	// Writes start
-
-	// Write the frame Command
 	var e error
 	if c.eltd != nil {
 		st := time.Now().UnixNano()
@@ -199,62 +322,99 @@ func (f *Frame) writeFrame(w *bufio.Writer, c *Connection) error {
 	} else {
 		_, e = w.WriteString(f.Command + "\n")
 	}
-
 	if c.checkWriteError(e) != nil {
-		return e
+		return n, e
 	}
+	n += int64(len(f.Command)) + 1
 	// fmt.Println("WRCMD", f.Command)
 	// Write the frame Headers
 	for i := 0; i < len(f.Headers); i += 2 {
-		if c.dld.wde && c.dld.wds {
-			_ = c.netconn.SetWriteDeadline(time.Now().Add(c.dld.wdld))
+		k, v := f.Headers[i], f.Headers[i+1]
+		if enc {
+			k, v = encode(k), encode(v)
 		}
-
-		if c.eltd != nil {
-			st := time.Now().UnixNano()
-			_, e = w.WriteString(f.Headers[i] + ":" + f.Headers[i+1] + "\n")
-			c.eltd.wivh.ens += time.Now().UnixNano() - st
-			c.eltd.wivh.ec++
-		} else {
-			_, e = w.WriteString(f.Headers[i] + ":" + f.Headers[i+1] + "\n")
+		if e = c.writeHeader(w, k, v); e != nil {
+			return n, e
 		}
-
-		if c.checkWriteError(e) != nil {
-			return e
+		n += int64(len(k)+len(v)) + 2
+	}
+	if act {
+		if e = c.writeHeader(w, HK_CONTENT_TYPE, DFLT_CONTENT_TYPE); e != nil {
+			return n, e
+		}
+		n += int64(len(HK_CONTENT_TYPE)+len(DFLT_CONTENT_TYPE)) + 2
+	}
+	if acl {
+		l := strconv.Itoa(len(body))
+		if e = c.writeHeader(w, HK_CONTENT_LENGTH, l); e != nil {
+			return n, e
 		}
-		// fmt.Println("WRHDR", f.Headers[i]+":"+f.Headers[i+1]+"\n")
+		n += int64(len(HK_CONTENT_LENGTH)+len(l)) + 2
 	}
+	m, e := c.writeFrameEnd(w, body, br, bl)
+	return n + m, e
+}
 
+/*
+	Write one header line.
+*/
+func (c *Connection) writeHeader(w *bufio.Writer, k, v string) error {
+	if c.dld.wde && c.dld.wds {
+		_ = c.netconn.SetWriteDeadline(time.Now().Add(c.dld.wdld))
+	}
+	var e error
+	if c.eltd != nil {
+		st := time.Now().UnixNano()
+		_, e = w.WriteString(k + ":" + v + "\n")
+		c.eltd.wivh.ens += time.Now().UnixNano() - st
+		c.eltd.wivh.ec++
+	} else {
+		_, e = w.WriteString(k + ":" + v + "\n")
+	}
+	// fmt.Println("WRHDR", k+":"+v+"\n")
+	return c.checkWriteError(e)
+}
+
+/*
+	Write the end of a frame: the line that ends the headers, the body and the
+	NUL.  A non-nil br supplies a body of bl bytes in place of b.  Returns the
+	number of bytes written.
+*/
+func (c *Connection) writeFrameEnd(w *bufio.Writer, b []byte, br io.Reader, bl int64) (int64, error) {
 	// Write the last Header LF
 	if c.dld.wde && c.dld.wds {
 		_ = c.netconn.SetWriteDeadline(time.Now().Add(c.dld.wdld))
 	}
-	e = w.WriteByte('\n')
+	e := w.WriteByte('\n')
 	if c.checkWriteError(e) != nil {
-		return e
+		return 0, e
 	}
-	// fmt.Printf("WDBG40 ok:%v\n", sclok)
-
 	// Write the body
-	if len(f.Body) != 0 { // Foolish to write 0 length data
-		// fmt.Println("WRBDY", f.Body)
-		e := c.writeBody(f)
+	if br != nil {
+		e := c.writeBodyFrom(br, bl)
 		if c.checkWriteError(e) != nil {
-			return e
+			return 1, e
 		}
+	} else if len(b) != 0 { // Foolish to write 0 length data
+		// fmt.Println("WRBDY", b)
+		e := c.writeBody(b)
+		if c.checkWriteError(e) != nil {
+			return 1, e
+		}
+		bl = int64(len(b))
 	}
 	if c.dld.wde && c.dld.wds {
 		_ = c.netconn.SetWriteDeadline(time.Now().Add(c.dld.wdld))
 	}
 	e = w.WriteByte(0)
 	if c.checkWriteError(e) != nil {
-		return e
+		return 1 + bl, e
 	}
 	// End of write loop - set no deadline
 	if c.dld.wde {
 		_ = c.netconn.SetWriteDeadline(c.dld.t0)
 	}
-	return nil
+	return 2 + bl, nil
 }
 
 func (c *Connection) checkWriteError(e error) error {
@@ -267,15 +427,15 @@ func (c *Connection) checkWriteError(e error) error {
 	}
 	if ne.Timeout() {
 		if c.dld.dns {
-			c.log("invoking write deadline callback 1")
+			c.debug("invoking write deadline callback 1")
 			c.dld.dlnotify(e, true)
 		}
 	}
 	return e
 }
 
-func (c *Connection) writeBody(f *Frame) error {
-	// fmt.Printf("WDBG99 body:%v bodystring: %v\n", f.Body, string(f.Body))
+func (c *Connection) writeBody(b []byte) error {
+	// fmt.Printf("WDBG99 body:%v bodystring: %v\n", b, string(b))
 	var n = 0
 	var e error
 	for {
@@ -284,16 +444,16 @@ func (c *Connection) writeBody(f *Frame) error {
 		}
 		if c.eltd != nil {
 			st := time.Now().UnixNano()
-			n, e = c.wtr.Write(f.Body)
+			n, e = c.wtr.Write(b)
 			c.eltd.wbdy.ens += time.Now().UnixNano() - st
 			c.eltd.wbdy.ec++
 		} else {
-			n, e = c.wtr.Write(f.Body)
+			n, e = c.wtr.Write(b)
 		}
-		if n == len(f.Body) {
+		if n == len(b) {
 			return e
 		}
-		c.log("SHORT WRITE", n, len(f.Body))
+		c.warn("SHORT WRITE", "written", n, "length", len(b))
 		if n == 0 { // Zero bytes would mean something is seriously wrong.
 			return e
 		}
@@ -301,14 +461,14 @@ func (c *Connection) writeBody(f *Frame) error {
 			return e
 		}
 		if c.dld.wde && c.dld.wds && c.dld.dns && isErrorTimeout(e) {
-			c.log("invoking write deadline callback 2")
+			c.debug("invoking write deadline callback 2")
 			c.dld.dlnotify(e, true)
 		}
 		// *Any* error from a bufio.Writer is *not* recoverable.  See code in
 		// bufio.go to understand this.  We get a new writer here, to clear any
 		// error condition.
 		c.wtr = bufio.NewWriter(c.netconn) // Create new writer
-		f.Body = f.Body[n:]
+		b = b[n:]
 	}
 }
 
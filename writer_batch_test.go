@@ -0,0 +1,112 @@
+//
+// Copyright © 2026 Guy M. Allard
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stompngo
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmallard/stompngo/fakebroker"
+)
+
+/*
+	Test that batched writes deliver every frame from concurrent senders.
+*/
+func TestWriteBatch(t *testing.T) {
+	for _, linger := range []time.Duration{0, time.Millisecond} {
+		b := fakebroker.New(nil)
+		o := &ConnectOptions{WriteBatch: 16, WriteLinger: linger}
+		conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+			SPL_12), o)
+		if e != nil {
+			t.Fatalf("TestWriteBatch CONNECT Failed: e:<%q>\n", e)
+		}
+		d := tdest("/queue/write.batch")
+		const senders, each = 8, 50
+		var wg sync.WaitGroup
+		errs := make(chan error, senders*each)
+		for i := 0; i < senders; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < each; j++ {
+					errs <- conn.Send(Headers{HK_DESTINATION, d},
+						fmt.Sprintf("sender %d message %d", i, j))
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for e := range errs {
+			if e != nil {
+				t.Fatalf("TestWriteBatch Expected no send error, got [%v]\n", e)
+			}
+		}
+		e = conn.Disconnect(empty_headers) // The receipt follows every SEND
+		checkDisconnectError(t, e)
+		if qd := b.QueueDepth(d); qd != senders*each {
+			t.Fatalf("TestWriteBatch Expected [%d] messages, got [%d] linger [%v]\n",
+				senders*each, qd, linger)
+		}
+		_ = b.Close()
+	}
+}
+
+/*
+	Test that every batched send gets a result when the connection ends with
+	frames still queued.
+*/
+func TestWriteBatchConnectionEnd(t *testing.T) {
+	b := fakebroker.New(&fakebroker.Config{
+		Script: func(s *fakebroker.Session, f *fakebroker.Frame) bool {
+			if f.Command == SEND && string(f.Body) == "close" {
+				time.Sleep(50 * time.Millisecond) // Stall the writer first
+				s.Close()
+				time.Sleep(50 * time.Millisecond) // Read nothing more
+				return true
+			}
+			return false
+		}})
+	defer b.Close()
+	o := &ConnectOptions{WriteBatch: 8, WriteLinger: time.Millisecond}
+	conn, e := ConnectWithOptions(b.Pipe(), headersProtocol(login_headers,
+		SPL_12), o)
+	if e != nil {
+		t.Fatalf("TestWriteBatchConnectionEnd CONNECT Failed: e:<%q>\n", e)
+	}
+	h := Headers{HK_DESTINATION, tdest("/queue/write.batch.end")}
+	if e = conn.Send(h, "close"); e != nil {
+		t.Fatalf("TestWriteBatchConnectionEnd Expected no send error, got [%v]\n",
+			e)
+	}
+	big := strings.Repeat("after", 16*1024) // More than the broker buffers
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e := conn.Send(h, big); e == nil {
+				t.Errorf("TestWriteBatchConnectionEnd Expected a send error\n")
+			}
+		}()
+	}
+	wg.Wait()
+	<-conn.rdrdc
+}